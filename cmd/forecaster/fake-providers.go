@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	fakeProviders "identity-forecaster/internal/app/fake-providers"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+const fakeProvidersCommand = "fake-providers"
+
+// runFakeProviders starts agify/genderize/nationalize compatible endpoints for local development,
+// e.g. forecaster fake-providers --port 9000 --rate-5xx 0.1 --latency 200ms
+func runFakeProviders(args []string) error {
+	flags := flag.NewFlagSet(fakeProvidersCommand, flag.ContinueOnError)
+
+	host := flags.String("host", "localhost", "host to listen on")
+	port := flags.Int("port", 9000, "port to listen on")
+
+	var cfg fakeProviders.Config
+	flags.DurationVar(&cfg.Latency, "latency", 0, "latency added to every response")
+	flags.DurationVar(&cfg.LatencyJitter, "latency-jitter", 0, "maximum random latency added on top of -latency")
+	flags.Float64Var(&cfg.TooManyRequests, "rate-429", 0, "share of requests answered with 429 Too Many Requests (0-1)")
+	flags.Float64Var(&cfg.ServerErrors, "rate-5xx", 0, "share of requests answered with 500 Internal Server Error (0-1)")
+	flags.Float64Var(&cfg.MalformedBodies, "rate-malformed", 0, "share of requests answered with a truncated JSON body (0-1)")
+	flags.IntVar(&cfg.RateLimit, "rate-limit", 1000, "names allowed per rate limit window, 0 disables the limit")
+	flags.DurationVar(&cfg.RateLimitWindow, "rate-limit-window", 24*time.Hour, "rate limit window")
+	flags.Int64Var(&cfg.Seed, "seed", 1, "seed of the injected failures and latency")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	e := fakeProviders.New(cfg)
+
+	go func() {
+		err := e.Start(net.JoinHostPort(*host, strconv.Itoa(*port)))
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Logger().Infoln(err)
+		}
+	}()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM)
+	<-c
+
+	const timeoutInterval = 5 * time.Second
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeoutInterval)
+	defer cancel()
+
+	return e.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"identity-forecaster/internal/app/forecaster/config"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/app/forecaster/repository"
+	"identity-forecaster/internal/app/forecaster/service"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+const importCommand = "import"
+
+var (
+	errNoImportFile      = errors.New("path to the CSV file is required")
+	errWrongReportFormat = errors.New("format of the report must be csv or json")
+)
+
+// runImport loads persons from a CSV file straight into the database and waits for their enrichment,
+// e.g. forecaster import --delimiter ";" --encoding windows-1251 --mapping "Фамилия:surname,Имя:name" staff.csv
+func runImport(args []string) error {
+	flags := flag.NewFlagSet(importCommand, flag.ContinueOnError)
+
+	delimiter := flags.String("delimiter", ",", "column delimiter, a single character or tab")
+	encoding := flags.String("encoding", domain.EncodingUTF8, "encoding of the file: utf-8 or windows-1251")
+	mapping := flags.String("mapping", "", "comma separated header:field pairs for the columns not named as the fields")
+	dryRun := flags.Bool("dry-run", false, "only check the file, nothing is written to the database")
+	reportPath := flags.String("report", "", "file to write the CSV report to, standard output if empty")
+	reportFormat := flags.String("report-format", "csv", "format of the report: csv or json")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errNoImportFile
+	}
+
+	if *reportFormat != "csv" && *reportFormat != "json" {
+		return errWrongReportFormat
+	}
+
+	opts, err := domain.ParseCSVImportOptions(*delimiter, *encoding, *mapping, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cfg := config.LoadConfig()
+	logger.SetLogfilePath(cfg.Logfile)
+
+	pgPool, err := repository.GetPgxPool(cfg.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer pgPool.Close()
+
+	providers, err := newProviders(cfg, pgPool)
+	if err != nil {
+		return err
+	}
+	defer providers.Close()
+
+	var wg sync.WaitGroup
+
+	s := service.New(repository.New(repository.NewPostgres(pgPool)))
+	importer := service.NewImporter(s, service.NewEnricher(s, providers, &wg, cfg.EnrichBatchSize, cfg.EnrichWorkers), cfg.BulkMaxItems)
+
+	report, err := importer.ImportCSV(context.Background(), file, opts)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *reportPath != "" {
+		reportFile, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer reportFile.Close()
+
+		out = reportFile
+	}
+
+	if *reportFormat == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteCSV(out)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "created: %d, skipped: %d, rejected: %d\n", report.Created, report.Skipped, report.Rejected)
+
+	wg.Wait()
+
+	return nil
+}
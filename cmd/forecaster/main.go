@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -13,7 +15,9 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"identity-forecaster/internal/app/forecaster/config"
+	"identity-forecaster/internal/app/forecaster/domain"
 	"identity-forecaster/internal/app/forecaster/handler"
+	"identity-forecaster/internal/app/forecaster/provider"
 	"identity-forecaster/internal/app/forecaster/repository"
 	"identity-forecaster/internal/app/forecaster/service"
 	"identity-forecaster/internal/pkg/logger"
@@ -21,24 +25,172 @@ import (
 	_ "identity-forecaster/docs"
 )
 
-func router(pgPool *pgxpool.Pool, wg *sync.WaitGroup, retriesAmount uint, msBetweenRetries uint, APIs []string) (*echo.Echo, error) {
+func router(pgPool *pgxpool.Pool, wg *sync.WaitGroup, providers domain.ProviderRegistry, cfg *config.Config) (*echo.Echo, error) {
 	e := echo.New()
 
 	pg := repository.NewPostgres(pgPool)
 
 	r := repository.New(pg)
 	s := service.New(r)
-	h := handler.New(s, wg, retriesAmount, msBetweenRetries, APIs)
+	h := handler.New(s, wg, providers, cfg.RequireVersion, cfg.ExportWriteTimeout)
+	enricher := service.NewEnricher(s, providers, wg, cfg.EnrichBatchSize, cfg.EnrichWorkers)
+	bh := handler.NewBulk(s, enricher, cfg.BulkMaxItems)
+	ih := handler.NewImports(service.NewImporter(s, enricher, cfg.BulkMaxItems))
 
-	e.POST("/create", h.CreatePerson)
+	pr := repository.NewProviders(pg)
+	ps := service.NewProviders(pr)
+	ph := handler.NewProviders(ps)
+
+	idempotent := handler.Idempotency(service.NewIdempotency(repository.NewIdempotency(pg)), cfg.IdempotencyTTL, cfg.IdempotencyMaxBodySize)
+
+	e.POST("/create", h.CreatePerson, idempotent)
+	e.POST("/persons/bulk", bh.CreatePersons, idempotent)
+	e.POST("/import/csv", ih.ImportCSV)
+	e.POST("/import/vcard", ih.ImportVCard)
 	e.DELETE("/delete/:id", h.DeletePersonByID)
 	e.PUT("/update/:id", h.UpdatePerson)
 	e.GET("/read", h.ReadPersons)
+	e.GET("/export", h.ExportPersons)
+	e.GET("/persons/:id", h.ReadPerson)
+	e.PUT("/persons/:id", h.ReplacePerson)
+	e.PATCH("/persons/:id", h.PatchPerson)
+	e.POST("/persons/merge", h.MergePersons)
+	e.GET("/duplicates", h.ReadDuplicates)
+	e.GET("/persons/:id/provider-responses", h.ReadProviderResponses)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	if cfg.AdminToken == "" {
+		logger.Logger().Infoln("ADMIN_TOKEN is empty, admin API is disabled")
+	}
+
+	admin := e.Group("/admin", handler.AdminAuth(cfg.AdminToken))
+	admin.POST("/providers", ph.CreateProvider)
+	admin.GET("/providers", ph.ReadProviders)
+	admin.PUT("/providers/:id", ph.UpdateProvider)
+	admin.DELETE("/providers/:id", ph.DeleteProvider)
+
 	return e, nil
 }
 
+type providerRegistry interface {
+	domain.ProviderRegistry
+	Watch(ctx context.Context, interval time.Duration)
+	Close() error
+}
+
+// newProviders builds the HTTP client for providers and loads them from the database, seeding it from the config first
+func newProviders(cfg *config.Config, pgPool *pgxpool.Pool) (providerRegistry, error) {
+	for _, providerCfg := range cfg.Providers {
+		if err := provider.Validate(providerCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	clientTransport, err := provider.NewClientTransport(provider.ClientConfig{
+		ProxyURL:            cfg.ProviderProxyURL,
+		CAFile:              cfg.ProviderCAFile,
+		ClientCertFile:      cfg.ProviderClientCertFile,
+		ClientKeyFile:       cfg.ProviderClientKeyFile,
+		DialTimeout:         cfg.ProviderDialTimeout,
+		TLSHandshakeTimeout: cfg.ProviderTLSTimeout,
+		KeepAlive:           cfg.ProviderKeepAlive,
+		IdleConnTimeout:     cfg.ProviderIdleConnTimeout,
+		MaxIdleConns:        cfg.ProviderMaxIdleConns,
+		MaxIdleConnsPerHost: cfg.ProviderMaxIdleConnsPerHost,
+		UserAgent:           cfg.ProviderUserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := provider.NewTransport(cfg.ProviderMode, cfg.ProviderCassettesDir, clientTransport)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Transport: transport, Timeout: cfg.ProviderTimeout}
+
+	providersRepo := repository.NewProviders(repository.NewPostgres(pgPool))
+	if err = providersRepo.SeedProviders(context.Background(), cfg.Providers); err != nil {
+		return nil, err
+	}
+
+	providers := provider.NewRegistry(providersRepo, client, cfg.RetriesAmount, cfg.RetryIntervalMilliseconds)
+	if err = providers.Reload(context.Background()); err != nil {
+		providers.Close()
+		return nil, err
+	}
+
+	return providers, nil
+}
+
+// cleanProviderResponses deletes raw provider responses older than retention, checking once in a while
+func cleanProviderResponses(ctx context.Context, s domain.ForecasterService, retention time.Duration) {
+	const cleanupInterval = time.Hour
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.DeleteProviderResponsesOlderThan(ctx, retention)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Logger().Errorln("couldn't delete old provider responses:", err)
+		} else if deleted > 0 {
+			logger.Logger().Infoln("deleted old provider responses:", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// findDuplicates searches for duplicate persons once in a while, the pairs scoring less than minScore are not stored
+func findDuplicates(ctx context.Context, s domain.ForecasterService, interval time.Duration, minScore float64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		found, err := s.FindDuplicates(ctx, minScore)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Logger().Errorln("couldn't find duplicate persons:", err)
+		} else if err == nil {
+			logger.Logger().Infoln("found pairs of duplicate persons:", found)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteExpiredIdempotencyKeys deletes the stored responses to requests with Idempotency-Key which are no longer needed
+func deleteExpiredIdempotencyKeys(ctx context.Context, s domain.IdempotencyService) {
+	const cleanupInterval = time.Hour
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Logger().Errorln("couldn't delete expired idempotency keys:", err)
+		} else if deleted > 0 {
+			logger.Logger().Infoln("deleted expired idempotency keys:", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // @title Identity Forecaster API
 // @version 1.0
 // @description Сервис, получающий ФИО, и обогащающий информацию о нем из открытых источников
@@ -49,9 +201,30 @@ func router(pgPool *pgxpool.Pool, wg *sync.WaitGroup, retriesAmount uint, msBetw
 // @Tag.name Persons
 // @Tag.description Группа запросов для управления сущностями
 
+// @Tag.name Admin
+// @Tag.description Группа запросов для управления источниками данных
+
 // @Schemes http
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == fakeProvidersCommand {
+		if err := runFakeProviders(os.Args[2:]); err != nil {
+			logger.Logger().Infoln(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == importCommand {
+		if err := runImport(os.Args[2:]); err != nil {
+			logger.Logger().Infoln(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	cfg := config.LoadConfig()
 
 	logger.SetLogfilePath(cfg.Logfile)
@@ -62,9 +235,33 @@ func main() {
 		panic(err)
 	}
 
+	providers, err := newProviders(cfg, pgPool)
+	if err != nil {
+		panic(err)
+	}
+	defer providers.Close()
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.ProvidersRefreshInterval > 0 {
+		go providers.Watch(watchCtx, time.Duration(cfg.ProvidersRefreshInterval)*time.Millisecond)
+	}
+
+	if cfg.ProviderResponsesRetention > 0 {
+		go cleanProviderResponses(watchCtx, service.New(repository.New(repository.NewPostgres(pgPool))), cfg.ProviderResponsesRetention)
+	}
+
+	if cfg.DuplicatesInterval > 0 {
+		go findDuplicates(watchCtx, service.New(repository.New(repository.NewPostgres(pgPool))), cfg.DuplicatesInterval, cfg.DuplicatesMinScore)
+	}
+
+	if cfg.IdempotencyTTL > 0 {
+		go deleteExpiredIdempotencyKeys(watchCtx, service.NewIdempotency(repository.NewIdempotency(repository.NewPostgres(pgPool))))
+	}
+
 	var wg sync.WaitGroup
 
-	r, err := router(pgPool, &wg, cfg.RetriesAmount, cfg.RetryIntervalMilliseconds, cfg.APIs)
+	r, err := router(pgPool, &wg, providers, cfg)
 	if err != nil {
 		panic(err)
 	}
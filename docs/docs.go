@@ -16,9 +16,185 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/providers": {
+            "get": {
+                "description": "Запрос для получения всех источников данных в порядке их опроса",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Запрос получения источников данных",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.StoredProvider"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "description": "Запрос для добавления нового источника данных, изменения применяются без перезапуска сервиса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Запрос добавления источника данных",
+                "parameters": [
+                    {
+                        "description": "описание источника",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.StoredProvider"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.StoredProvider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/admin/providers/{id}": {
+            "put": {
+                "description": "Запрос для включения/выключения источника, изменения его позиции, таймаута и параметров повторных запросов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Запрос обновления источника данных",
+                "parameters": [
+                    {
+                        "description": "изменяемые параметры источника",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.ProviderUpdate"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.StoredProvider"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Запрос для удаления источника данных (чтобы временно отключить источник, лучше использовать обновление с enabled = false)",
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Запрос удаления источника данных",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id источника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
         "/create": {
             "post": {
-                "description": "Запрос для добавления информации о новой сущности",
+                "description": "Запрос для добавления информации о новой сущности. on_conflict задает, что делать, если сущность с таким ФИО уже есть:\nerror - вернуть 409 (сущность добавляется до ответа вместе с данными из источников, удаленная восстанавливается), ignore - ничего не менять,\nupdate - заменить данные из источников (и восстановить удаленную), revive - восстановить удаленную, а существующую не менять (по умолчанию)",
                 "consumes": [
                     "application/json"
                 ],
@@ -35,24 +211,63 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/domain.Person"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "enum": [
+                            "error",
+                            "ignore",
+                            "update",
+                            "revive"
+                        ],
+                        "description": "что делать с уже существующей сущностью",
+                        "name": "on_conflict",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ключ, повторный запрос с которым вернет ответ на первый запрос",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
+                    "201": {
+                        "description": "Created",
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "адрес добавленной сущности (только для on_conflict=error)"
+                            }
+                        }
+                    },
                     "202": {
                         "description": "Accepted"
                     },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
                     "500": {
                         "description": "Internal Server Error"
+                    },
+                    "502": {
+                        "description": "Bad Gateway"
                     }
                 }
             }
         },
         "/delete/{id}": {
             "delete": {
-                "description": "Запрос для удаления сущности",
+                "description": "Запрос для удаления сущности. Если задан If-Match (ETag из GET /persons/{id}) или version, сущность удаляется, только если с тех пор не менялась",
                 "tags": [
                     "Persons"
                 ],
@@ -65,6 +280,19 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 3,
+                        "description": "версия сущности, которую удаляет клиент",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag сущности, которую удаляет клиент",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -77,22 +305,28 @@ const docTemplate = `{
                     "404": {
                         "description": "Not Found"
                     },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "428": {
+                        "description": "Precondition Required"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
                 }
             }
         },
-        "/read": {
+        "/duplicates": {
             "get": {
-                "description": "Запрос для получения сохраненной информации о сущностях с возможностью применения фильтров и пагинацией",
+                "description": "Запрос для получения пар сущностей, которые фоновый поиск дубликатов считает одной сущностью, по убыванию score.\nscore складывается из сходства ФИО (name_score, 0.7) и совпадения возраста, гендера и национальности (attribute_score, 0.3)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Persons"
                 ],
-                "summary": "Запрос чтения информации о сущностях",
+                "summary": "Запрос получения возможных дубликатов",
                 "parameters": [
                     {
                         "type": "integer",
@@ -103,11 +337,82 @@ const docTemplate = `{
                     },
                     {
                         "type": "integer",
-                        "example": 1,
-                        "description": "максимальное число записей на странице (1 и больше)",
+                        "example": 10,
+                        "description": "максимальное число пар на странице (от 1 до 50)",
                         "name": "limit",
                         "in": "query"
                     },
+                    {
+                        "type": "number",
+                        "example": 0.8,
+                        "description": "минимальный score пары (от 0 до 1)",
+                        "name": "min_score",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.DuplicatePair"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/export": {
+            "get": {
+                "description": "Запрос для выгрузки всех сущностей, подходящих под фильтры (те же, что и у /read), без пагинации. Записи отправляются по мере чтения из базы",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "text/vcard",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос выгрузки сущностей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "vcf",
+                            "xlsx"
+                        ],
+                        "default": "csv",
+                        "description": "формат выгрузки",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "id,name,surname,age",
+                        "description": "столбцы через запятую (каждый не больше одного раза, по умолчанию - все, для vcf не используется)",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "добавить в xlsx лист со статистикой по выгруженным сущностям (для других форматов - 400)",
+                        "name": "stats",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "example": 1,
@@ -184,15 +489,47 @@ const docTemplate = `{
                         "description": "конкретная национальность",
                         "name": "nationality",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "dmitr",
+                        "description": "часть имени без учета регистра (так же есть surname_like и patronymic_like)",
+                        "name": "name_like",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Dm",
+                        "description": "начало имени без учета регистра (так же есть surname_prefix и patronymic_prefix)",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Дмитрий Smirnoff",
+                        "description": "слова, каждое из которых звучит как имя, фамилия или отчество (учитывает транслитерацию)",
+                        "name": "sounds_like",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Smirnv Dmitry",
+                        "description": "нечеткий поиск по ФИО с опечатками",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "gender==female;age=lt=30",
+                        "description": "выражение RSQL, как у /read",
+                        "name": "filter",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK"
                     },
-                    "204": {
-                        "description": "No Content"
-                    },
                     "400": {
                         "description": "Bad Request"
                     },
@@ -202,48 +539,903 @@ const docTemplate = `{
                 }
             }
         },
-        "/update/{id}": {
-            "put": {
-                "description": "Запрос для обновления информации о сущности (кроме id)",
+        "/import/csv": {
+            "post": {
+                "description": "Запрос для добавления сущностей из CSV файла. Первая строка файла - заголовок, столбцы с названиями полей (name, surname, patronymic, age, gender, nationality) сопоставляются автоматически, остальные - через параметр mapping. Для сущностей без age, gender и nationality данные запрашиваются из источников в фоне. В ответе - отчет о каждой строке: создана, пропущена как дубликат (по имени, фамилии и отчеству) или отклонена",
                 "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "text/csv",
                     "application/json"
                 ],
                 "tags": [
                     "Persons"
                 ],
-                "summary": "Запрос обновления информации о сущности",
+                "summary": "Запрос импорта сущностей из CSV",
                 "parameters": [
                     {
-                        "description": "описание сущности",
+                        "description": "CSV файл",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.PersonWithAPIData"
+                            "type": "string"
                         }
                     },
                     {
-                        "type": "integer",
-                        "example": 1,
-                        "description": "id сущности",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "default": ",",
+                        "description": "разделитель столбцов (один символ или tab)",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "enum": [
+                            "utf-8",
+                            "windows-1251"
+                        ],
+                        "default": "utf-8",
+                        "description": "кодировка файла",
+                        "name": "encoding",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Фамилия:surname,Имя:name,Отчество:patronymic",
+                        "description": "сопоставление заголовков и полей через запятую",
+                        "name": "mapping",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "только проверить файл, ничего не добавляя",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "enum": [
+                            "csv",
+                            "json"
+                        ],
+                        "default": "csv",
+                        "description": "формат отчета",
+                        "name": "report",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "отчет пробного импорта",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ImportReport"
+                        },
+                        "headers": {
+                            "X-Import-Created": {
+                                "type": "integer",
+                                "description": "число созданных сущностей"
+                            },
+                            "X-Import-Rejected": {
+                                "type": "integer",
+                                "description": "число отклоненных строк"
+                            },
+                            "X-Import-Skipped": {
+                                "type": "integer",
+                                "description": "число пропущенных строк"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "отчет импорта",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ImportReport"
+                        },
+                        "headers": {
+                            "X-Import-Created": {
+                                "type": "integer",
+                                "description": "число созданных сущностей"
+                            },
+                            "X-Import-Rejected": {
+                                "type": "integer",
+                                "description": "число отклоненных строк"
+                            },
+                            "X-Import-Skipped": {
+                                "type": "integer",
+                                "description": "число пропущенных строк"
+                            }
+                        }
                     },
                     "400": {
                         "description": "Bad Request"
                     },
-                    "404": {
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/import/vcard": {
+            "post": {
+                "description": "Запрос для добавления сущностей из файла с одной или несколькими vCard: имя, фамилия и отчество берутся из N, гендер - из GENDER, возраст и национальность - из X-PREDICTED-AGE и X-NATIONALITY. Для сущностей без этих данных они запрашиваются из источников в фоне. В ответе - отчет о каждой vCard (номер строки - строка BEGIN:VCARD)",
+                "consumes": [
+                    "text/vcard"
+                ],
+                "produces": [
+                    "text/csv",
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос импорта сущностей из vCard",
+                "parameters": [
+                    {
+                        "description": "vCard файл",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "только проверить файл, ничего не добавляя",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "enum": [
+                            "csv",
+                            "json"
+                        ],
+                        "default": "csv",
+                        "description": "формат отчета",
+                        "name": "report",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "отчет пробного импорта",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ImportReport"
+                        },
+                        "headers": {
+                            "X-Import-Created": {
+                                "type": "integer",
+                                "description": "число созданных сущностей"
+                            },
+                            "X-Import-Rejected": {
+                                "type": "integer",
+                                "description": "число отклоненных vCard"
+                            },
+                            "X-Import-Skipped": {
+                                "type": "integer",
+                                "description": "число пропущенных vCard"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "отчет импорта",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ImportReport"
+                        },
+                        "headers": {
+                            "X-Import-Created": {
+                                "type": "integer",
+                                "description": "число созданных сущностей"
+                            },
+                            "X-Import-Rejected": {
+                                "type": "integer",
+                                "description": "число отклоненных vCard"
+                            },
+                            "X-Import-Skipped": {
+                                "type": "integer",
+                                "description": "число пропущенных vCard"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/persons/bulk": {
+            "post": {
+                "description": "Запрос для добавления информации о множестве сущностей за раз в виде JSON массива или NDJSON (по сущности на строку). Каждая сущность проверяется отдельно, принятые сущности добавляются в одной транзакции, а данные из источников для них запрашиваются в фоне пачками. В ответе - сводка с номерами строк (для NDJSON) или элементов массива (для JSON), которые не были приняты",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос добавления множества сущностей",
+                "parameters": [
+                    {
+                        "description": "информация о сущностях",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Person"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ключ, повторный запрос с которым вернет ответ на первый запрос",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/domain.BulkSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/domain.BulkSummary"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/persons/merge": {
+            "post": {
+                "description": "Запрос для объединения двух сущностей в одну: merged_id удаляется, а запросы по его id ведут к survivor_id.\nВ fields для имени, фамилии, отчества, возраста, гендера и национальности указывается, чье значение оставить (survivor или merged),\nне указанные поля сохраняют значение survivor_id, если оно не пустое. Обе сущности до объединения сохраняются в истории",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос объединения сущностей",
+                "parameters": [
+                    {
+                        "description": "объединяемые сущности и источники значений полей",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.MergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonFromDB"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/persons/{id}": {
+            "get": {
+                "description": "Запрос для получения сущности по id. В ответе есть заголовки ETag и Last-Modified, с которыми в If-None-Match или If-Modified-Since\nповторный запрос вернет 304 без тела, если сущность не изменилась. С суффиксом .vcf сущность возвращается в виде vCard",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос получения сущности",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id сущности",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из предыдущего ответа",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified из предыдущего ответа",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonFromDB"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "строгий валидатор представления сущности"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "время последнего изменения сущности"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect",
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "адрес сущности, с которой была объединена запрошенная"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "put": {
+                "description": "Запрос для полной замены сущности: все поля обязательны, пустая строка или нулевой возраст очищают значение.\nПоле version и If-Match проверяются так же, как в PUT /update/{id}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос замены сущности",
+                "parameters": [
+                    {
+                        "description": "новое описание сущности",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonReplacement"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id сущности",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag сущности, которую изменяет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonFromDB"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "строгий валидатор сущности после замены"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "428": {
+                        "description": "Precondition Required"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "patch": {
+                "description": "Запрос для изменения сущности по JSON Merge Patch (RFC 7396): отсутствующие поля не меняются, null очищает значение\n(имя и фамилию очистить нельзя). Поле version и If-Match проверяются так же, как в PUT /update/{id}",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос частичного изменения сущности",
+                "parameters": [
+                    {
+                        "description": "изменяемые поля сущности",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonReplacement"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id сущности",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag сущности, которую изменяет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonFromDB"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "строгий валидатор сущности после изменения"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Conflict"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type"
+                    },
+                    "428": {
+                        "description": "Precondition Required"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/persons/{id}.vcf": {
+            "get": {
+                "description": "Запрос для получения сущности в виде vCard 4.0 (N - фамилия, имя и отчество, GENDER - гендер, X-PREDICTED-AGE и X-NATIONALITY - возраст и национальность)",
+                "produces": [
+                    "text/vcard"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос получения сущности в виде vCard",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id сущности",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect",
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "адрес сущности, с которой была объединена запрошенная"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/persons/{id}/provider-responses": {
+            "get": {
+                "description": "Запрос для получения сохраненных ответов внешних источников, из которых были получены данные о сущности",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос получения исходных ответов источников",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id сущности",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.ProviderResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/read": {
+            "get": {
+                "description": "Запрос для получения сохраненной информации о сущностях с возможностью применения фильтров и пагинацией.\nПагинация по номеру страницы возвращает массив сущностей (или объект с метаданными при envelope=true), по курсору (задан after) - всегда объект.\nСсылки на соседние страницы передаются в заголовке Link, а общее число сущностей - в X-Total-Count, если оно подсчитывается (count).\nПри ошибке в filter в ответе 400 есть описание ошибки и позиция неверного фрагмента: {\"error\": \"...\", \"position\": 5, \"token\": \"...\"}",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос чтения информации о сущностях",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "номер страницы (1 и больше), нельзя задавать вместе с after",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "максимальное число записей на странице (1 и больше)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "eyJzIjoiaWQiLCJ2IjpbMTBdfQ",
+                        "description": "курсор из next_cursor предыдущей страницы, пустое значение - первая страница",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "-age,surname,name",
+                        "description": "поля сортировки через запятую, с минусом - по убыванию (при равенстве всех полей сортируется по id), score - только вместе с q",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "вернуть объект с items, total, page, limit, next и prev вместо массива",
+                        "name": "envelope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "enum": [
+                            "exact",
+                            "estimate",
+                            "none"
+                        ],
+                        "description": "подсчет общего числа сущностей: точный, оценка по статистике постгреса или без подсчета (по умолчанию exact с envelope=true и none без него)",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "нижняя граница возраста (включительно)",
+                        "name": "agegt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "верхняя граница возраста (не включительно)",
+                        "name": "agelt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "конкретный возраст (если заданы границы - перезаписывает их)",
+                        "name": "age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "нижняя граница id (включительно)",
+                        "name": "idgt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "верхняя граница id (не включительно)",
+                        "name": "idlt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "конкретный id (если заданы границы - перезаписывает их)",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Dmitriy\"",
+                        "description": "конкретное имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Smirnov\"",
+                        "description": "конкретная фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"Petrovich\"",
+                        "description": "конкретное отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"male\"",
+                        "description": "конкретный гендер",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "\"RU\"",
+                        "description": "конкретная национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "dmitr",
+                        "description": "часть имени без учета регистра (так же есть surname_like и patronymic_like)",
+                        "name": "name_like",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Dm",
+                        "description": "начало имени без учета регистра (так же есть surname_prefix и patronymic_prefix)",
+                        "name": "name_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Дмитрий Smirnoff",
+                        "description": "слова, каждое из которых звучит как имя, фамилия или отчество (учитывает транслитерацию)",
+                        "name": "sounds_like",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Smirnv Dmitry",
+                        "description": "нечеткий поиск по ФИО с опечатками, результат по умолчанию отсортирован по убыванию score",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "gender==female;age=lt=30",
+                        "description": "выражение RSQL: ; - И, , - ИЛИ, операторы ==, !=, =lt=, =le=, =gt=, =ge=, =in=, =out=, =isnull=",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonsPage"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "ссылки на первую, последнюю, следующую и предыдущую страницы"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "общее число сущностей, подходящих под фильтры (если оно подсчитывается)"
+                            },
+                            "X-Total-Count-Estimated": {
+                                "type": "string",
+                                "description": "true, если число получено из статистики"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/update/{id}": {
+            "put": {
+                "description": "Запрос для обновления информации о сущности (кроме id), в ответе - сущность после обновления с новыми ETag и version.\nЕсли задан If-Match (ETag из GET /persons/{id}) или version, сущность обновляется, только если с тех пор не менялась",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Persons"
+                ],
+                "summary": "Запрос обновления информации о сущности",
+                "parameters": [
+                    {
+                        "description": "описание сущности",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonUpdate"
+                        }
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "id сущности",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag сущности, которую изменяет клиент",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.PersonFromDB"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "строгий валидатор сущности после обновления"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
                         "description": "Not Found"
                     },
                     "409": {
                         "description": "Conflict"
                     },
+                    "412": {
+                        "description": "Precondition Failed"
+                    },
+                    "428": {
+                        "description": "Precondition Required"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     }
@@ -252,6 +1444,170 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "domain.BulkError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "required fields not provided"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "violations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/validation.Violation"
+                    }
+                }
+            }
+        },
+        "domain.BulkSummary": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "accepted_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.BulkError"
+                    }
+                },
+                "rejected": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "domain.DuplicatePair": {
+            "type": "object",
+            "properties": {
+                "attribute_score": {
+                    "type": "number",
+                    "example": 1
+                },
+                "duplicate": {
+                    "$ref": "#/definitions/domain.PersonFromDB"
+                },
+                "found_at": {
+                    "type": "string"
+                },
+                "name_score": {
+                    "type": "number",
+                    "example": 0.8
+                },
+                "person": {
+                    "$ref": "#/definitions/domain.PersonFromDB"
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.86
+                }
+            }
+        },
+        "domain.ImportReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "dry_run": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "rejected": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ImportRow"
+                    }
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "domain.ImportRow": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Dmitriy"
+                },
+                "patronymic": {
+                    "type": "string",
+                    "example": "Petrovich"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "created",
+                        "skipped",
+                        "rejected"
+                    ],
+                    "example": "created"
+                },
+                "surname": {
+                    "type": "string",
+                    "example": "Smirnov"
+                },
+                "violations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/validation.Violation"
+                    }
+                }
+            }
+        },
+        "domain.MergeRequest": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": "name:merged"
+                },
+                "merged_id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "survivor_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
         "domain.Person": {
             "type": "object",
             "properties": {
@@ -269,7 +1625,73 @@ const docTemplate = `{
                 }
             }
         },
-        "domain.PersonWithAPIData": {
+        "domain.PersonFromDB": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "domain.PersonReplacement": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "gender": {
+                    "type": "string",
+                    "example": "male"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Dmitriy"
+                },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "patronymic": {
+                    "type": "string",
+                    "example": "Petrovich"
+                },
+                "surname": {
+                    "type": "string",
+                    "example": "Smirnov"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "domain.PersonUpdate": {
             "type": "object",
             "properties": {
                 "age": {
@@ -299,6 +1721,218 @@ const docTemplate = `{
                 "surname": {
                     "type": "string",
                     "example": "Smirnov"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "domain.PersonsPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.PersonFromDB"
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "next": {
+                    "type": "string",
+                    "example": "/read?limit=10&page=3"
+                },
+                "next_cursor": {
+                    "type": "string",
+                    "example": "eyJzIjoiaWQiLCJ2IjpbMTBdfQ"
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "prev": {
+                    "type": "string",
+                    "example": "/read?limit=10&page=1"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "total_estimated": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "domain.ProviderResponse": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "object"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "person_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "agify"
+                },
+                "received_at": {
+                    "type": "string",
+                    "example": "2024-01-20T15:04:05Z"
+                },
+                "status_code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://api.agify.io/?name=Dmitriy"
+                }
+            }
+        },
+        "domain.ProviderUpdate": {
+            "type": "object",
+            "properties": {
+                "concurrency": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "position": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "retries": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "retry_interval_ms": {
+                    "type": "integer",
+                    "example": 150
+                },
+                "timeout_ms": {
+                    "type": "integer",
+                    "example": 3000
+                }
+            }
+        },
+        "domain.StoredProvider": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "command": {
+                    "type": "string"
+                },
+                "concurrency": {
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "input": {
+                    "type": "string",
+                    "example": "name"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "agify"
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "position": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "probabilities": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "query_param": {
+                    "type": "string",
+                    "example": "name"
+                },
+                "record_headers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "retries": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "retry_interval_ms": {
+                    "type": "integer",
+                    "example": 150
+                },
+                "timeout_ms": {
+                    "type": "integer",
+                    "example": 3000
+                },
+                "type": {
+                    "type": "string",
+                    "example": "http"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://api.agify.io/"
+                }
+            }
+        },
+        "validation.Violation": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "too_small"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "age"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be at least 0"
                 }
             }
         }
@@ -307,6 +1941,10 @@ const docTemplate = `{
         {
             "description": "Группа запросов для управления сущностями",
             "name": "Persons"
+        },
+        {
+            "description": "Группа запросов для управления источниками данных",
+            "name": "Admin"
         }
     ]
 }`
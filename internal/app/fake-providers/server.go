@@ -0,0 +1,202 @@
+package fake_providers
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"identity-forecaster/internal/pkg/logger"
+)
+
+const (
+	batchParam  = "name[]"
+	singleParam = "name"
+)
+
+var countries = []string{"RU", "UA", "BY", "KZ", "US", "DE", "FR", "PL", "GB", "IT", "ES", "TR", "CN", "JP", "IN", "BR"}
+
+type Config struct {
+	Latency         time.Duration
+	LatencyJitter   time.Duration
+	TooManyRequests float64
+	ServerErrors    float64
+	MalformedBodies float64
+	RateLimit       int
+	RateLimitWindow time.Duration
+	Seed            int64
+}
+
+type server struct {
+	cfg         Config
+	mu          sync.Mutex
+	random      *rand.Rand
+	used        int
+	windowStart time.Time
+}
+
+// New returns a server answering like agify, genderize and nationalize do, the answers depend only on the name,
+// while latency, errors and malformed bodies are injected with the configured probabilities
+func New(cfg Config) *echo.Echo {
+	s := &server{cfg: cfg, random: rand.New(rand.NewSource(cfg.Seed)), windowStart: time.Now()}
+
+	e := echo.New()
+	e.HideBanner = true
+
+	e.GET("/agify", s.handle(age))
+	e.GET("/genderize", s.handle(gender))
+	e.GET("/nationalize", s.handle(nationality))
+
+	return e
+}
+
+type answerFunc func(name string) map[string]interface{}
+
+func (s *server) handle(answer answerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		query := c.QueryParams()
+		names, isBatch := query[batchParam]
+		if !isBatch {
+			names = query[singleParam]
+		}
+
+		if len(names) == 0 || (!isBatch && names[0] == "") {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Missing 'name' parameter"})
+		}
+
+		delay, failure := s.roll()
+		time.Sleep(delay)
+
+		limit, remaining, reset, limitReached := s.takeRequests(len(names))
+		c.Response().Header().Set("X-Rate-Limit-Limit", strconv.Itoa(limit))
+		c.Response().Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(remaining))
+		c.Response().Header().Set("X-Rate-Limit-Reset", strconv.Itoa(reset))
+
+		if limitReached || failure == http.StatusTooManyRequests {
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "Request limit reached"})
+		}
+
+		if failure == http.StatusInternalServerError {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+		}
+
+		var body interface{}
+		if isBatch {
+			answers := make([]map[string]interface{}, 0, len(names))
+			for _, name := range names {
+				answers = append(answers, answer(name))
+			}
+
+			body = answers
+		} else {
+			body = answer(names[0])
+		}
+
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		if failure == malformedBody {
+			content = content[:len(content)/2]
+		}
+
+		logger.Logger().Debugln("fake provider answered", c.Request().URL.String())
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, content)
+	}
+}
+
+const malformedBody = -1
+
+// roll decides the latency and the failure (if any) of a single request
+func (s *server) roll() (time.Duration, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delay := s.cfg.Latency
+	if s.cfg.LatencyJitter > 0 {
+		delay += time.Duration(s.random.Int63n(int64(s.cfg.LatencyJitter)))
+	}
+
+	switch value := s.random.Float64(); {
+	case value < s.cfg.TooManyRequests:
+		return delay, http.StatusTooManyRequests
+	case value < s.cfg.TooManyRequests+s.cfg.ServerErrors:
+		return delay, http.StatusInternalServerError
+	case value < s.cfg.TooManyRequests+s.cfg.ServerErrors+s.cfg.MalformedBodies:
+		return delay, malformedBody
+	default:
+		return delay, 0
+	}
+}
+
+func (s *server) takeRequests(amount int) (limit int, remaining int, reset int, limitReached bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.RateLimit <= 0 {
+		return 0, 0, 0, false
+	}
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.cfg.RateLimitWindow {
+		s.windowStart = now
+		s.used = 0
+	}
+
+	reset = int(s.cfg.RateLimitWindow.Seconds() - now.Sub(s.windowStart).Seconds())
+
+	if s.used+amount > s.cfg.RateLimit {
+		return s.cfg.RateLimit, s.cfg.RateLimit - s.used, reset, true
+	}
+
+	s.used += amount
+	return s.cfg.RateLimit, s.cfg.RateLimit - s.used, reset, false
+}
+
+func hash(name string, salt string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(salt))
+	h.Write([]byte(name))
+	return h.Sum64()
+}
+
+func count(name string) int {
+	return int(hash(name, "count") % 100000)
+}
+
+func probability(name string, salt string) float64 {
+	return float64(50+hash(name, salt)%50) / 100
+}
+
+func age(name string) map[string]interface{} {
+	return map[string]interface{}{"count": count(name), "name": name, "age": 18 + int(hash(name, "age")%63)}
+}
+
+func gender(name string) map[string]interface{} {
+	g := "male"
+	if hash(name, "gender")%2 == 1 {
+		g = "female"
+	}
+
+	return map[string]interface{}{"count": count(name), "name": name, "gender": g, "probability": probability(name, "gender")}
+}
+
+func nationality(name string) map[string]interface{} {
+	first := int(hash(name, "nationality") % uint64(len(countries)))
+
+	remaining := 1.0
+	result := make([]map[string]interface{}, 0, 3)
+	for i := 0; i < 3; i++ {
+		p := float64(int(remaining*probability(name, countries[(first+i)%len(countries)])*100)) / 100
+		remaining -= p
+		result = append(result, map[string]interface{}{"country_id": countries[(first+i)%len(countries)], "probability": p})
+	}
+
+	return map[string]interface{}{"count": count(name), "name": name, "country": result}
+}
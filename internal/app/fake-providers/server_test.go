@@ -0,0 +1,72 @@
+package fake_providers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, ts *httptest.Server, path string) (*http.Response, []byte) {
+	resp, err := ts.Client().Get(ts.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, body
+}
+
+func TestFakeProviders(t *testing.T) {
+	ts := httptest.NewServer(New(Config{RateLimit: 4, RateLimitWindow: time.Hour}))
+	defer ts.Close()
+
+	resp, first := get(t, ts, "/agify?name="+url.QueryEscape("Дмитрий"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "4", resp.Header.Get("X-Rate-Limit-Limit"))
+	require.Equal(t, "3", resp.Header.Get("X-Rate-Limit-Remaining"))
+
+	var single map[string]interface{}
+	require.NoError(t, json.Unmarshal(first, &single))
+	require.Equal(t, "Дмитрий", single["name"])
+	require.IsType(t, float64(0), single["age"])
+
+	_, second := get(t, ts, "/agify?name="+url.QueryEscape("Дмитрий"))
+	require.Equal(t, first, second)
+
+	resp, body := get(t, ts, "/nationalize?name[]=Smirnov&name[]=Ivanov")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var batch []map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &batch))
+	require.Len(t, batch, 2)
+	require.Equal(t, "Ivanov", batch[1]["name"])
+	require.Len(t, batch[0]["country"], 3)
+
+	resp, _ = get(t, ts, "/genderize?name=Anna")
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	resp, _ = get(t, ts, "/genderize")
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestFakeProvidersFailures(t *testing.T) {
+	ts := httptest.NewServer(New(Config{ServerErrors: 1}))
+	defer ts.Close()
+
+	resp, _ := get(t, ts, "/genderize?name=Anna")
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	ts = httptest.NewServer(New(Config{MalformedBodies: 1}))
+	defer ts.Close()
+
+	resp, body := get(t, ts, "/genderize?name=Anna")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.False(t, json.Valid(body))
+}
@@ -10,4 +10,32 @@ var (
 	ErrIncorrectQueryParam       = errors.New("incorrect value of a query param provided")
 	ErrRequiredFieldsNotProvided = errors.New("required fields not provided")
 	ErrUniqueViolation           = errors.New("the entity already exists in table")
+	ErrUnknownAttribute          = errors.New("unknown attribute of a person")
+	ErrWrongAttributeType        = errors.New("value of the attribute has wrong type")
+	ErrUnknownProviderType       = errors.New("unknown type of a provider")
+	ErrWrongProviderConfig       = errors.New("provider config is incorrect")
+	ErrPluginExited              = errors.New("plugin process is not running")
+	ErrPluginFailed              = errors.New("plugin returned an error")
+	ErrWrongAdminToken           = errors.New("admin token is missing or wrong")
+	ErrAdminAPIDisabled          = errors.New("admin API is disabled because no admin token is configured")
+	ErrUnknownProviderMode       = errors.New("unknown mode of providers")
+	ErrNoRecordedResponse        = errors.New("no recorded response for the request")
+	ErrEmptyBulk                 = errors.New("no items provided")
+	ErrTooManyItems              = errors.New("too many items in the request")
+	ErrDuplicateItem             = errors.New("the same person is already present in the request")
+	ErrTrailingData              = errors.New("unexpected data after the JSON object")
+	ErrWrongCSVOptions           = errors.New("options of the CSV import are incorrect")
+	ErrWrongCSVMapping           = errors.New("columns of the CSV file could not be mapped to the required fields")
+	ErrWrongClientConfig         = errors.New("config of the http client for providers is incorrect")
+	ErrWrongCursor               = errors.New("cursor is malformed or was made for another sort")
+	ErrUnsupportedFilterOperator = errors.New("operator is not supported in a filter")
+	ErrWrongFilterArguments      = errors.New("wrong number of arguments for the filter operator")
+	ErrWrongMergeRequest         = errors.New("persons to merge or sources of their fields are incorrect")
+	ErrVersionMismatch           = errors.New("the person was changed since the provided version")
+	ErrPreconditionRequired      = errors.New("If-Match header or version is required")
+	ErrWrongPrecondition         = errors.New("If-Match header and version point to different versions")
+	ErrWrongPatch                = errors.New("merge patch of a person has to be a JSON object")
+	ErrWrongIdempotencyKey       = errors.New("Idempotency-Key has to be from 1 to 255 printable ASCII characters")
+	ErrIdempotencyKeyReused      = errors.New("Idempotency-Key was already used for another request")
+	ErrIdempotencyKeyInProgress  = errors.New("request with the Idempotency-Key is still being processed")
 )
@@ -2,38 +2,90 @@ package config
 
 import (
 	"errors"
+	"identity-forecaster/internal/app/forecaster/domain"
 	"identity-forecaster/internal/pkg/logger"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 const (
-	defaultHost                      = "localhost"
-	defaultContainerHost             = "0.0.0.0"
-	defaultPort                      = "8787"
-	defaultDSN                       = "host=localhost dbname=identity-forecaster user=identity-forecaster password=identity-forecaster port=5432 sslmode=disable"
-	defaultContainerDSN              = "host=postgres dbname=identity-forecaster user=identity-forecaster password=identity-forecaster port=5432 sslmode=disable"
-	defaultLogfile                   = "logfile.log"
-	defaultAPIs                      = "https://api.agify.io/,https://api.genderize.io/,https://api.nationalize.io/"
-	defaultRetriesAmount             = 5
-	defaultRetryIntervalMilliseconds = 150
-	defaultIsInContainer             = false
-	envFile                          = ".env"
+	defaultHost                        = "localhost"
+	defaultContainerHost               = "0.0.0.0"
+	defaultPort                        = "8787"
+	defaultDSN                         = "host=localhost dbname=identity-forecaster user=identity-forecaster password=identity-forecaster port=5432 sslmode=disable"
+	defaultContainerDSN                = "host=postgres dbname=identity-forecaster user=identity-forecaster password=identity-forecaster port=5432 sslmode=disable"
+	defaultLogfile                     = "logfile.log"
+	defaultAPIs                        = "https://api.agify.io/,https://api.genderize.io/,https://api.nationalize.io/"
+	defaultRetriesAmount               = 5
+	defaultRetryIntervalMilliseconds   = 150
+	defaultIsInContainer               = false
+	defaultProvidersRefreshInterval    = 5000
+	defaultProviderMode                = "live"
+	defaultProviderCassettesDir        = "provider-cassettes"
+	defaultProviderResponsesRetention  = 30 * 24 * time.Hour
+	defaultProviderTimeout             = 10 * time.Second
+	defaultProviderDialTimeout         = 5 * time.Second
+	defaultProviderTLSTimeout          = 5 * time.Second
+	defaultProviderKeepAlive           = 30 * time.Second
+	defaultProviderIdleConnTimeout     = 90 * time.Second
+	defaultProviderMaxIdleConns        = 100
+	defaultProviderMaxIdleConnsPerHost = 10
+	defaultProviderUserAgent           = "identity-forecaster/1.0"
+	defaultBulkMaxItems                = 10000
+	defaultEnrichBatchSize             = 50
+	defaultEnrichWorkers               = 4
+	defaultDuplicatesInterval          = time.Hour
+	defaultDuplicatesMinScore          = 0.6
+	defaultRequireVersion              = false
+	defaultIdempotencyTTL              = 24 * time.Hour
+	defaultIdempotencyMaxBodySize      = 16 << 20
+	defaultExportWriteTimeout          = 30 * time.Second
+	envFile                            = ".env"
 )
 
 type Config struct {
-	ServiceHost               string `env:"HOST"`
-	ServicePort               string `env:"PORT"`
-	DatabaseDSN               string `env:"DSN"`
-	Logfile                   string `env:"LOGFILE"`
-	APIsStr                   string `env:"API"`
-	RetriesAmount             uint   `env:"RETRIES"`
-	RetryIntervalMilliseconds uint   `env:"INTERVAL"`
-	IsInContainer             bool   `env:"IN_CONTAINER"`
-	APIs                      []string
+	ServiceHost                 string        `env:"HOST"`
+	ServicePort                 string        `env:"PORT"`
+	DatabaseDSN                 string        `env:"DSN"`
+	Logfile                     string        `env:"LOGFILE"`
+	APIsStr                     string        `env:"API"`
+	RetriesAmount               uint          `env:"RETRIES"`
+	RetryIntervalMilliseconds   uint          `env:"INTERVAL"`
+	IsInContainer               bool          `env:"IN_CONTAINER"`
+	ProvidersFile               string        `env:"PROVIDERS_FILE"`
+	ProvidersRefreshInterval    uint          `env:"PROVIDERS_REFRESH_INTERVAL"`
+	AdminToken                  string        `env:"ADMIN_TOKEN"`
+	ProviderMode                string        `env:"PROVIDER_MODE"`
+	ProviderCassettesDir        string        `env:"PROVIDER_CASSETTES_DIR"`
+	ProviderResponsesRetention  time.Duration `env:"PROVIDER_RESPONSES_RETENTION"`
+	ProviderProxyURL            string        `env:"PROVIDER_PROXY_URL"`
+	ProviderCAFile              string        `env:"PROVIDER_CA_FILE"`
+	ProviderClientCertFile      string        `env:"PROVIDER_CLIENT_CERT_FILE"`
+	ProviderClientKeyFile       string        `env:"PROVIDER_CLIENT_KEY_FILE"`
+	ProviderTimeout             time.Duration `env:"PROVIDER_TIMEOUT"`
+	ProviderDialTimeout         time.Duration `env:"PROVIDER_DIAL_TIMEOUT"`
+	ProviderTLSTimeout          time.Duration `env:"PROVIDER_TLS_TIMEOUT"`
+	ProviderKeepAlive           time.Duration `env:"PROVIDER_KEEP_ALIVE"`
+	ProviderIdleConnTimeout     time.Duration `env:"PROVIDER_IDLE_CONN_TIMEOUT"`
+	ProviderMaxIdleConns        int           `env:"PROVIDER_MAX_IDLE_CONNS"`
+	ProviderMaxIdleConnsPerHost int           `env:"PROVIDER_MAX_IDLE_CONNS_PER_HOST"`
+	ProviderUserAgent           string        `env:"PROVIDER_USER_AGENT"`
+	BulkMaxItems                int           `env:"BULK_MAX_ITEMS"`
+	EnrichBatchSize             int           `env:"ENRICH_BATCH_SIZE"`
+	EnrichWorkers               int           `env:"ENRICH_WORKERS"`
+	DuplicatesInterval          time.Duration `env:"DUPLICATES_INTERVAL"`
+	DuplicatesMinScore          float64       `env:"DUPLICATES_MIN_SCORE"`
+	RequireVersion              bool          `env:"REQUIRE_VERSION"`
+	IdempotencyTTL              time.Duration `env:"IDEMPOTENCY_TTL"`
+	IdempotencyMaxBodySize      int64         `env:"IDEMPOTENCY_MAX_BODY_SIZE"`
+	ExportWriteTimeout          time.Duration `env:"EXPORT_WRITE_TIMEOUT"`
+	APIs                        []string
+	Providers                   []domain.ProviderConfig
 }
 
 func LoadConfig() *Config {
@@ -45,39 +97,102 @@ func LoadConfig() *Config {
 		alreadyInitialized = wasIsInContainerSet
 		if val == "true" {
 			envCfg = Config{
-				ServiceHost:               defaultContainerHost,
-				ServicePort:               defaultPort,
-				DatabaseDSN:               defaultContainerDSN,
-				Logfile:                   defaultLogfile,
-				RetriesAmount:             defaultRetriesAmount,
-				RetryIntervalMilliseconds: defaultRetryIntervalMilliseconds,
-				IsInContainer:             defaultIsInContainer,
-				APIsStr:                   defaultAPIs,
+				ServiceHost:                 defaultContainerHost,
+				ServicePort:                 defaultPort,
+				DatabaseDSN:                 defaultContainerDSN,
+				Logfile:                     defaultLogfile,
+				RetriesAmount:               defaultRetriesAmount,
+				RetryIntervalMilliseconds:   defaultRetryIntervalMilliseconds,
+				IsInContainer:               defaultIsInContainer,
+				APIsStr:                     defaultAPIs,
+				ProvidersRefreshInterval:    defaultProvidersRefreshInterval,
+				ProviderMode:                defaultProviderMode,
+				ProviderCassettesDir:        defaultProviderCassettesDir,
+				ProviderResponsesRetention:  defaultProviderResponsesRetention,
+				ProviderTimeout:             defaultProviderTimeout,
+				ProviderDialTimeout:         defaultProviderDialTimeout,
+				ProviderTLSTimeout:          defaultProviderTLSTimeout,
+				ProviderKeepAlive:           defaultProviderKeepAlive,
+				ProviderIdleConnTimeout:     defaultProviderIdleConnTimeout,
+				ProviderMaxIdleConns:        defaultProviderMaxIdleConns,
+				ProviderMaxIdleConnsPerHost: defaultProviderMaxIdleConnsPerHost,
+				ProviderUserAgent:           defaultProviderUserAgent,
+				BulkMaxItems:                defaultBulkMaxItems,
+				EnrichBatchSize:             defaultEnrichBatchSize,
+				EnrichWorkers:               defaultEnrichWorkers,
+				DuplicatesInterval:          defaultDuplicatesInterval,
+				DuplicatesMinScore:          defaultDuplicatesMinScore,
+				RequireVersion:              defaultRequireVersion,
+				IdempotencyTTL:              defaultIdempotencyTTL,
+				IdempotencyMaxBodySize:      defaultIdempotencyMaxBodySize,
+				ExportWriteTimeout:          defaultExportWriteTimeout,
 			}
 		} else {
 			envCfg = Config{
-				ServiceHost:               defaultHost,
-				ServicePort:               defaultPort,
-				DatabaseDSN:               defaultDSN,
-				Logfile:                   defaultLogfile,
-				RetriesAmount:             defaultRetriesAmount,
-				RetryIntervalMilliseconds: defaultRetryIntervalMilliseconds,
-				IsInContainer:             defaultIsInContainer,
-				APIsStr:                   defaultAPIs,
+				ServiceHost:                 defaultHost,
+				ServicePort:                 defaultPort,
+				DatabaseDSN:                 defaultDSN,
+				Logfile:                     defaultLogfile,
+				RetriesAmount:               defaultRetriesAmount,
+				RetryIntervalMilliseconds:   defaultRetryIntervalMilliseconds,
+				IsInContainer:               defaultIsInContainer,
+				APIsStr:                     defaultAPIs,
+				ProvidersRefreshInterval:    defaultProvidersRefreshInterval,
+				ProviderMode:                defaultProviderMode,
+				ProviderCassettesDir:        defaultProviderCassettesDir,
+				ProviderResponsesRetention:  defaultProviderResponsesRetention,
+				ProviderTimeout:             defaultProviderTimeout,
+				ProviderDialTimeout:         defaultProviderDialTimeout,
+				ProviderTLSTimeout:          defaultProviderTLSTimeout,
+				ProviderKeepAlive:           defaultProviderKeepAlive,
+				ProviderIdleConnTimeout:     defaultProviderIdleConnTimeout,
+				ProviderMaxIdleConns:        defaultProviderMaxIdleConns,
+				ProviderMaxIdleConnsPerHost: defaultProviderMaxIdleConnsPerHost,
+				ProviderUserAgent:           defaultProviderUserAgent,
+				BulkMaxItems:                defaultBulkMaxItems,
+				EnrichBatchSize:             defaultEnrichBatchSize,
+				EnrichWorkers:               defaultEnrichWorkers,
+				DuplicatesInterval:          defaultDuplicatesInterval,
+				DuplicatesMinScore:          defaultDuplicatesMinScore,
+				RequireVersion:              defaultRequireVersion,
+				IdempotencyTTL:              defaultIdempotencyTTL,
+				IdempotencyMaxBodySize:      defaultIdempotencyMaxBodySize,
+				ExportWriteTimeout:          defaultExportWriteTimeout,
 			}
 		}
 	}
 
 	if !alreadyInitialized {
 		envCfg = Config{
-			ServiceHost:               defaultHost,
-			ServicePort:               defaultPort,
-			DatabaseDSN:               defaultDSN,
-			Logfile:                   defaultLogfile,
-			RetriesAmount:             defaultRetriesAmount,
-			RetryIntervalMilliseconds: defaultRetryIntervalMilliseconds,
-			IsInContainer:             defaultIsInContainer,
-			APIsStr:                   defaultAPIs,
+			ServiceHost:                 defaultHost,
+			ServicePort:                 defaultPort,
+			DatabaseDSN:                 defaultDSN,
+			Logfile:                     defaultLogfile,
+			RetriesAmount:               defaultRetriesAmount,
+			RetryIntervalMilliseconds:   defaultRetryIntervalMilliseconds,
+			IsInContainer:               defaultIsInContainer,
+			APIsStr:                     defaultAPIs,
+			ProvidersRefreshInterval:    defaultProvidersRefreshInterval,
+			ProviderMode:                defaultProviderMode,
+			ProviderCassettesDir:        defaultProviderCassettesDir,
+			ProviderResponsesRetention:  defaultProviderResponsesRetention,
+			ProviderTimeout:             defaultProviderTimeout,
+			ProviderDialTimeout:         defaultProviderDialTimeout,
+			ProviderTLSTimeout:          defaultProviderTLSTimeout,
+			ProviderKeepAlive:           defaultProviderKeepAlive,
+			ProviderIdleConnTimeout:     defaultProviderIdleConnTimeout,
+			ProviderMaxIdleConns:        defaultProviderMaxIdleConns,
+			ProviderMaxIdleConnsPerHost: defaultProviderMaxIdleConnsPerHost,
+			ProviderUserAgent:           defaultProviderUserAgent,
+			BulkMaxItems:                defaultBulkMaxItems,
+			EnrichBatchSize:             defaultEnrichBatchSize,
+			EnrichWorkers:               defaultEnrichWorkers,
+			DuplicatesInterval:          defaultDuplicatesInterval,
+			DuplicatesMinScore:          defaultDuplicatesMinScore,
+			RequireVersion:              defaultRequireVersion,
+			IdempotencyTTL:              defaultIdempotencyTTL,
+			IdempotencyMaxBodySize:      defaultIdempotencyMaxBodySize,
+			ExportWriteTimeout:          defaultExportWriteTimeout,
 		}
 	}
 
@@ -92,6 +207,61 @@ func LoadConfig() *Config {
 
 	envCfg.APIs = strings.Split(envCfg.APIsStr, ",")
 
-	logger.Logger().Infoln(envCfg)
+	if envCfg.ProvidersFile != "" {
+		envCfg.Providers, err = loadProviders(envCfg.ProvidersFile)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		envCfg.Providers = providersFromAPIs(envCfg.APIs)
+	}
+
+	cfgToLog := envCfg
+	if cfgToLog.AdminToken != "" {
+		cfgToLog.AdminToken = "***"
+	}
+
+	logger.Logger().Infoln(cfgToLog)
 	return &envCfg
 }
+
+// loadProviders reads provider definitions from a YAML file, JSON files are accepted too as YAML is a superset of JSON
+func loadProviders(path string) ([]domain.ProviderConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file domain.ProvidersFile
+	if err = yaml.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+
+	return file.Providers, nil
+}
+
+// providersFromAPIs keeps the API variable working by describing the known public APIs the same way a providers file would
+func providersFromAPIs(apis []string) []domain.ProviderConfig {
+	providers := make([]domain.ProviderConfig, 0, len(apis))
+	for _, api := range apis {
+		cfg := domain.ProviderConfig{Name: api, Type: domain.ProviderTypeHTTP, URL: api, Input: "name", QueryParam: "name"}
+
+		if strings.Contains(api, "agify") {
+			cfg.Name = "agify"
+			cfg.Fields = map[string]string{domain.AttributeAge: "age"}
+		} else if strings.Contains(api, "genderize") {
+			cfg.Name = "genderize"
+			cfg.Fields = map[string]string{domain.AttributeGender: "gender"}
+			cfg.Probabilities = map[string]string{domain.AttributeGender: "probability"}
+		} else if strings.Contains(api, "nationalize") {
+			cfg.Name = "nationalize"
+			cfg.Input = "surname"
+			cfg.Fields = map[string]string{domain.AttributeNationality: "country[0].country_id"}
+			cfg.Probabilities = map[string]string{domain.AttributeNationality: "country[0].probability"}
+		}
+
+		providers = append(providers, cfg)
+	}
+
+	return providers
+}
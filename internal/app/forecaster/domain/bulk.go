@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"errors"
+
+	"identity-forecaster/pkg/validation"
+)
+
+type IdentifiedPerson struct {
+	ID int
+	Person
+}
+
+type BulkError struct {
+	Line  int    `json:"line" example:"2"`
+	Error string `json:"error" example:"required fields not provided"`
+	// Violations are set when the item was rejected by the validation of a person
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+type BulkSummary struct {
+	Accepted    int         `json:"accepted" example:"1"`
+	Skipped     int         `json:"skipped" example:"0"`
+	Rejected    int         `json:"rejected" example:"1"`
+	AcceptedIDs []int       `json:"accepted_ids"`
+	Errors      []BulkError `json:"errors"`
+}
+
+func (s *BulkSummary) Reject(line int, err error) {
+	s.Rejected++
+	bulkErr := BulkError{Line: line, Error: err.Error()}
+
+	var violations validation.Errors
+	if errors.As(err, &violations) {
+		bulkErr.Violations = violations
+	}
+
+	s.Errors = append(s.Errors, bulkErr)
+}
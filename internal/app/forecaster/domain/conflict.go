@@ -0,0 +1,32 @@
+package domain
+
+import appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+
+// ConflictMode tells what a create does when a person with the same name, surname and patronymic is already stored
+type ConflictMode string
+
+const (
+	// ConflictError fails the create with ErrUniqueViolation when the stored person is not deleted, a deleted one is revived
+	ConflictError ConflictMode = "error"
+	// ConflictIgnore keeps the stored person as it is, even when it is deleted
+	ConflictIgnore ConflictMode = "ignore"
+	// ConflictUpdate replaces the data from providers of the stored person, reviving it when it is deleted
+	ConflictUpdate ConflictMode = "update"
+	// ConflictRevive revives the stored person with the new data when it is deleted and keeps it as it is otherwise
+	ConflictRevive ConflictMode = "revive"
+)
+
+// DefaultConflictMode is the mode of the creates that do not choose one
+const DefaultConflictMode = ConflictRevive
+
+// ParseConflictMode returns DefaultConflictMode for an empty mode
+func ParseConflictMode(mode string) (ConflictMode, error) {
+	switch ConflictMode(mode) {
+	case "":
+		return DefaultConflictMode, nil
+	case ConflictError, ConflictIgnore, ConflictUpdate, ConflictRevive:
+		return ConflictMode(mode), nil
+	default:
+		return "", appErrors.ErrIncorrectQueryParam
+	}
+}
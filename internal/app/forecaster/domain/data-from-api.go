@@ -1,13 +1,115 @@
 package domain
 
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+)
+
+const (
+	AttributeAge         = "age"
+	AttributeGender      = "gender"
+	AttributeNationality = "nationality"
+)
+
 type DataFromAPI struct {
-	Age          int           `json:"age,omitempty"`
-	Gender       string        `json:"gender,omitempty"`
-	Nationality  string        `json:"nationality,omitempty"`
-	CountrySlice []CountryInfo `json:"country"`
+	Age           int                `json:"age,omitempty"`
+	Gender        string             `json:"gender,omitempty"`
+	Nationality   string             `json:"nationality,omitempty"`
+	Probabilities map[string]float64 `json:"probabilities,omitempty"`
+	RawResponses  []ProviderResponse `json:"-"`
+}
+
+type ProviderResponse struct {
+	ID         int               `json:"id" example:"1"`
+	PersonID   int               `json:"person_id" example:"1"`
+	Provider   string            `json:"provider" example:"agify"`
+	URL        string            `json:"url,omitempty" example:"https://api.agify.io/?name=Dmitriy"`
+	StatusCode int               `json:"status_code,omitempty" example:"200"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       json.RawMessage   `json:"body" swaggertype:"object"`
+	ReceivedAt time.Time         `json:"received_at" example:"2024-01-20T15:04:05Z"`
+}
+
+func IsKnownAttribute(attribute string) bool {
+	return attribute == AttributeAge || attribute == AttributeGender || attribute == AttributeNationality
+}
+
+func (d *DataFromAPI) SetAttribute(attribute string, value interface{}) error {
+	switch attribute {
+	case AttributeAge:
+		age, ok := value.(float64)
+		if !ok || age != math.Trunc(age) {
+			return appErrors.ErrWrongAttributeType
+		}
+
+		d.Age = int(age)
+	case AttributeGender:
+		gender, ok := value.(string)
+		if !ok {
+			return appErrors.ErrWrongAttributeType
+		}
+
+		d.Gender = gender
+	case AttributeNationality:
+		nationality, ok := value.(string)
+		if !ok {
+			return appErrors.ErrWrongAttributeType
+		}
+
+		d.Nationality = nationality
+	default:
+		return appErrors.ErrUnknownAttribute
+	}
+
+	return nil
+}
+
+func (d *DataFromAPI) SetProbability(attribute string, value interface{}) error {
+	if !IsKnownAttribute(attribute) {
+		return appErrors.ErrUnknownAttribute
+	}
+
+	probability, ok := value.(float64)
+	if !ok {
+		return appErrors.ErrWrongAttributeType
+	}
+
+	if d.Probabilities == nil {
+		d.Probabilities = make(map[string]float64)
+	}
+
+	d.Probabilities[attribute] = probability
+	return nil
 }
 
-type CountryInfo struct {
-	CountryID   string  `json:"country_id"`
-	Probability float32 `json:"probability"`
+// Merge fills the attributes which are still empty with the values from another provider.
+func (d *DataFromAPI) Merge(other DataFromAPI) {
+	if d.Age == 0 {
+		d.Age = other.Age
+	}
+
+	if d.Gender == "" {
+		d.Gender = other.Gender
+	}
+
+	if d.Nationality == "" {
+		d.Nationality = other.Nationality
+	}
+
+	for attribute, probability := range other.Probabilities {
+		if _, alreadySet := d.Probabilities[attribute]; alreadySet {
+			continue
+		}
+
+		if d.Probabilities == nil {
+			d.Probabilities = make(map[string]float64)
+		}
+
+		d.Probabilities[attribute] = probability
+	}
+
+	d.RawResponses = append(d.RawResponses, other.RawResponses...)
 }
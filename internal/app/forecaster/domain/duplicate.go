@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"time"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+)
+
+// DuplicatePair is a pair of persons the duplicates search considers to be the same person, Person has the lower id
+type DuplicatePair struct {
+	Person    PersonFromDB `json:"person"`
+	Duplicate PersonFromDB `json:"duplicate"`
+	// Score is 0.7 of NameScore and 0.3 of AttributeScore
+	Score float64 `json:"score" example:"0.86"`
+	// NameScore is the trigram similarity of the full names, 0.9 when the names only sound alike
+	NameScore float64 `json:"name_score" example:"0.8"`
+	// AttributeScore is the share of the attributes known for both persons that agree, ages agree within two years.
+	// It is 0.5 when no attribute is known for both
+	AttributeScore float64   `json:"attribute_score" example:"1"`
+	FoundAt        time.Time `json:"found_at"`
+}
+
+const (
+	MergeFromSurvivor = "survivor"
+	MergeFromMerged   = "merged"
+)
+
+// MergeFields are the fields of a person whose values may be chosen in a merge
+var MergeFields = []string{"name", "surname", "patronymic", AttributeAge, AttributeGender, AttributeNationality}
+
+// MergeRequest merges the person MergedID into the person SurvivorID, the merged person is deleted and its id leads to the survivor
+type MergeRequest struct {
+	SurvivorID int `json:"survivor_id" example:"1"`
+	MergedID   int `json:"merged_id" example:"2"`
+	// Fields maps a field of MergeFields to the person its value is taken from, survivor or merged.
+	// Fields which are not listed keep the value of the survivor unless it is empty
+	Fields map[string]string `json:"fields,omitempty" example:"name:merged"`
+}
+
+func (r MergeRequest) Validate() error {
+	if r.SurvivorID < 1 || r.MergedID < 1 || r.SurvivorID == r.MergedID {
+		return appErrors.ErrWrongMergeRequest
+	}
+
+	for field, source := range r.Fields {
+		if _, ok := (PersonFromDB{}).Column(field); !ok || field == "id" {
+			return appErrors.ErrUnknownAttribute
+		}
+
+		if source != MergeFromSurvivor && source != MergeFromMerged {
+			return appErrors.ErrWrongMergeRequest
+		}
+	}
+
+	return nil
+}
+
+// Apply returns the survivor with the values chosen by the request
+func (r MergeRequest) Apply(survivor PersonFromDB, merged PersonFromDB) PersonFromDB {
+	result := survivor
+	result.Score = nil
+
+	for _, field := range MergeFields {
+		source, ok := r.Fields[field]
+		if ok && source == MergeFromSurvivor {
+			continue
+		}
+
+		value, _ := survivor.Column(field)
+		if !ok && value != "" && value != 0 {
+			continue
+		}
+
+		switch field {
+		case "name":
+			result.Name = merged.Name
+		case "surname":
+			result.Surname = merged.Surname
+		case "patronymic":
+			result.Patronymic = merged.Patronymic
+		case AttributeAge:
+			result.Age = merged.Age
+		case AttributeGender:
+			result.Gender = merged.Gender
+		case AttributeNationality:
+			result.Nationality = merged.Nationality
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"strconv"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/pkg/rsql"
+)
+
+type FilterOperator string
+
+const (
+	OpEqual          FilterOperator = "=="
+	OpNotEqual       FilterOperator = "!="
+	OpLess           FilterOperator = "=lt="
+	OpLessOrEqual    FilterOperator = "=le="
+	OpGreater        FilterOperator = "=gt="
+	OpGreaterOrEqual FilterOperator = "=ge="
+	OpIn             FilterOperator = "=in="
+	OpOut            FilterOperator = "=out="
+	// OpIsNull takes true or false, a value is null when it is not set, which is also an empty string or zero age
+	OpIsNull FilterOperator = "=isnull="
+)
+
+// FilterExpression is either a FilterGroup or a FilterCondition
+type FilterExpression interface {
+	filterExpression()
+}
+
+type FilterGroup struct {
+	// Or joins the children with OR instead of AND
+	Or       bool
+	Children []FilterExpression
+}
+
+// FilterCondition compares a column of PersonColumns, Values have the type PersonFromDB.Column has for the column
+// and hold a single bool for OpIsNull
+type FilterCondition struct {
+	Column   string
+	Operator FilterOperator
+	Values   []interface{}
+}
+
+func (FilterGroup) filterExpression()     {}
+func (FilterCondition) filterExpression() {}
+
+// ParseFilterExpression parses an RSQL expression like nationality=in=(RU,UA);(gender==female,age=lt=30) and checks it
+// against the columns of a person, errors are *rsql.Error pointing to the offending token
+func ParseFilterExpression(expression string) (FilterExpression, error) {
+	node, err := rsql.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterExpressionFromNode(node)
+}
+
+func filterExpressionFromNode(node rsql.Node) (FilterExpression, error) {
+	switch node := node.(type) {
+	case rsql.Logical:
+		group := FilterGroup{Or: node.Operator == rsql.Or, Children: make([]FilterExpression, len(node.Children))}
+		for i, child := range node.Children {
+			var err error
+			if group.Children[i], err = filterExpressionFromNode(child); err != nil {
+				return nil, err
+			}
+		}
+
+		return group, nil
+	case rsql.Comparison:
+		return filterConditionFromComparison(node)
+	default:
+		return nil, appErrors.ErrUnsupportedFilterOperator
+	}
+}
+
+func filterConditionFromComparison(comparison rsql.Comparison) (FilterExpression, error) {
+	typed, ok := (PersonFromDB{}).Column(comparison.Selector.Value)
+	if !ok {
+		return nil, comparison.Selector.Errorf(appErrors.ErrUnknownAttribute)
+	}
+
+	condition := FilterCondition{Column: comparison.Selector.Value, Operator: FilterOperator(comparison.Operator.Value)}
+
+	switch condition.Operator {
+	case OpEqual, OpNotEqual, OpLess, OpLessOrEqual, OpGreater, OpGreaterOrEqual, OpIsNull:
+		if len(comparison.Arguments) != 1 {
+			return nil, comparison.Operator.Errorf(appErrors.ErrWrongFilterArguments)
+		}
+	case OpIn, OpOut:
+	default:
+		return nil, comparison.Operator.Errorf(appErrors.ErrUnsupportedFilterOperator)
+	}
+
+	for _, argument := range comparison.Arguments {
+		var value interface{} = argument.Value
+
+		var err error
+		switch {
+		case condition.Operator == OpIsNull:
+			value, err = strconv.ParseBool(argument.Value)
+		case isInt(typed):
+			value, err = strconv.Atoi(argument.Value)
+		}
+
+		if err != nil {
+			return nil, argument.Errorf(appErrors.ErrWrongAttributeType)
+		}
+
+		condition.Values = append(condition.Values, value)
+	}
+
+	return condition, nil
+}
+
+func isInt(value interface{}) bool {
+	_, ok := value.(int)
+	return ok
+}
@@ -17,6 +17,14 @@ type Filters struct {
 	PatronymicEqualTo  StrFilter
 	GenderEqualTo      StrFilter
 	NationalityEqualTo StrFilter
+	// TextMatches are case-insensitive substring and prefix matches of name, surname and patronymic
+	TextMatches []TextMatch
+	// SoundsLike are the phonetic keys of words each of which has to sound like the name, surname or patronymic
+	SoundsLike []string
+	// Query is the fuzzy search query matched against the full name, typos are tolerated
+	Query StrFilter
+	// Expression is applied together with the other filters, it is nil when not set
+	Expression FilterExpression
 }
 
 type IntMoreFilter struct {
@@ -57,3 +65,10 @@ func (f *StrFilter) Set(value string) {
 
 	f.Value = sql.NullString{String: value, Valid: true}
 }
+
+// TextMatch is a case-insensitive match of a column, Value is taken literally, without wildcards
+type TextMatch struct {
+	Column string
+	Prefix bool
+	Value  string
+}
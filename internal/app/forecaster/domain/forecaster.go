@@ -1,18 +1,48 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ForecasterService interface {
-	CreatePerson(ctx context.Context, person Person, dataFromAPI DataFromAPI) error
-	DeletePersonByID(ctx context.Context, id int) error
-	UpdatePerson(ctx context.Context, id int, data PersonWithAPIData) error
-	ReadPersons(ctx context.Context, page int, limit int, filters Filters) ([]PersonFromDB, error)
+	// CreatePerson returns the id of the created, revived or updated person, 0 when the stored person was kept as it is
+	CreatePerson(ctx context.Context, person Person, dataFromAPI DataFromAPI, mode ConflictMode) (int, error)
+	CreatePersons(ctx context.Context, persons []PersonWithAPIData, dryRun bool) ([]int, error)
+	EnrichPerson(ctx context.Context, id int, dataFromAPI DataFromAPI) error
+	DeletePersonByID(ctx context.Context, id int, version int) error
+	UpdatePerson(ctx context.Context, id int, data PersonWithAPIData, version int) (PersonFromDB, error)
+	PatchPerson(ctx context.Context, id int, patch PersonPatch, version int) (PersonFromDB, error)
+	ReplacePerson(ctx context.Context, id int, replacement PersonReplacement, version int) (PersonFromDB, error)
+	ReadPersons(ctx context.Context, page Page, filters Filters) (PersonsPage, error)
+	ReadPersonByID(ctx context.Context, id int) (PersonFromDB, error)
+	StreamPersons(ctx context.Context, filters Filters, fn func(PersonFromDB) error) error
+	ReadProviderResponses(ctx context.Context, personID int) ([]ProviderResponse, error)
+	DeleteProviderResponsesOlderThan(ctx context.Context, age time.Duration) (int64, error)
+	FindDuplicates(ctx context.Context, minScore float64) (int64, error)
+	ReadDuplicates(ctx context.Context, minScore float64, limit int, offset int) ([]DuplicatePair, error)
+	MergePersons(ctx context.Context, request MergeRequest) (PersonFromDB, error)
 }
 
 //go:generate mockgen -destination=mocks/forecaster_repo_mock.gen.go -package=mocks . ForecasterRepository
 type ForecasterRepository interface {
-	CreatePerson(ctx context.Context, person Person, dataFromAPI DataFromAPI) error
-	DeletePersonByID(ctx context.Context, id int) error
-	UpdatePerson(ctx context.Context, id int, data PersonWithAPIData) error
-	ReadPersons(ctx context.Context, page int, limit int, filters Filters) ([]PersonFromDB, error)
+	CreatePerson(ctx context.Context, person Person, dataFromAPI DataFromAPI, mode ConflictMode) (int, error)
+	CreatePersons(ctx context.Context, persons []PersonWithAPIData, dryRun bool) ([]int, error)
+	EnrichPerson(ctx context.Context, id int, dataFromAPI DataFromAPI) error
+	DeletePersonByID(ctx context.Context, id int, version int) error
+	UpdatePerson(ctx context.Context, id int, data PersonWithAPIData, version int) (PersonFromDB, error)
+	// ModifyPerson replaces the person with the result of modify, nothing else changes the person in between
+	ModifyPerson(ctx context.Context, id int, version int, modify func(PersonFromDB) (PersonFromDB, error)) (PersonFromDB, error)
+	ReadPersons(ctx context.Context, page Page, filters Filters) (PersonsPage, error)
+	ReadPersonByID(ctx context.Context, id int) (PersonFromDB, error)
+	StreamPersons(ctx context.Context, filters Filters, fn func(PersonFromDB) error) error
+	ReadProviderResponses(ctx context.Context, personID int) ([]ProviderResponse, error)
+	DeleteProviderResponsesOlderThan(ctx context.Context, age time.Duration) (int64, error)
+	FindDuplicates(ctx context.Context, minScore float64) (int64, error)
+	ReadDuplicates(ctx context.Context, minScore float64, limit int, offset int) ([]DuplicatePair, error)
+	MergePersons(ctx context.Context, request MergeRequest) (PersonFromDB, error)
+}
+
+type Enricher interface {
+	Enqueue(persons []IdentifiedPerson)
 }
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentResponse is the outcome of a request made with an Idempotency-Key, which is sent again to the retries of the request
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+type IdempotencyService interface {
+	// BeginRequest claims the key for the request with the hash by the token unique to the request. It returns the stored
+	// response when the request was already made, ErrIdempotencyKeyReused when the key was used for another request and
+	// ErrIdempotencyKeyInProgress when the request with the key is still being processed
+	BeginRequest(ctx context.Context, key string, token string, requestHash string, ttl time.Duration) (*IdempotentResponse, error)
+	// CompleteRequest stores the response while the key is claimed by the token, otherwise it returns ErrNoRowsAffected
+	CompleteRequest(ctx context.Context, key string, token string, response IdempotentResponse) error
+	// AbandonRequest releases the key claimed by the token, so that the request can be retried
+	AbandonRequest(ctx context.Context, key string, token string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+//go:generate mockgen -destination=mocks/idempotency_repo_mock.gen.go -package=mocks . IdempotencyRepository
+type IdempotencyRepository interface {
+	BeginRequest(ctx context.Context, key string, token string, requestHash string, ttl time.Duration) (*IdempotentResponse, error)
+	CompleteRequest(ctx context.Context, key string, token string, response IdempotentResponse) error
+	AbandonRequest(ctx context.Context, key string, token string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/pkg/validation"
+)
+
+const (
+	ImportStatusCreated  = "created"
+	ImportStatusSkipped  = "skipped"
+	ImportStatusRejected = "rejected"
+
+	EncodingUTF8        = "utf-8"
+	EncodingWindows1251 = "windows-1251"
+)
+
+// ImportFields are the fields of a person a CSV column can be mapped to
+var ImportFields = []string{"name", "surname", "patronymic", AttributeAge, AttributeGender, AttributeNationality}
+
+type Importer interface {
+	ImportCSV(ctx context.Context, r io.Reader, opts CSVImportOptions) (ImportReport, error)
+	ImportVCard(ctx context.Context, r io.Reader, dryRun bool) (ImportReport, error)
+}
+
+type CSVImportOptions struct {
+	Delimiter rune
+	Encoding  string
+	// Mapping maps headers of the file to fields of a person, headers named as the fields are mapped implicitly
+	Mapping map[string]string
+	DryRun  bool
+}
+
+type ImportRow struct {
+	Line       int    `json:"line" example:"2"`
+	Status     string `json:"status" example:"created" enums:"created,skipped,rejected"`
+	ID         int    `json:"id,omitempty" example:"1"`
+	Name       string `json:"name,omitempty" example:"Dmitriy"`
+	Surname    string `json:"surname,omitempty" example:"Smirnov"`
+	Patronymic string `json:"patronymic,omitempty" example:"Petrovich"`
+	Error      string `json:"error,omitempty"`
+	// Violations are set when the row was rejected by the validation of a person, the CSV report has only Error
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun   bool        `json:"dry_run" example:"false"`
+	Created  int         `json:"created" example:"1"`
+	Skipped  int         `json:"skipped" example:"0"`
+	Rejected int         `json:"rejected" example:"0"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+// ParseCSVImportOptions builds options from their text form, e.g. delimiter ";" or "tab" and mapping "Фамилия:surname,Имя:name"
+func ParseCSVImportOptions(delimiter string, encoding string, mapping string, dryRun bool) (CSVImportOptions, error) {
+	opts := CSVImportOptions{Delimiter: ',', Encoding: EncodingUTF8, Mapping: make(map[string]string), DryRun: dryRun}
+
+	switch delimiter {
+	case "":
+	case "tab", "\\t":
+		opts.Delimiter = '\t'
+	default:
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return CSVImportOptions{}, appErrors.ErrWrongCSVOptions
+		}
+
+		opts.Delimiter = r
+	}
+
+	if encoding != "" {
+		opts.Encoding = strings.ToLower(encoding)
+	}
+
+	if opts.Encoding != EncodingUTF8 && opts.Encoding != EncodingWindows1251 {
+		return CSVImportOptions{}, appErrors.ErrWrongCSVOptions
+	}
+
+	if mapping == "" {
+		return opts, nil
+	}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		header, field, found := strings.Cut(pair, ":")
+		if !found || strings.TrimSpace(header) == "" || !IsImportField(strings.TrimSpace(field)) {
+			return CSVImportOptions{}, appErrors.ErrWrongCSVOptions
+		}
+
+		opts.Mapping[strings.TrimSpace(header)] = strings.TrimSpace(field)
+	}
+
+	return opts, nil
+}
+
+func IsImportField(field string) bool {
+	for _, known := range ImportFields {
+		if field == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *ImportReport) Add(row ImportRow) {
+	switch row.Status {
+	case ImportStatusCreated:
+		r.Created++
+	case ImportStatusSkipped:
+		r.Skipped++
+	case ImportStatusRejected:
+		r.Rejected++
+	}
+
+	r.Rows = append(r.Rows, row)
+}
+
+func (r ImportReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"line", "status", "id", "name", "surname", "patronymic", "error"})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range r.Rows {
+		id := ""
+		if row.ID != 0 {
+			id = strconv.Itoa(row.ID)
+		}
+
+		err = writer.Write([]string{strconv.Itoa(row.Line), row.Status, id, row.Name, row.Surname, row.Patronymic, row.Error})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
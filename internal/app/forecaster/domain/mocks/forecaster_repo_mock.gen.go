@@ -8,6 +8,7 @@ import (
 	context "context"
 	domain "identity-forecaster/internal/app/forecaster/domain"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -36,58 +37,208 @@ func (m *MockForecasterRepository) EXPECT() *MockForecasterRepositoryMockRecorde
 }
 
 // CreatePerson mocks base method.
-func (m *MockForecasterRepository) CreatePerson(arg0 context.Context, arg1 domain.Person, arg2 domain.DataFromAPI) error {
+func (m *MockForecasterRepository) CreatePerson(arg0 context.Context, arg1 domain.Person, arg2 domain.DataFromAPI, arg3 domain.ConflictMode) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePerson", arg0, arg1, arg2)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "CreatePerson", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreatePerson indicates an expected call of CreatePerson.
-func (mr *MockForecasterRepositoryMockRecorder) CreatePerson(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockForecasterRepositoryMockRecorder) CreatePerson(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePerson", reflect.TypeOf((*MockForecasterRepository)(nil).CreatePerson), arg0, arg1, arg2, arg3)
+}
+
+// CreatePersons mocks base method.
+func (m *MockForecasterRepository) CreatePersons(arg0 context.Context, arg1 []domain.PersonWithAPIData, arg2 bool) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePersons", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePersons indicates an expected call of CreatePersons.
+func (mr *MockForecasterRepositoryMockRecorder) CreatePersons(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePerson", reflect.TypeOf((*MockForecasterRepository)(nil).CreatePerson), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePersons", reflect.TypeOf((*MockForecasterRepository)(nil).CreatePersons), arg0, arg1, arg2)
 }
 
 // DeletePersonByID mocks base method.
-func (m *MockForecasterRepository) DeletePersonByID(arg0 context.Context, arg1 int) error {
+func (m *MockForecasterRepository) DeletePersonByID(arg0 context.Context, arg1, arg2 int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeletePersonByID", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeletePersonByID", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeletePersonByID indicates an expected call of DeletePersonByID.
-func (mr *MockForecasterRepositoryMockRecorder) DeletePersonByID(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockForecasterRepositoryMockRecorder) DeletePersonByID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePersonByID", reflect.TypeOf((*MockForecasterRepository)(nil).DeletePersonByID), arg0, arg1, arg2)
+}
+
+// DeleteProviderResponsesOlderThan mocks base method.
+func (m *MockForecasterRepository) DeleteProviderResponsesOlderThan(arg0 context.Context, arg1 time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProviderResponsesOlderThan", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProviderResponsesOlderThan indicates an expected call of DeleteProviderResponsesOlderThan.
+func (mr *MockForecasterRepositoryMockRecorder) DeleteProviderResponsesOlderThan(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProviderResponsesOlderThan", reflect.TypeOf((*MockForecasterRepository)(nil).DeleteProviderResponsesOlderThan), arg0, arg1)
+}
+
+// EnrichPerson mocks base method.
+func (m *MockForecasterRepository) EnrichPerson(arg0 context.Context, arg1 int, arg2 domain.DataFromAPI) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrichPerson", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnrichPerson indicates an expected call of EnrichPerson.
+func (mr *MockForecasterRepositoryMockRecorder) EnrichPerson(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrichPerson", reflect.TypeOf((*MockForecasterRepository)(nil).EnrichPerson), arg0, arg1, arg2)
+}
+
+// FindDuplicates mocks base method.
+func (m *MockForecasterRepository) FindDuplicates(arg0 context.Context, arg1 float64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDuplicates", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDuplicates indicates an expected call of FindDuplicates.
+func (mr *MockForecasterRepositoryMockRecorder) FindDuplicates(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDuplicates", reflect.TypeOf((*MockForecasterRepository)(nil).FindDuplicates), arg0, arg1)
+}
+
+// MergePersons mocks base method.
+func (m *MockForecasterRepository) MergePersons(arg0 context.Context, arg1 domain.MergeRequest) (domain.PersonFromDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergePersons", arg0, arg1)
+	ret0, _ := ret[0].(domain.PersonFromDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergePersons indicates an expected call of MergePersons.
+func (mr *MockForecasterRepositoryMockRecorder) MergePersons(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergePersons", reflect.TypeOf((*MockForecasterRepository)(nil).MergePersons), arg0, arg1)
+}
+
+// ModifyPerson mocks base method.
+func (m *MockForecasterRepository) ModifyPerson(arg0 context.Context, arg1, arg2 int, arg3 func(domain.PersonFromDB) (domain.PersonFromDB, error)) (domain.PersonFromDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModifyPerson", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.PersonFromDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModifyPerson indicates an expected call of ModifyPerson.
+func (mr *MockForecasterRepositoryMockRecorder) ModifyPerson(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyPerson", reflect.TypeOf((*MockForecasterRepository)(nil).ModifyPerson), arg0, arg1, arg2, arg3)
+}
+
+// ReadDuplicates mocks base method.
+func (m *MockForecasterRepository) ReadDuplicates(arg0 context.Context, arg1 float64, arg2, arg3 int) ([]domain.DuplicatePair, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadDuplicates", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]domain.DuplicatePair)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadDuplicates indicates an expected call of ReadDuplicates.
+func (mr *MockForecasterRepositoryMockRecorder) ReadDuplicates(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePersonByID", reflect.TypeOf((*MockForecasterRepository)(nil).DeletePersonByID), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadDuplicates", reflect.TypeOf((*MockForecasterRepository)(nil).ReadDuplicates), arg0, arg1, arg2, arg3)
+}
+
+// ReadPersonByID mocks base method.
+func (m *MockForecasterRepository) ReadPersonByID(arg0 context.Context, arg1 int) (domain.PersonFromDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPersonByID", arg0, arg1)
+	ret0, _ := ret[0].(domain.PersonFromDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPersonByID indicates an expected call of ReadPersonByID.
+func (mr *MockForecasterRepositoryMockRecorder) ReadPersonByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPersonByID", reflect.TypeOf((*MockForecasterRepository)(nil).ReadPersonByID), arg0, arg1)
 }
 
 // ReadPersons mocks base method.
-func (m *MockForecasterRepository) ReadPersons(arg0 context.Context, arg1, arg2 int, arg3 domain.Filters) ([]domain.PersonFromDB, error) {
+func (m *MockForecasterRepository) ReadPersons(arg0 context.Context, arg1 domain.Page, arg2 domain.Filters) (domain.PersonsPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReadPersons", arg0, arg1, arg2, arg3)
-	ret0, _ := ret[0].([]domain.PersonFromDB)
+	ret := m.ctrl.Call(m, "ReadPersons", arg0, arg1, arg2)
+	ret0, _ := ret[0].(domain.PersonsPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ReadPersons indicates an expected call of ReadPersons.
-func (mr *MockForecasterRepositoryMockRecorder) ReadPersons(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockForecasterRepositoryMockRecorder) ReadPersons(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPersons", reflect.TypeOf((*MockForecasterRepository)(nil).ReadPersons), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPersons", reflect.TypeOf((*MockForecasterRepository)(nil).ReadPersons), arg0, arg1, arg2)
 }
 
-// UpdatePerson mocks base method.
-func (m *MockForecasterRepository) UpdatePerson(arg0 context.Context, arg1 int, arg2 domain.PersonWithAPIData) error {
+// ReadProviderResponses mocks base method.
+func (m *MockForecasterRepository) ReadProviderResponses(arg0 context.Context, arg1 int) ([]domain.ProviderResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdatePerson", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "ReadProviderResponses", arg0, arg1)
+	ret0, _ := ret[0].([]domain.ProviderResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadProviderResponses indicates an expected call of ReadProviderResponses.
+func (mr *MockForecasterRepositoryMockRecorder) ReadProviderResponses(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadProviderResponses", reflect.TypeOf((*MockForecasterRepository)(nil).ReadProviderResponses), arg0, arg1)
+}
+
+// StreamPersons mocks base method.
+func (m *MockForecasterRepository) StreamPersons(arg0 context.Context, arg1 domain.Filters, arg2 func(domain.PersonFromDB) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamPersons", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// StreamPersons indicates an expected call of StreamPersons.
+func (mr *MockForecasterRepositoryMockRecorder) StreamPersons(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamPersons", reflect.TypeOf((*MockForecasterRepository)(nil).StreamPersons), arg0, arg1, arg2)
+}
+
+// UpdatePerson mocks base method.
+func (m *MockForecasterRepository) UpdatePerson(arg0 context.Context, arg1 int, arg2 domain.PersonWithAPIData, arg3 int) (domain.PersonFromDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePerson", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(domain.PersonFromDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // UpdatePerson indicates an expected call of UpdatePerson.
-func (mr *MockForecasterRepositoryMockRecorder) UpdatePerson(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockForecasterRepositoryMockRecorder) UpdatePerson(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePerson", reflect.TypeOf((*MockForecasterRepository)(nil).UpdatePerson), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePerson", reflect.TypeOf((*MockForecasterRepository)(nil).UpdatePerson), arg0, arg1, arg2, arg3)
 }
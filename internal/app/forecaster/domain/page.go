@@ -0,0 +1,244 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+)
+
+// SortField is a column of PersonColumns and the direction of ordering by it
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// Sort is the order of persons, rows equal by every field are ordered by id so that pages do not overlap
+type Sort []SortField
+
+// DefaultSort orders persons by id, it is used when no sort is requested
+var DefaultSort = Sort{{Column: "id"}}
+
+// RelevanceColumn is the similarity of a person to the fuzzy search query, it may be sorted by only when Filters.Query is set
+const RelevanceColumn = "score"
+
+// RelevanceSort puts the persons most similar to the fuzzy search query first
+var RelevanceSort = Sort{{Column: RelevanceColumn, Desc: true}}
+
+// ParseSort reads a comma separated list of columns like -age,surname,name, a minus means descending order.
+// Every column may be used once, an empty string means DefaultSort
+func ParseSort(s string) (Sort, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultSort, nil
+	}
+
+	keys := strings.Split(s, ",")
+	sort := make(Sort, 0, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+
+		field := SortField{Column: key}
+		if strings.HasPrefix(key, "-") {
+			field = SortField{Column: key[1:], Desc: true}
+		}
+
+		if _, ok := (PersonFromDB{}).sortValue(field.Column); !ok {
+			return nil, appErrors.ErrIncorrectQueryParam
+		}
+
+		if _, ok := seen[field.Column]; ok {
+			return nil, appErrors.ErrIncorrectQueryParam
+		}
+		seen[field.Column] = struct{}{}
+
+		sort = append(sort, field)
+	}
+
+	return sort, nil
+}
+
+func (s Sort) String() string {
+	fields := make([]string, len(s))
+	for i, field := range s {
+		fields[i] = field.Column
+		if field.Desc {
+			fields[i] = "-" + field.Column
+		}
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// HasRelevance tells whether the persons are sorted by RelevanceColumn
+func (s Sort) HasRelevance() bool {
+	for _, field := range s {
+		if field.Column == RelevanceColumn {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Keys returns the fields rows are actually ordered by, which is the sort itself with ascending id appended if it is missing
+func (s Sort) Keys() Sort {
+	for _, field := range s {
+		if field.Column == "id" {
+			return s
+		}
+	}
+
+	return append(s[:len(s):len(s)], SortField{Column: "id"})
+}
+
+// Cursor points to the last row of a page, the next page starts right after it.
+// Values are the values of Sort.Keys of that row
+type Cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// CursorAfter makes a cursor pointing to the person
+func CursorAfter(sort Sort, person PersonFromDB) Cursor {
+	keys := sort.Keys()
+
+	cursor := Cursor{Sort: sort.String(), Values: make([]interface{}, len(keys))}
+	for i, key := range keys {
+		cursor.Values[i], _ = person.sortValue(key.Column)
+	}
+
+	return cursor
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor reverts Cursor.Encode, the cursor has to be made for the same sort
+func DecodeCursor(token string, sort Sort) (Cursor, error) {
+	var cursor Cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, appErrors.ErrWrongCursor
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	keys := sort.Keys()
+	if err = d.Decode(&cursor); err != nil || cursor.Sort != sort.String() || len(cursor.Values) != len(keys) {
+		return cursor, appErrors.ErrWrongCursor
+	}
+
+	for i, key := range keys {
+		if cursor.Values[i], err = columnValue(key.Column, cursor.Values[i]); err != nil {
+			return cursor, err
+		}
+	}
+
+	return cursor, nil
+}
+
+// sortValue returns the value of a column persons can be sorted by, which is a column of PersonColumns or RelevanceColumn
+func (p PersonFromDB) sortValue(column string) (interface{}, bool) {
+	if column != RelevanceColumn {
+		return p.Column(column)
+	}
+
+	if p.Score == nil {
+		return 0.0, true
+	}
+
+	return *p.Score, true
+}
+
+// columnValue converts a decoded JSON value to the type PersonFromDB.sortValue has for the column
+func columnValue(column string, value interface{}) (interface{}, error) {
+	typed, _ := (PersonFromDB{}).sortValue(column)
+
+	switch typed.(type) {
+	case float64:
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, appErrors.ErrWrongCursor
+		}
+
+		f, err := number.Float64()
+		if err != nil {
+			return nil, appErrors.ErrWrongCursor
+		}
+
+		return f, nil
+	case int:
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, appErrors.ErrWrongCursor
+		}
+
+		n, err := number.Int64()
+		if err != nil {
+			return nil, appErrors.ErrWrongCursor
+		}
+
+		return int(n), nil
+	default:
+		str, ok := value.(string)
+		if !ok {
+			return nil, appErrors.ErrWrongCursor
+		}
+
+		return str, nil
+	}
+}
+
+// CountMode tells how the total number of persons matching the filters is obtained
+type CountMode string
+
+const (
+	CountExact CountMode = "exact"
+	// CountEstimate takes the number of rows expected by the query planner from the table statistics, it does not scan the table
+	CountEstimate CountMode = "estimate"
+	CountNone     CountMode = "none"
+)
+
+// ParseCountMode checks the mode, an empty string means byDefault
+func ParseCountMode(s string, byDefault CountMode) (CountMode, error) {
+	switch mode := CountMode(s); mode {
+	case "":
+		return byDefault, nil
+	case CountExact, CountEstimate, CountNone:
+		return mode, nil
+	default:
+		return "", appErrors.ErrIncorrectQueryParam
+	}
+}
+
+// Page selects persons either by page number, which skips (Number-1)*Limit rows, or after a cursor when After is set
+type Page struct {
+	Number int
+	Limit  int
+	Sort   Sort
+	After  *Cursor
+	Count  CountMode
+}
+
+type PersonsPage struct {
+	Items []PersonFromDB `json:"items"`
+	// Total is nil when counting is turned off with CountNone
+	Total          *int64 `json:"total,omitempty" example:"120"`
+	TotalEstimated bool   `json:"total_estimated,omitempty" example:"false"`
+	Page           int    `json:"page,omitempty" example:"2"`
+	Limit          int    `json:"limit,omitempty" example:"10"`
+	Next           string `json:"next,omitempty" example:"/read?limit=10&page=3"`
+	Prev           string `json:"prev,omitempty" example:"/read?limit=10&page=1"`
+	// NextCursor is empty when there are no more persons
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJ2IjpbMTBdfQ"`
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+)
+
+// VersionMember is the member of a patch or a replacement holding the version the changes are made to, it is not a value to set
+const VersionMember = "version"
+
+// PersonPatch is a JSON merge patch of a person (RFC 7396): a member that is absent keeps the value and null clears it,
+// which is an empty string or zero age. Name and surname can not be cleared
+type PersonPatch map[string]json.RawMessage
+
+// ParsePersonPatch checks that the patch is an object of the columns a client may change with values of their types
+func ParsePersonPatch(body []byte) (PersonPatch, error) {
+	var patch PersonPatch
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return nil, appErrors.ErrWrongPatch
+	}
+
+	if _, err := patch.Version(); err != nil {
+		return nil, err
+	}
+
+	// the values are checked by applying them to an empty person, the members which are absent stay empty and are not checked
+	var person PersonFromDB
+	for member, value := range patch {
+		if member == VersionMember {
+			continue
+		}
+
+		if err := person.setColumn(member, value); err != nil {
+			return nil, err
+		}
+	}
+
+	_, hasName := patch["name"]
+	_, hasSurname := patch["surname"]
+	if hasName && person.Name == "" || hasSurname && person.Surname == "" {
+		return nil, appErrors.ErrRequiredFieldsNotProvided
+	}
+
+	if err := person.withAPIData().Validate(); err != nil {
+		return nil, err
+	}
+
+	return patch, nil
+}
+
+// Version returns the version member, nil when it is absent or null
+func (p PersonPatch) Version() (*int, error) {
+	value, ok := p[VersionMember]
+	if !ok || isNull(value) {
+		return nil, nil
+	}
+
+	var version int
+	if err := json.Unmarshal(value, &version); err != nil {
+		return nil, appErrors.ErrWrongAttributeType
+	}
+
+	return &version, nil
+}
+
+// Apply returns the person with the patch applied
+func (p PersonPatch) Apply(person PersonFromDB) (PersonFromDB, error) {
+	for member, value := range p {
+		if member == VersionMember {
+			continue
+		}
+
+		if err := person.setColumn(member, value); err != nil {
+			return PersonFromDB{}, err
+		}
+	}
+
+	if person.Name == "" || person.Surname == "" {
+		return PersonFromDB{}, appErrors.ErrRequiredFieldsNotProvided
+	}
+
+	return person, nil
+}
+
+// PersonReplacement is the full representation of a person, every field is required and may be null only in JSON,
+// an empty string or zero age clears the value
+type PersonReplacement struct {
+	Name        *string `json:"name" example:"Dmitriy"`
+	Surname     *string `json:"surname" example:"Smirnov"`
+	Patronymic  *string `json:"patronymic" example:"Petrovich"`
+	Age         *int    `json:"age" example:"25"`
+	Gender      *string `json:"gender" example:"male"`
+	Nationality *string `json:"nationality" example:"RU"`
+	// Version makes the replacement fail when the person was changed since, it is not checked when empty
+	Version *int `json:"version,omitempty" example:"3"`
+}
+
+func (r PersonReplacement) Validate() error {
+	if r.Name == nil || r.Surname == nil || r.Patronymic == nil || r.Age == nil || r.Gender == nil || r.Nationality == nil ||
+		*r.Name == "" || *r.Surname == "" {
+		return appErrors.ErrRequiredFieldsNotProvided
+	}
+
+	return PersonWithAPIData{Name: *r.Name, Surname: *r.Surname, Patronymic: *r.Patronymic, Age: *r.Age, Gender: *r.Gender,
+		Nationality: *r.Nationality}.Validate()
+}
+
+// Apply returns the person with all the values replaced, only the id and the version are kept
+func (r PersonReplacement) Apply(person PersonFromDB) (PersonFromDB, error) {
+	if err := r.Validate(); err != nil {
+		return PersonFromDB{}, err
+	}
+
+	person.Name, person.Surname, person.Patronymic = *r.Name, *r.Surname, *r.Patronymic
+	person.Age, person.Gender, person.Nationality = *r.Age, *r.Gender, *r.Nationality
+
+	return person, nil
+}
+
+// setColumn sets a column of PersonColumns other than id from a JSON value, null sets the zero value
+func (p *PersonFromDB) setColumn(column string, value json.RawMessage) error {
+	current, ok := p.Column(column)
+	if !ok || column == "id" {
+		return appErrors.ErrUnknownAttribute
+	}
+
+	if _, ok = current.(int); ok {
+		var age int
+		if !isNull(value) && json.Unmarshal(value, &age) != nil {
+			return appErrors.ErrWrongAttributeType
+		}
+
+		p.Age = age
+		return nil
+	}
+
+	var str string
+	if !isNull(value) && json.Unmarshal(value, &str) != nil {
+		return appErrors.ErrWrongAttributeType
+	}
+
+	switch column {
+	case "name":
+		p.Name = str
+	case "surname":
+		p.Surname = str
+	case "patronymic":
+		p.Patronymic = str
+	case AttributeGender:
+		p.Gender = str
+	case AttributeNationality:
+		p.Nationality = str
+	}
+
+	return nil
+}
+
+func (p PersonFromDB) withAPIData() PersonWithAPIData {
+	return PersonWithAPIData{Name: p.Name, Surname: p.Surname, Patronymic: p.Patronymic, Age: p.Age, Gender: p.Gender,
+		Nationality: p.Nationality}
+}
+
+func isNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
@@ -1,11 +1,27 @@
 package domain
 
+import (
+	"strings"
+	"time"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/pkg/validation"
+)
+
 type Person struct {
-	Name       string `json:"name" example:"Dmitriy"`
-	Surname    string `json:"surname" example:"Smirnov"`
-	Patronymic string `json:"patronymic,omitempty" example:"Petrovich"`
+	Name       string `json:"name" example:"Dmitriy" validate:"required,max=100,script=Latin Cyrillic"`
+	Surname    string `json:"surname" example:"Smirnov" validate:"required,max=100,script=Latin Cyrillic"`
+	Patronymic string `json:"patronymic,omitempty" example:"Petrovich" validate:"max=100,script=Latin Cyrillic"`
+}
+
+// Validate checks the person by the rules of its validate tags, the error is validation.Errors with all the violations
+func (p Person) Validate() error {
+	return validation.Struct(p)
 }
 
+// PersonColumns are the columns of a stored person in their default order
+var PersonColumns = []string{"id", "name", "surname", "patronymic", AttributeAge, AttributeGender, AttributeNationality}
+
 type PersonFromDB struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
@@ -14,18 +30,42 @@ type PersonFromDB struct {
 	Age         int    `json:"age"`
 	Gender      string `json:"gender"`
 	Nationality string `json:"nationality"`
+	// Score is the similarity to the fuzzy search query, it is set only when the query is
+	Score *float64 `json:"score,omitempty"`
+	// Version is incremented by every change of the person, including the data from providers added in the background right
+	// after it is created, it is sent only when it was read
+	Version int `json:"version,omitempty" example:"3"`
+	// UpdatedAt is the time of the last change, it is sent in the Last-Modified header of a single person
+	UpdatedAt time.Time `json:"-"`
 }
 
 type PersonWithAPIData struct {
-	Name        string `json:"name,omitempty" example:"Dmitriy"`
-	Surname     string `json:"surname,omitempty" example:"Smirnov"`
-	Patronymic  string `json:"patronymic,omitempty" example:"Petrovich"`
-	Age         int    `json:"age,omitempty" example:"25"`
-	Gender      string `json:"gender,omitempty" example:"male"`
-	Nationality string `json:"nationality,omitempty" example:"RU"`
+	Name        string `json:"name,omitempty" example:"Dmitriy" validate:"max=100,script=Latin Cyrillic"`
+	Surname     string `json:"surname,omitempty" example:"Smirnov" validate:"max=100,script=Latin Cyrillic"`
+	Patronymic  string `json:"patronymic,omitempty" example:"Petrovich" validate:"max=100,script=Latin Cyrillic"`
+	Age         int    `json:"age,omitempty" example:"25" validate:"min=0,max=150"`
+	Gender      string `json:"gender,omitempty" example:"male" validate:"oneof=male female"`
+	Nationality string `json:"nationality,omitempty" example:"RU" validate:"iso3166"`
 	IsDeleted   *bool  `json:"is_deleted,omitempty" example:"false"`
 }
 
+// Validate checks the values which are set, so it fits partial updates, the error is validation.Errors with all the violations
+func (p PersonWithAPIData) Validate() error {
+	return validation.Struct(p)
+}
+
+// HasAPIData tells whether all the attributes providers are asked for are already set, so there is nothing to fetch
+func (p PersonWithAPIData) HasAPIData() bool {
+	return p.Age != 0 && p.Gender != "" && p.Nationality != ""
+}
+
+// PersonUpdate is PersonWithAPIData with the version of the person the changes are made to
+type PersonUpdate struct {
+	PersonWithAPIData
+	// Version makes the update fail when the person was changed since, it is not checked when empty
+	Version *int `json:"version,omitempty" example:"3"`
+}
+
 func (p *PersonWithAPIData) ReplaceDefaultValuesWithFieldsOfStruct(newValues PersonWithAPIData) {
 	if p.Name == "" {
 		p.Name = newValues.Name
@@ -56,8 +96,72 @@ func (p *PersonWithAPIData) ReplaceDefaultValuesWithFieldsOfStruct(newValues Per
 		return
 	}
 
+	// persons stored before is_deleted was always set have it NULL, which means they are not deleted
 	if p.IsDeleted == nil {
-		isDeleted := true
+		isDeleted := false
 		p.IsDeleted = &isDeleted
 	}
 }
+
+func (p PersonWithAPIData) Person() Person {
+	return Person{Name: p.Name, Surname: p.Surname, Patronymic: p.Patronymic}
+}
+
+func (p Person) FieldByName(field string) (string, bool) {
+	switch field {
+	case "name":
+		return p.Name, true
+	case "surname":
+		return p.Surname, true
+	case "patronymic":
+		return p.Patronymic, true
+	default:
+		return "", false
+	}
+}
+
+// Column returns the value of a column from PersonColumns, int for id and age and string for the rest
+func (p PersonFromDB) Column(column string) (interface{}, bool) {
+	switch column {
+	case "id":
+		return p.ID, true
+	case "name":
+		return p.Name, true
+	case "surname":
+		return p.Surname, true
+	case "patronymic":
+		return p.Patronymic, true
+	case AttributeAge:
+		return p.Age, true
+	case AttributeGender:
+		return p.Gender, true
+	case AttributeNationality:
+		return p.Nationality, true
+	default:
+		return nil, false
+	}
+}
+
+// ParseColumns checks a comma separated list of columns, every column may be used once, an empty list means all of PersonColumns
+func ParseColumns(list string) ([]string, error) {
+	if list == "" {
+		return PersonColumns, nil
+	}
+
+	columns := strings.Split(list, ",")
+	seen := make(map[string]struct{}, len(columns))
+
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column)
+		if _, ok := (PersonFromDB{}).Column(columns[i]); !ok {
+			return nil, appErrors.ErrIncorrectQueryParam
+		}
+
+		if _, ok := seen[columns[i]]; ok {
+			return nil, appErrors.ErrIncorrectQueryParam
+		}
+		seen[columns[i]] = struct{}{}
+	}
+
+	return columns, nil
+}
@@ -0,0 +1,100 @@
+package domain
+
+import "context"
+
+const (
+	ProviderTypeHTTP = "http"
+	ProviderTypeExec = "exec"
+)
+
+type Provider interface {
+	Name() string
+	Fetch(ctx context.Context, person Person) (DataFromAPI, error)
+}
+
+type ProviderRegistry interface {
+	Providers() []Provider
+}
+
+type ProvidersService interface {
+	CreateProvider(ctx context.Context, provider StoredProvider) (StoredProvider, error)
+	UpdateProvider(ctx context.Context, id int, update ProviderUpdate) (StoredProvider, error)
+	DeleteProvider(ctx context.Context, id int) error
+	ReadProviders(ctx context.Context) ([]StoredProvider, error)
+}
+
+//go:generate mockgen -destination=mocks/providers_repo_mock.gen.go -package=mocks . ProvidersRepository
+type ProvidersRepository interface {
+	CreateProvider(ctx context.Context, provider StoredProvider) (StoredProvider, error)
+	// ModifyProvider replaces the provider with the result of modify, nothing else changes the provider in between
+	ModifyProvider(ctx context.Context, id int, modify func(StoredProvider) (StoredProvider, error)) (StoredProvider, error)
+	DeleteProvider(ctx context.Context, id int) error
+	ReadProviders(ctx context.Context) ([]StoredProvider, error)
+	SeedProviders(ctx context.Context, cfgs []ProviderConfig) error
+}
+
+type ProviderConfig struct {
+	Name          string            `json:"name" yaml:"name" example:"agify"`
+	Type          string            `json:"type" yaml:"type" example:"http"`
+	URL           string            `json:"url,omitempty" yaml:"url" example:"https://api.agify.io/"`
+	Input         string            `json:"input,omitempty" yaml:"input" example:"name"`
+	QueryParam    string            `json:"query_param,omitempty" yaml:"query_param" example:"name"`
+	Params        map[string]string `json:"params,omitempty" yaml:"params"`
+	Fields        map[string]string `json:"fields,omitempty" yaml:"fields"`
+	Probabilities map[string]string `json:"probabilities,omitempty" yaml:"probabilities"`
+	RecordHeaders []string          `json:"record_headers,omitempty" yaml:"record_headers"`
+
+	Command             string   `json:"command,omitempty" yaml:"command"`
+	Args                []string `json:"args,omitempty" yaml:"args"`
+	TimeoutMilliseconds uint     `json:"timeout_ms,omitempty" yaml:"timeout_ms" example:"3000"`
+	Concurrency         uint     `json:"concurrency,omitempty" yaml:"concurrency"`
+
+	Retries                   uint `json:"retries,omitempty" yaml:"retries" example:"5"`
+	RetryIntervalMilliseconds uint `json:"retry_interval_ms,omitempty" yaml:"retry_interval_ms" example:"150"`
+}
+
+type ProvidersFile struct {
+	Providers []ProviderConfig `json:"providers" yaml:"providers"`
+}
+
+type StoredProvider struct {
+	ID       int  `json:"id" example:"1"`
+	Position int  `json:"position" example:"1"`
+	Enabled  bool `json:"enabled" example:"true"`
+	ProviderConfig
+}
+
+type ProviderUpdate struct {
+	Enabled                   *bool `json:"enabled,omitempty" example:"false"`
+	Position                  *int  `json:"position,omitempty" example:"2"`
+	TimeoutMilliseconds       *uint `json:"timeout_ms,omitempty" example:"3000"`
+	Concurrency               *uint `json:"concurrency,omitempty" example:"4"`
+	Retries                   *uint `json:"retries,omitempty" example:"3"`
+	RetryIntervalMilliseconds *uint `json:"retry_interval_ms,omitempty" example:"150"`
+}
+
+func (u ProviderUpdate) Apply(p *StoredProvider) {
+	if u.Enabled != nil {
+		p.Enabled = *u.Enabled
+	}
+
+	if u.Position != nil {
+		p.Position = *u.Position
+	}
+
+	if u.TimeoutMilliseconds != nil {
+		p.TimeoutMilliseconds = *u.TimeoutMilliseconds
+	}
+
+	if u.Concurrency != nil {
+		p.Concurrency = *u.Concurrency
+	}
+
+	if u.Retries != nil {
+		p.Retries = *u.Retries
+	}
+
+	if u.RetryIntervalMilliseconds != nil {
+		p.RetryIntervalMilliseconds = *u.RetryIntervalMilliseconds
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/pkg/vcard"
+)
+
+const (
+	vCardAge         = "X-PREDICTED-AGE"
+	vCardNationality = "X-NATIONALITY"
+	vCardID          = "X-IDENTITY-FORECASTER-ID"
+)
+
+// VCard describes the person as a vCard 4.0: N holds surname, name and patronymic, predicted values go to X- properties
+func (p PersonFromDB) VCard() vcard.Card {
+	var card vcard.Card
+
+	card.AddText("FN", strings.Join(strings.Fields(p.Name+" "+p.Patronymic+" "+p.Surname), " "))
+	card.AddStructured("N", p.Surname, p.Name, p.Patronymic, "", "")
+
+	switch p.Gender {
+	case "":
+	case "male":
+		card.AddText("GENDER", "M")
+	case "female":
+		card.AddText("GENDER", "F")
+	default:
+		card.AddStructured("GENDER", "O", p.Gender)
+	}
+
+	if p.Age != 0 {
+		card.AddText(vCardAge, strconv.Itoa(p.Age))
+	}
+
+	if p.Nationality != "" {
+		card.AddText(vCardNationality, p.Nationality)
+	}
+
+	card.AddText(vCardID, strconv.Itoa(p.ID))
+
+	return card
+}
+
+// PersonFromVCard reverses PersonFromDB.VCard
+func PersonFromVCard(card vcard.Card) (PersonWithAPIData, error) {
+	var person PersonWithAPIData
+
+	if n, ok := card.Get("N"); ok {
+		components := n.Structured()
+		for len(components) < 3 {
+			components = append(components, "")
+		}
+
+		person.Surname, person.Name, person.Patronymic = components[0], components[1], components[2]
+	}
+
+	if person.Name == "" || person.Surname == "" {
+		return person, appErrors.ErrRequiredFieldsNotProvided
+	}
+
+	if gender, ok := card.Get("GENDER"); ok {
+		components := gender.Structured()
+
+		switch strings.ToUpper(components[0]) {
+		case "M":
+			person.Gender = "male"
+		case "F":
+			person.Gender = "female"
+		default:
+			if len(components) > 1 {
+				person.Gender = components[1]
+			}
+		}
+	}
+
+	if age, ok := card.Get(vCardAge); ok {
+		value, err := strconv.Atoi(strings.TrimSpace(age.Text()))
+		if err != nil || value < 0 {
+			return person, appErrors.ErrWrongAttributeType
+		}
+
+		person.Age = value
+	}
+
+	if nationality, ok := card.Get(vCardNationality); ok && nationality.Text() != "" {
+		person.Nationality = nationality.Text()
+	}
+
+	return person, nil
+}
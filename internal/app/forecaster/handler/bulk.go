@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+	jsonDuplicateChecker "identity-forecaster/pkg/json-duplicate-checker"
+	mimeChecker "identity-forecaster/pkg/json-mime-checker"
+)
+
+type bulk struct {
+	srv      domain.ForecasterService
+	enricher domain.Enricher
+	maxItems int
+}
+
+func NewBulk(srv domain.ForecasterService, enricher domain.Enricher, maxItems int) *bulk {
+	return &bulk{srv: srv, enricher: enricher, maxItems: maxItems}
+}
+
+// bulkItem is a not yet validated element of a bulk request, line is the line of NDJSON or the position in JSON array
+type bulkItem struct {
+	line int
+	raw  []byte
+}
+
+// @Tags Persons
+// @Summary Запрос добавления множества сущностей
+// @Description Запрос для добавления информации о множестве сущностей за раз в виде JSON массива или NDJSON (по сущности на строку). Каждая сущность проверяется отдельно, принятые сущности добавляются в одной транзакции, а данные из источников для них запрашиваются в фоне пачками. В ответе - сводка с номерами строк (для NDJSON) или элементов массива (для JSON), которые не были приняты
+// @Accept json,application/x-ndjson
+// @Produce json
+// @Param input body []domain.Person true "информация о сущностях"
+// @Param Idempotency-Key header string false "ключ, повторный запрос с которым вернет ответ на первый запрос"
+// @Success 202 {object} domain.BulkSummary
+// @Failure 400 {object} domain.BulkSummary
+// @Failure 409
+// @Failure 413
+// @Failure 422
+// @Failure 500
+// @Router /persons/bulk [post]
+func (h *bulk) CreatePersons(c echo.Context) error {
+	summary := domain.BulkSummary{AcceptedIDs: make([]int, 0), Errors: make([]domain.BulkError, 0)}
+	persons := make([]domain.PersonWithAPIData, 0)
+	seen := make(map[domain.Person]int)
+
+	// every item is checked as soon as it is read, so only the accepted persons are kept in memory
+	add := func(item bulkItem) {
+		person, err := decodePerson(item.raw)
+		if err != nil {
+			summary.Reject(item.line, err)
+			return
+		}
+
+		if line, ok := seen[person]; ok {
+			summary.Reject(item.line, fmt.Errorf("%w: line %d", appErrors.ErrDuplicateItem, line))
+			return
+		}
+
+		seen[person] = item.line
+		persons = append(persons, domain.PersonWithAPIData{Name: person.Name, Surname: person.Surname, Patronymic: person.Patronymic})
+	}
+
+	var err error
+
+	switch {
+	case mimeChecker.IsJSONContentTypeCorrect(c.Request()):
+		err = h.readJSONArray(c.Request().Body, add)
+	case mimeChecker.IsNDJSONContentTypeCorrect(c.Request()):
+		err = h.readNDJSON(c.Request().Body, add)
+	default:
+		err = appErrors.ErrWrongContentType
+	}
+
+	if errors.Is(err, appErrors.ErrTooManyItems) {
+		c.Response().WriteHeader(http.StatusRequestEntityTooLarge)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if len(persons) == 0 && summary.Rejected == 0 {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrEmptyBulk)
+		return appErrors.ErrEmptyBulk
+	}
+
+	if len(persons) == 0 {
+		logger.Logger().Debugln("all items of the bulk request were rejected")
+		return c.JSON(http.StatusBadRequest, summary)
+	}
+
+	ids, err := h.srv.CreatePersons(c.Request().Context(), persons, false)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	toEnrich := make([]domain.IdentifiedPerson, 0, len(persons))
+	for i, id := range ids {
+		if id == 0 {
+			summary.Skipped++
+			continue
+		}
+
+		summary.Accepted++
+		summary.AcceptedIDs = append(summary.AcceptedIDs, id)
+		toEnrich = append(toEnrich, domain.IdentifiedPerson{ID: id, Person: persons[i].Person()})
+	}
+
+	h.enricher.Enqueue(toEnrich)
+
+	logger.Logger().Infoln("successfully got bulk info to process:", summary.Accepted, summary.Skipped, summary.Rejected)
+	return c.JSON(http.StatusAccepted, summary)
+}
+
+// readJSONArray passes the elements of the array to fn one by one as they are read from the body
+func (h *bulk) readJSONArray(body io.Reader, fn func(bulkItem)) error {
+	d := json.NewDecoder(body)
+
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+
+	if delim, ok := t.(json.Delim); !ok || delim != '[' {
+		return appErrors.ErrWrongContentType
+	}
+
+	for count := 0; d.More(); count++ {
+		if count == h.maxItems {
+			return appErrors.ErrTooManyItems
+		}
+
+		var raw json.RawMessage
+		if err = d.Decode(&raw); err != nil {
+			return err
+		}
+
+		fn(bulkItem{line: count + 1, raw: raw})
+	}
+
+	_, err = d.Token()
+	return err
+}
+
+// readNDJSON passes the non-empty lines to fn one by one as they are read from the body
+func (h *bulk) readNDJSON(body io.Reader, fn func(bulkItem)) error {
+	r := bufio.NewReader(body)
+
+	count := 0
+	for line := 1; ; line++ {
+		raw, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) != 0 {
+			if count == h.maxItems {
+				return appErrors.ErrTooManyItems
+			}
+
+			count++
+			fn(bulkItem{line: line, raw: trimmed})
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
+
+// decodePerson applies the same checks to a single JSON object as the create request does
+func decodePerson(raw []byte) (domain.Person, error) {
+	var person domain.Person
+
+	err := jsonDuplicateChecker.CheckDuplicatesInJSON(json.NewDecoder(bytes.NewReader(raw)), nil)
+	if err != nil {
+		return person, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.DisallowUnknownFields()
+
+	if err = d.Decode(&person); err != nil {
+		return person, err
+	}
+
+	if d.More() {
+		return person, appErrors.ErrTrailingData
+	}
+
+	return person, person.Validate()
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+	jsonDuplicateChecker "identity-forecaster/pkg/json-duplicate-checker"
+	mimeChecker "identity-forecaster/pkg/json-mime-checker"
+)
+
+// @Tags Persons
+// @Summary Запрос получения возможных дубликатов
+// @Description Запрос для получения пар сущностей, которые фоновый поиск дубликатов считает одной сущностью, по убыванию score.
+// @Description score складывается из сходства ФИО (name_score, 0.7) и совпадения возраста, гендера и национальности (attribute_score, 0.3)
+// @Produce json
+// @Param page query int false "номер страницы (1 и больше)" Example(1)
+// @Param limit query int false "максимальное число пар на странице (от 1 до 50)" Example(10)
+// @Param min_score query number false "минимальный score пары (от 0 до 1)" Example(0.8)
+// @Success 200 {array} domain.DuplicatePair
+// @Success 204
+// @Failure 400
+// @Failure 500
+// @Router /duplicates [get]
+func (h *forecaster) ReadDuplicates(c echo.Context) error {
+	pageStr := c.QueryParam("page")
+	if pageStr == "" {
+		pageStr = "1"
+	}
+
+	page, err := strconv.Atoi(pageStr)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	limitStr := c.QueryParam("limit")
+	if limitStr == "" {
+		limitStr = "10"
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	var minScore float64
+	if minScoreStr := c.QueryParam("min_score"); minScoreStr != "" {
+		if minScore, err = strconv.ParseFloat(minScoreStr, 64); err != nil {
+			c.Response().WriteHeader(http.StatusBadRequest)
+			logger.Logger().Debugln(err)
+			return err
+		}
+	}
+
+	if page < 1 || limit < 1 || limit > 50 || minScore < 0 || minScore > 1 {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrIncorrectQueryParam)
+		return appErrors.ErrIncorrectQueryParam
+	}
+
+	pairs, err := h.srv.ReadDuplicates(c.Request().Context(), minScore, limit, (page-1)*limit)
+
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNoContent)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	logger.Logger().Infoln("successfully sent duplicates")
+	return c.JSON(http.StatusOK, pairs)
+}
+
+// @Tags Persons
+// @Summary Запрос объединения сущностей
+// @Description Запрос для объединения двух сущностей в одну: merged_id удаляется, а запросы по его id ведут к survivor_id.
+// @Description В fields для имени, фамилии, отчества, возраста, гендера и национальности указывается, чье значение оставить (survivor или merged),
+// @Description не указанные поля сохраняют значение survivor_id, если оно не пустое. Обе сущности до объединения сохраняются в истории
+// @Accept json
+// @Produce json
+// @Param input body domain.MergeRequest true "объединяемые сущности и источники значений полей"
+// @Success 200 {object} domain.PersonFromDB
+// @Failure 400
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /persons/merge [post]
+func (h *forecaster) MergePersons(c echo.Context) error {
+	if !mimeChecker.IsJSONContentTypeCorrect(c.Request()) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrWrongContentType)
+		return appErrors.ErrWrongContentType
+	}
+
+	bytesToCheck, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	err = jsonDuplicateChecker.CheckDuplicatesInJSON(json.NewDecoder(bytes.NewReader(bytesToCheck)), nil)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(bytesToCheck))
+	d.DisallowUnknownFields()
+
+	var request domain.MergeRequest
+
+	if err = d.Decode(&request); err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	person, err := h.srv.MergePersons(c.Request().Context(), request)
+
+	if errors.Is(err, appErrors.ErrWrongMergeRequest) || errors.Is(err, appErrors.ErrUnknownAttribute) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNotFound)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if errors.Is(err, appErrors.ErrUniqueViolation) {
+		c.Response().WriteHeader(http.StatusConflict)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	logger.Logger().Infoln("successfully merged persons", request.MergedID, "into", request.SurvivorID)
+	return c.JSON(http.StatusOK, person)
+}
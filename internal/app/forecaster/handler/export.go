@@ -0,0 +1,486 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+	"identity-forecaster/pkg/vcard"
+	"identity-forecaster/pkg/xlsx"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+	exportFormatVCard  = "vcf"
+	exportFormatXLSX   = "xlsx"
+
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// personWriter encodes persons of an export one by one
+type personWriter interface {
+	Write(person domain.PersonFromDB) error
+	Close() error
+}
+
+// @Tags Persons
+// @Summary Запрос выгрузки сущностей
+// @Description Запрос для выгрузки всех сущностей, подходящих под фильтры (те же, что и у /read), без пагинации. Записи отправляются по мере чтения из базы
+// @Produce text/csv,application/x-ndjson,text/vcard,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "формат выгрузки" Enums(csv, ndjson, vcf, xlsx) default(csv)
+// @Param columns query string false "столбцы через запятую (каждый не больше одного раза, по умолчанию - все, для vcf не используется)" Example(id,name,surname,age)
+// @Param stats query bool false "добавить в xlsx лист со статистикой по выгруженным сущностям (для других форматов - 400)" default(false)
+// @Param agegt query int false "нижняя граница возраста (включительно)" Example(1)
+// @Param agelt query int false "верхняя граница возраста (не включительно)" Example(1)
+// @Param age query int false "конкретный возраст (если заданы границы - перезаписывает их)" Example(1)
+// @Param idgt query int false "нижняя граница id (включительно)" Example(1)
+// @Param idlt query int false "верхняя граница id (не включительно)" Example(1)
+// @Param id query int false "конкретный id (если заданы границы - перезаписывает их)" Example(1)
+// @Param name query string false "конкретное имя" Example("Dmitriy")
+// @Param surname query string false "конкретная фамилия" Example("Smirnov")
+// @Param patronymic query string false "конкретное отчество" Example("Petrovich")
+// @Param gender query string false "конкретный гендер" Example("male")
+// @Param nationality query string false "конкретная национальность" Example("RU")
+// @Param name_like query string false "часть имени без учета регистра (так же есть surname_like и patronymic_like)" Example(dmitr)
+// @Param name_prefix query string false "начало имени без учета регистра (так же есть surname_prefix и patronymic_prefix)" Example(Dm)
+// @Param sounds_like query string false "слова, каждое из которых звучит как имя, фамилия или отчество (учитывает транслитерацию)" Example(Дмитрий Smirnoff)
+// @Param q query string false "нечеткий поиск по ФИО с опечатками" Example(Smirnv Dmitry)
+// @Param filter query string false "выражение RSQL, как у /read" Example(gender==female;age=lt=30)
+// @Success 200
+// @Failure 400
+// @Failure 500
+// @Router /export [get]
+func (h *forecaster) ExportPersons(c echo.Context) error {
+	var err error
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = exportFormatCSV
+	}
+
+	if format != exportFormatCSV && format != exportFormatNDJSON && format != exportFormatVCard && format != exportFormatXLSX {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrIncorrectQueryParam)
+		return appErrors.ErrIncorrectQueryParam
+	}
+
+	withStats := false
+	if statsStr := c.QueryParam("stats"); statsStr != "" {
+		if withStats, err = strconv.ParseBool(statsStr); err != nil {
+			c.Response().WriteHeader(http.StatusBadRequest)
+			logger.Logger().Debugln(err)
+			return err
+		}
+	}
+
+	// only a workbook has a place for the stats
+	if withStats && format != exportFormatXLSX {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrIncorrectQueryParam)
+		return appErrors.ErrIncorrectQueryParam
+	}
+
+	columns, err := domain.ParseColumns(c.QueryParam("columns"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	filters, err := filtersFromQuery(c, math.MaxInt32)
+	if err != nil {
+		return writeFiltersError(c, err)
+	}
+
+	var writer personWriter
+	switch format {
+	case exportFormatNDJSON:
+		c.Response().Header().Set("Content-Type", "application/x-ndjson")
+		writer = newNDJSONPersonWriter(c.Response(), columns)
+	case exportFormatVCard:
+		c.Response().Header().Set("Content-Type", "text/vcard; charset=utf-8")
+		writer = vcardPersonWriter{vcard.NewEncoder(c.Response())}
+	case exportFormatXLSX:
+		c.Response().Header().Set("Content-Type", xlsxContentType)
+		writer = newXLSXPersonWriter(c.Response(), columns, withStats)
+	default:
+		c.Response().Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writer = newCSVPersonWriter(c.Response(), columns)
+	}
+
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=\"persons."+format+"\"")
+
+	// the rows are read from the database while they are sent, so a client that stops reading must not hold the connection forever
+	write := writer.Write
+	if h.exportWriteTimeout > 0 {
+		rc := http.NewResponseController(c.Response())
+		write = func(person domain.PersonFromDB) error {
+			if err := rc.SetWriteDeadline(time.Now().Add(h.exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+
+			return writer.Write(person)
+		}
+	}
+
+	err = h.srv.StreamPersons(c.Request().Context(), filters, write)
+	if err == nil {
+		err = writer.Close()
+	}
+
+	// the status is already sent once anything is written, the client sees a truncated body then
+	if err != nil && !c.Response().Committed {
+		c.Response().Header().Del("Content-Disposition")
+		c.Response().WriteHeader(http.StatusInternalServerError)
+	}
+
+	if err != nil {
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	logger.Logger().Infoln("successfully exported persons")
+	if !c.Response().Committed {
+		c.Response().WriteHeader(http.StatusOK)
+	}
+
+	return nil
+}
+
+// csvPersonWriter delays the header until the first row or Close, so a failed query can still be answered with 500
+type csvPersonWriter struct {
+	*csv.Writer
+	columns       []string
+	record        []string
+	headerWritten bool
+}
+
+func newCSVPersonWriter(w io.Writer, columns []string) *csvPersonWriter {
+	return &csvPersonWriter{Writer: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+}
+
+func (w *csvPersonWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+
+	w.headerWritten = true
+	return w.Writer.Write(w.columns)
+}
+
+func (w *csvPersonWriter) Write(person domain.PersonFromDB) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	for i, column := range w.columns {
+		value, _ := person.Column(column)
+
+		switch v := value.(type) {
+		case int:
+			w.record[i] = strconv.Itoa(v)
+		case string:
+			w.record[i] = v
+		}
+	}
+
+	return w.Writer.Write(w.record)
+}
+
+func (w *csvPersonWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+type ndjsonPersonWriter struct {
+	w       io.Writer
+	columns []string
+	buf     []byte
+}
+
+func newNDJSONPersonWriter(w io.Writer, columns []string) *ndjsonPersonWriter {
+	return &ndjsonPersonWriter{w: w, columns: columns}
+}
+
+// Write keeps the order of the requested columns, which a map passed to json.Marshal would not
+func (w *ndjsonPersonWriter) Write(person domain.PersonFromDB) error {
+	w.buf = append(w.buf[:0], '{')
+
+	for i, column := range w.columns {
+		if i > 0 {
+			w.buf = append(w.buf, ',')
+		}
+
+		value, _ := person.Column(column)
+
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		w.buf = append(append(append(w.buf, key...), ':'), encoded...)
+	}
+
+	w.buf = append(w.buf, '}', '\n')
+
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+func (w *ndjsonPersonWriter) Close() error {
+	return nil
+}
+
+// xlsxPersonWriter puts persons to the first sheet with id and age as numbers, the optional second sheet holds
+// aggregates collected while the rows pass through. Like csvPersonWriter it starts the workbook only with the first row
+// or Close, so a failed query can still be answered with 500. Persons past the row limit of a sheet fail the export
+// with xlsx.ErrTooManyRows
+type xlsxPersonWriter struct {
+	*xlsx.Writer
+	w       io.Writer
+	columns []string
+	cells   []interface{}
+	stats   *personStats
+}
+
+func newXLSXPersonWriter(w io.Writer, columns []string, withStats bool) *xlsxPersonWriter {
+	writer := &xlsxPersonWriter{w: w, columns: columns, cells: make([]interface{}, len(columns))}
+	if withStats {
+		writer.stats = newPersonStats()
+	}
+
+	return writer
+}
+
+func (w *xlsxPersonWriter) start() error {
+	if w.Writer != nil {
+		return nil
+	}
+
+	sheets := []string{"Persons"}
+	if w.stats != nil {
+		sheets = append(sheets, "Stats")
+	}
+
+	xw, err := xlsx.NewWriter(w.w, sheets...)
+	if err != nil {
+		return err
+	}
+
+	if err = xw.NextSheet(true); err != nil {
+		return err
+	}
+
+	xw.SetAutoFilter()
+	w.Writer = xw
+
+	return xw.WriteHeader(w.columns...)
+}
+
+func (w *xlsxPersonWriter) Write(person domain.PersonFromDB) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	for i, column := range w.columns {
+		w.cells[i], _ = person.Column(column)
+
+		// an unknown age is left empty rather than shown as 0
+		if column == domain.AttributeAge && person.Age == 0 {
+			w.cells[i] = nil
+		}
+	}
+
+	if w.stats != nil {
+		w.stats.add(person)
+	}
+
+	return w.WriteRow(w.cells...)
+}
+
+func (w *xlsxPersonWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	if w.stats != nil {
+		if err := w.NextSheet(true); err != nil {
+			return err
+		}
+
+		if err := w.stats.write(w.Writer); err != nil {
+			return err
+		}
+	}
+
+	return w.Writer.Close()
+}
+
+type personStats struct {
+	total         int
+	withAge       int
+	ageSum        int
+	minAge        int
+	maxAge        int
+	genders       map[string]int
+	nationalities map[string]int
+}
+
+func newPersonStats() *personStats {
+	return &personStats{genders: make(map[string]int), nationalities: make(map[string]int)}
+}
+
+func (s *personStats) add(person domain.PersonFromDB) {
+	s.total++
+
+	if person.Age > 0 {
+		if s.withAge == 0 || person.Age < s.minAge {
+			s.minAge = person.Age
+		}
+
+		if person.Age > s.maxAge {
+			s.maxAge = person.Age
+		}
+
+		s.withAge++
+		s.ageSum += person.Age
+	}
+
+	if person.Gender != "" {
+		s.genders[person.Gender]++
+	}
+
+	if person.Nationality != "" {
+		s.nationalities[person.Nationality]++
+	}
+}
+
+func (s *personStats) write(w *xlsx.Writer) error {
+	if err := w.WriteHeader("metric", "value"); err != nil {
+		return err
+	}
+
+	rows := [][]interface{}{{"total", s.total}}
+	if s.withAge > 0 {
+		rows = append(rows, []interface{}{"average age", float64(s.ageSum) / float64(s.withAge)},
+			[]interface{}{"min age", s.minAge}, []interface{}{"max age", s.maxAge})
+	}
+
+	for _, group := range []struct {
+		prefix string
+		counts map[string]int
+	}{{"gender", s.genders}, {"nationality", s.nationalities}} {
+		keys := make([]string, 0, len(group.counts))
+		for key := range group.counts {
+			keys = append(keys, key)
+		}
+
+		// the most common values go first, ties are ordered alphabetically to keep the output stable
+		sort.Slice(keys, func(i, j int) bool {
+			if group.counts[keys[i]] != group.counts[keys[j]] {
+				return group.counts[keys[i]] > group.counts[keys[j]]
+			}
+
+			return keys[i] < keys[j]
+		})
+
+		for _, key := range keys {
+			rows = append(rows, []interface{}{group.prefix + ": " + key, group.counts[key]})
+		}
+	}
+
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type vcardPersonWriter struct {
+	*vcard.Encoder
+}
+
+func (w vcardPersonWriter) Write(person domain.PersonFromDB) error {
+	return w.Encode(person.VCard())
+}
+
+func (w vcardPersonWriter) Close() error {
+	return nil
+}
+
+// @Tags Persons
+// @Summary Запрос получения сущности в виде vCard
+// @Description Запрос для получения сущности в виде vCard 4.0 (N - фамилия, имя и отчество, GENDER - гендер, X-PREDICTED-AGE и X-NATIONALITY - возраст и национальность)
+// @Produce text/vcard
+// @Param id path int true "id сущности" Example(1)
+// @Success 200
+// @Success 307
+// @Header 307 {string} Location "адрес сущности, с которой была объединена запрошенная"
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /persons/{id}.vcf [get]
+func (h *forecaster) ReadPersonVCard(c echo.Context) error {
+	idStr, ok := strings.CutSuffix(c.Param("id"), ".vcf")
+	if !ok {
+		c.Response().WriteHeader(http.StatusNotFound)
+		logger.Logger().Debugln(appErrors.ErrNoRowsFound)
+		return appErrors.ErrNoRowsFound
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	person, err := h.srv.ReadPersonByID(c.Request().Context(), id)
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNotFound)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	// the person was merged into another one
+	if person.ID != id {
+		return c.Redirect(http.StatusTemporaryRedirect, "/persons/"+strconv.Itoa(person.ID)+".vcf")
+	}
+
+	c.Response().Header().Set("Content-Type", "text/vcard; charset=utf-8")
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=\"person-"+idStr+".vcf\"")
+	c.Response().WriteHeader(http.StatusOK)
+
+	logger.Logger().Infoln("successfully sent vCard of a person")
+	return vcard.NewEncoder(c.Response()).Encode(person.VCard())
+}
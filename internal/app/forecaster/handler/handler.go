@@ -6,44 +6,69 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/avast/retry-go/v4"
 	"github.com/labstack/echo/v4"
 
 	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
 	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/app/forecaster/provider"
 	"identity-forecaster/internal/pkg/logger"
 	jsonDuplicateChecker "identity-forecaster/pkg/json-duplicate-checker"
 	mimeChecker "identity-forecaster/pkg/json-mime-checker"
+	"identity-forecaster/pkg/phonetic"
+	"identity-forecaster/pkg/rsql"
+	"identity-forecaster/pkg/validation"
 )
 
 type forecaster struct {
-	srv  domain.ForecasterService
-	apis []string
+	srv       domain.ForecasterService
+	providers domain.ProviderRegistry
+	// requireVersion rejects updates and deletes that do not tell the version of the person with 428
+	requireVersion bool
+	// exportWriteTimeout stops an export when the client does not take the next part for so long, zero means no limit
+	exportWriteTimeout time.Duration
 	*sync.WaitGroup
-	retriesAmount              uint
-	millisecondsBetweenRetries uint
 }
 
-func New(srv domain.ForecasterService, wg *sync.WaitGroup, retriesAmount uint, millisecondsBetweenRetries uint, apis []string) *forecaster {
-	return &forecaster{srv: srv, WaitGroup: wg, apis: apis, retriesAmount: retriesAmount, millisecondsBetweenRetries: millisecondsBetweenRetries}
+func New(srv domain.ForecasterService, wg *sync.WaitGroup, providers domain.ProviderRegistry, requireVersion bool,
+	exportWriteTimeout time.Duration) *forecaster {
+	return &forecaster{srv: srv, WaitGroup: wg, providers: providers, requireVersion: requireVersion,
+		exportWriteTimeout: exportWriteTimeout}
 }
 
 // @Tags Persons
 // @Summary Запрос добавления сущности
-// @Description Запрос для добавления информации о новой сущности
+// @Description Запрос для добавления информации о новой сущности. on_conflict задает, что делать, если сущность с таким ФИО уже есть:
+// @Description error - вернуть 409 (сущность добавляется до ответа вместе с данными из источников, удаленная восстанавливается), ignore - ничего не менять,
+// @Description update - заменить данные из источников (и восстановить удаленную), revive - восстановить удаленную, а существующую не менять (по умолчанию)
 // @Accept json
 // @Param input body domain.Person true "информация о сущности"
+// @Param on_conflict query string false "что делать с уже существующей сущностью" Enums(error, ignore, update, revive)
+// @Param Idempotency-Key header string false "ключ, повторный запрос с которым вернет ответ на первый запрос"
+// @Success 201
+// @Header 201 {string} Location "адрес добавленной сущности (только для on_conflict=error)"
 // @Success 202
 // @Failure 400
+// @Failure 409
+// @Failure 413
+// @Failure 422
 // @Failure 500
+// @Failure 502
 // @Router /create [post]
 func (h *forecaster) CreatePerson(c echo.Context) error {
+	mode, err := domain.ParseConflictMode(c.QueryParam("on_conflict"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
 	if !mimeChecker.IsJSONContentTypeCorrect(c.Request()) {
 		c.Response().WriteHeader(http.StatusBadRequest)
 		logger.Logger().Debugln(appErrors.ErrWrongContentType)
@@ -79,42 +104,25 @@ func (h *forecaster) CreatePerson(c echo.Context) error {
 		return err
 	}
 
-	if person.Name == "" || person.Surname == "" {
-		c.Response().WriteHeader(http.StatusBadRequest)
-		logger.Logger().Debugln(appErrors.ErrRequiredFieldsNotProvided)
-		return appErrors.ErrRequiredFieldsNotProvided
+	if err = person.Validate(); err != nil {
+		return writeValidationError(c, err)
 	}
 
-	var data, resultData domain.DataFromAPI
+	if mode == domain.ConflictError {
+		return h.createPersonOrConflict(c, person)
+	}
 
 	h.Add(1)
 	go func() {
 		defer h.Done()
 
-		for _, api := range h.apis {
-			if strings.Contains(api, "nationalize") {
-				data, err = h.GetDataFromAPI(person.Surname, api, h.retriesAmount, h.millisecondsBetweenRetries)
-			} else {
-				data, err = h.GetDataFromAPI(person.Name, api, h.retriesAmount, h.millisecondsBetweenRetries)
-			}
-
-			if err != nil {
-				logger.Logger().Debugln(err)
-				return
-			}
-
-			if strings.Contains(api, "agify") {
-				resultData.Age = data.Age
-			} else if strings.Contains(api, "genderize") {
-				resultData.Gender = data.Gender
-			} else if strings.Contains(api, "nationalize") {
-				logger.Logger().Infoln(data.CountrySlice)
-				resultData.Nationality = data.CountrySlice[0].CountryID
-			}
+		resultData, err := provider.FetchAll(context.Background(), h.providers.Providers(), person)
+		if err != nil {
+			logger.Logger().Debugln(err)
+			return
 		}
 
-		if err = h.srv.CreatePerson(context.Background(), person, resultData); err != nil {
-			c.Response().WriteHeader(http.StatusInternalServerError)
+		if _, err := h.srv.CreatePerson(context.Background(), person, resultData, mode); err != nil {
 			logger.Logger().Debugln(err)
 			return
 		}
@@ -125,54 +133,47 @@ func (h *forecaster) CreatePerson(c echo.Context) error {
 	return nil
 }
 
-func (h *forecaster) GetDataFromAPI(name string, api string, retriesAmount uint, interval uint) (domain.DataFromAPI, error) {
-	var dataToReturn domain.DataFromAPI
-
-	url := api + "?name=" + name
-
-	err := retry.Do(func() error {
-		logger.Logger().Infoln("attempt to get info from external api...")
-		resp, err := http.Get(url)
-		logger.Logger().Infoln(resp.StatusCode)
-		if err != nil {
-			logger.Logger().Infoln(err)
-			return err
-		}
-		defer resp.Body.Close()
-
-		if !(resp.StatusCode > 199 && resp.StatusCode < 400) {
-			return appErrors.ErrWrongStatusCode
-		}
-
-		var data domain.DataFromAPI
-		d := json.NewDecoder(resp.Body)
-		err = d.Decode(&data)
-		if err != nil {
-			logger.Logger().Infoln(err)
-			return err
-		}
+// createPersonOrConflict stores the person before answering, so that the conflict is reported to the client. The data
+// from providers is fetched first, so a new or revived person is never stored without it
+func (h *forecaster) createPersonOrConflict(c echo.Context, person domain.Person) error {
+	resultData, err := provider.FetchAll(c.Request().Context(), h.providers.Providers(), person)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadGateway)
+		logger.Logger().Debugln(err)
+		return err
+	}
 
-		dataToReturn = data
+	id, err := h.srv.CreatePerson(c.Request().Context(), person, resultData, domain.ConflictError)
 
-		return nil
-	}, retry.Attempts(retriesAmount), retry.Delay(time.Duration(interval)*time.Millisecond))
+	if errors.Is(err, appErrors.ErrUniqueViolation) {
+		c.Response().WriteHeader(http.StatusConflict)
+		logger.Logger().Debugln(err)
+		return err
+	}
 
 	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
 		logger.Logger().Debugln(err)
-		return domain.DataFromAPI{}, err
+		return err
 	}
 
-	logger.Logger().Infoln("successfully got info")
-	return dataToReturn, nil
+	logger.Logger().Infoln("successfully created a person", id)
+	c.Response().Header().Set(echo.HeaderLocation, "/persons/"+strconv.Itoa(id))
+	c.Response().WriteHeader(http.StatusCreated)
+	return nil
 }
 
 // @Tags Persons
 // @Summary Запрос удаления сущности
-// @Description Запрос для удаления сущности
+// @Description Запрос для удаления сущности. Если задан If-Match (ETag из GET /persons/{id}) или version, сущность удаляется, только если с тех пор не менялась
 // @Param id path int true "id сущности" Example(1)
+// @Param version query int false "версия сущности, которую удаляет клиент" Example(3)
+// @Param If-Match header string false "ETag сущности, которую удаляет клиент"
 // @Success 200
 // @Failure 400
 // @Failure 404
+// @Failure 412
+// @Failure 428
 // @Failure 500
 // @Router /delete/{id} [delete]
 func (h *forecaster) DeletePersonByID(c echo.Context) error {
@@ -185,13 +186,34 @@ func (h *forecaster) DeletePersonByID(c echo.Context) error {
 		return err
 	}
 
-	err = h.srv.DeletePersonByID(c.Request().Context(), id)
+	var versionParam *int
+	if versionStr := c.QueryParam("version"); versionStr != "" {
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			c.Response().WriteHeader(http.StatusBadRequest)
+			logger.Logger().Debugln(err)
+			return err
+		}
+
+		versionParam = &version
+	}
+
+	version, err := h.expectedVersion(c.Request(), id, versionParam)
+	if err != nil {
+		return writePreconditionError(c, err)
+	}
+
+	err = h.srv.DeletePersonByID(c.Request().Context(), id, version)
 	if errors.Is(err, appErrors.ErrNoRowsAffected) {
 		c.Response().WriteHeader(http.StatusNotFound)
 		logger.Logger().Debugln(err)
 		return err
 	}
 
+	if errors.Is(err, appErrors.ErrVersionMismatch) {
+		return writePreconditionError(c, err)
+	}
+
 	if err != nil {
 		c.Response().WriteHeader(http.StatusInternalServerError)
 		logger.Logger().Debugln(err)
@@ -204,14 +226,20 @@ func (h *forecaster) DeletePersonByID(c echo.Context) error {
 
 // @Tags Persons
 // @Summary Запрос обновления информации о сущности
-// @Description Запрос для обновления информации о сущности (кроме id)
+// @Description Запрос для обновления информации о сущности (кроме id), в ответе - сущность после обновления с новыми ETag и version.
+// @Description Если задан If-Match (ETag из GET /persons/{id}) или version, сущность обновляется, только если с тех пор не менялась
 // @Accept json
-// @Param input body domain.PersonWithAPIData true "описание сущности"
+// @Produce json
+// @Param input body domain.PersonUpdate true "описание сущности"
 // @Param id path int true "id сущности" Example(1)
-// @Success 200
+// @Param If-Match header string false "ETag сущности, которую изменяет клиент"
+// @Success 200 {object} domain.PersonFromDB
+// @Header 200 {string} ETag "строгий валидатор сущности после обновления"
 // @Failure 400
 // @Failure 404
 // @Failure 409
+// @Failure 412
+// @Failure 428
 // @Failure 500
 // @Router /update/{id} [put]
 func (h *forecaster) UpdatePerson(c echo.Context) error {
@@ -251,7 +279,7 @@ func (h *forecaster) UpdatePerson(c echo.Context) error {
 	d := json.NewDecoder(c.Request().Body)
 	d.DisallowUnknownFields()
 
-	var newPersonData domain.PersonWithAPIData
+	var newPersonData domain.PersonUpdate
 
 	if err = d.Decode(&newPersonData); err != nil {
 		c.Response().WriteHeader(http.StatusBadRequest)
@@ -259,7 +287,16 @@ func (h *forecaster) UpdatePerson(c echo.Context) error {
 		return err
 	}
 
-	err = h.srv.UpdatePerson(c.Request().Context(), id, newPersonData)
+	if err = newPersonData.PersonWithAPIData.Validate(); err != nil {
+		return writeValidationError(c, err)
+	}
+
+	version, err := h.expectedVersion(c.Request(), id, newPersonData.Version)
+	if err != nil {
+		return writePreconditionError(c, err)
+	}
+
+	person, err := h.srv.UpdatePerson(c.Request().Context(), id, newPersonData.PersonWithAPIData, version)
 
 	if errors.Is(err, appErrors.ErrNoRowsFound) || errors.Is(err, appErrors.ErrNoRowsAffected) {
 		c.Response().WriteHeader(http.StatusNotFound)
@@ -273,6 +310,17 @@ func (h *forecaster) UpdatePerson(c echo.Context) error {
 		return err
 	}
 
+	if errors.Is(err, appErrors.ErrVersionMismatch) {
+		return writePreconditionError(c, err)
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	body, err := setValidators(c, person)
 	if err != nil {
 		c.Response().WriteHeader(http.StatusInternalServerError)
 		logger.Logger().Debugln(err)
@@ -280,16 +328,22 @@ func (h *forecaster) UpdatePerson(c echo.Context) error {
 	}
 
 	logger.Logger().Infoln("successfully updated")
-	c.Response().WriteHeader(http.StatusOK)
-	return nil
+	return c.JSONBlob(http.StatusOK, body)
 }
 
 // @Tags Persons
 // @Summary Запрос чтения информации о сущностях
-// @Description Запрос для получения сохраненной информации о сущностях с возможностью применения фильтров и пагинацией
+// @Description Запрос для получения сохраненной информации о сущностях с возможностью применения фильтров и пагинацией.
+// @Description Пагинация по номеру страницы возвращает массив сущностей (или объект с метаданными при envelope=true), по курсору (задан after) - всегда объект.
+// @Description Ссылки на соседние страницы передаются в заголовке Link, а общее число сущностей - в X-Total-Count, если оно подсчитывается (count).
+// @Description При ошибке в filter в ответе 400 есть описание ошибки и позиция неверного фрагмента: {"error": "...", "position": 5, "token": "..."}
 // @Produce json
-// @Param page query int false "номер страницы (1 и больше)" Example(1)
+// @Param page query int false "номер страницы (1 и больше), нельзя задавать вместе с after" Example(1)
 // @Param limit query int false "максимальное число записей на странице (1 и больше)" Example(1)
+// @Param after query string false "курсор из next_cursor предыдущей страницы, пустое значение - первая страница" Example(eyJzIjoiaWQiLCJ2IjpbMTBdfQ)
+// @Param sort query string false "поля сортировки через запятую, с минусом - по убыванию (при равенстве всех полей сортируется по id), score - только вместе с q" Example(-age,surname,name)
+// @Param envelope query bool false "вернуть объект с items, total, page, limit, next и prev вместо массива" default(false)
+// @Param count query string false "подсчет общего числа сущностей: точный, оценка по статистике постгреса или без подсчета (по умолчанию exact с envelope=true и none без него)" Enums(exact, estimate, none)
 // @Param agegt query int false "нижняя граница возраста (включительно)" Example(1)
 // @Param agelt query int false "верхняя граница возраста (не включительно)" Example(1)
 // @Param age query int false "конкретный возраст (если заданы границы - перезаписывает их)" Example(1)
@@ -301,7 +355,15 @@ func (h *forecaster) UpdatePerson(c echo.Context) error {
 // @Param patronymic query string false "конкретное отчество" Example("Petrovich")
 // @Param gender query string false "конкретный гендер" Example("male")
 // @Param nationality query string false "конкретная национальность" Example("RU")
-// @Success 200
+// @Param name_like query string false "часть имени без учета регистра (так же есть surname_like и patronymic_like)" Example(dmitr)
+// @Param name_prefix query string false "начало имени без учета регистра (так же есть surname_prefix и patronymic_prefix)" Example(Dm)
+// @Param sounds_like query string false "слова, каждое из которых звучит как имя, фамилия или отчество (учитывает транслитерацию)" Example(Дмитрий Smirnoff)
+// @Param q query string false "нечеткий поиск по ФИО с опечатками, результат по умолчанию отсортирован по убыванию score" Example(Smirnv Dmitry)
+// @Param filter query string false "выражение RSQL: ; - И, , - ИЛИ, операторы ==, !=, =lt=, =le=, =gt=, =ge=, =in=, =out=, =isnull=" Example(gender==female;age=lt=30)
+// @Success 200 {object} domain.PersonsPage
+// @Header 200 {integer} X-Total-Count "общее число сущностей, подходящих под фильтры (если оно подсчитывается)"
+// @Header 200 {string} X-Total-Count-Estimated "true, если число получено из статистики"
+// @Header 200 {string} Link "ссылки на первую, последнюю, следующую и предыдущую страницы"
 // @Success 204
 // @Failure 400
 // @Failure 500
@@ -337,13 +399,204 @@ func (h *forecaster) ReadPersons(c echo.Context) error {
 		return appErrors.ErrIncorrectQueryParam
 	}
 
+	filters, err := filtersFromQuery(c, math.MaxInt32)
+	if err != nil {
+		return writeFiltersError(c, err)
+	}
+
+	sort, err := domain.ParseSort(c.QueryParam("sort"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	// fuzzy search results are ordered by relevance unless another order is requested
+	if c.QueryParam("sort") == "" && filters.Query.Value.Valid {
+		sort = domain.RelevanceSort
+	}
+
+	if sort.HasRelevance() && !filters.Query.Value.Valid {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrIncorrectQueryParam)
+		return appErrors.ErrIncorrectQueryParam
+	}
+
+	withEnvelope := false
+	if envelopeStr := c.QueryParam("envelope"); envelopeStr != "" {
+		if withEnvelope, err = strconv.ParseBool(envelopeStr); err != nil {
+			c.Response().WriteHeader(http.StatusBadRequest)
+			logger.Logger().Debugln(err)
+			return err
+		}
+	}
+
+	// counting may cost more than the page itself, so the total is only computed for the envelope or on request
+	defaultCount := domain.CountNone
+	if withEnvelope {
+		defaultCount = domain.CountExact
+	}
+
+	count, err := domain.ParseCountMode(c.QueryParam("count"), defaultCount)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	pageParams := domain.Page{Number: page, Limit: limit, Sort: sort, Count: count}
+
+	// an empty after starts the cursor pagination from the first row
+	withCursor := c.QueryParams().Has("after")
+	if withCursor && c.QueryParam("page") != "" {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrIncorrectQueryParam)
+		return appErrors.ErrIncorrectQueryParam
+	}
+
+	if after := c.QueryParam("after"); after != "" {
+		cursor, err := domain.DecodeCursor(after, sort)
+		if err != nil {
+			c.Response().WriteHeader(http.StatusBadRequest)
+			logger.Logger().Debugln(err)
+			return err
+		}
+
+		pageParams.After = &cursor
+	}
+
+	persons, err := h.srv.ReadPersons(c.Request().Context(), pageParams, filters)
+
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNoContent)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	setPageMetadata(c, &persons, pageParams, withCursor)
+
+	// the bare array is kept for the clients of page based pagination, they get the metadata from the headers
+	var response interface{} = persons.Items
+	if withCursor || withEnvelope {
+		response = persons
+	}
+
+	c.Response().Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(c.Response()).Encode(response)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	logger.Logger().Infoln("successfully sent info about persons")
+	c.Response().WriteHeader(http.StatusOK)
+	return nil
+}
+
+// setPageMetadata fills the page, limit and links of persons and sets X-Total-Count and Link headers.
+// Links are relative to work behind proxies, a cursor page has no link to the previous one
+func setPageMetadata(c echo.Context, persons *domain.PersonsPage, page domain.Page, withCursor bool) {
+	link := func(param string, value string) string {
+		query := c.Request().URL.Query()
+		query.Set(param, value)
+		return c.Request().URL.Path + "?" + query.Encode()
+	}
+
+	persons.Limit = page.Limit
+	links := make([]string, 0, 4)
+
+	if withCursor {
+		if persons.NextCursor != "" {
+			persons.Next = link("after", persons.NextCursor)
+		}
+
+		links = append(links, "<"+link("after", "")+">; rel=\"first\"")
+	} else {
+		persons.Page = page.Number
+
+		if persons.NextCursor != "" {
+			persons.Next = link("page", strconv.Itoa(page.Number+1))
+		}
+
+		if page.Number > 1 {
+			persons.Prev = link("page", strconv.Itoa(page.Number-1))
+		}
+
+		links = append(links, "<"+link("page", "1")+">; rel=\"first\"")
+		if persons.Total != nil && !persons.TotalEstimated && *persons.Total > 0 {
+			last := (*persons.Total + int64(page.Limit) - 1) / int64(page.Limit)
+			links = append(links, "<"+link("page", strconv.FormatInt(last, 10))+">; rel=\"last\"")
+		}
+	}
+
+	if persons.Next != "" {
+		links = append(links, "<"+persons.Next+">; rel=\"next\"")
+	}
+
+	if persons.Prev != "" {
+		links = append(links, "<"+persons.Prev+">; rel=\"prev\"")
+	}
+
+	c.Response().Header().Set("Link", strings.Join(links, ", "))
+
+	if persons.Total != nil {
+		c.Response().Header().Set("X-Total-Count", strconv.FormatInt(*persons.Total, 10))
+		if persons.TotalEstimated {
+			c.Response().Header().Set("X-Total-Count-Estimated", "true")
+		}
+	}
+}
+
+// writeFiltersError answers with 400, the body points to the offending token when the filter expression is wrong
+func writeFiltersError(c echo.Context, err error) error {
+	logger.Logger().Debugln(err)
+
+	var expressionErr *rsql.Error
+	if errors.As(err, &expressionErr) {
+		if jsonErr := c.JSON(http.StatusBadRequest, expressionErr); jsonErr != nil {
+			logger.Logger().Debugln(jsonErr)
+		}
+
+		return err
+	}
+
+	c.Response().WriteHeader(http.StatusBadRequest)
+	return err
+}
+
+// writeValidationError answers with 400, the body lists all the violations when the person failed the validation
+func writeValidationError(c echo.Context, err error) error {
+	logger.Logger().Debugln(err)
+
+	var violations validation.Errors
+	if errors.As(err, &violations) {
+		if jsonErr := c.JSON(http.StatusBadRequest, violations); jsonErr != nil {
+			logger.Logger().Debugln(jsonErr)
+		}
+
+		return err
+	}
+
+	c.Response().WriteHeader(http.StatusBadRequest)
+	return err
+}
+
+// filtersFromQuery reads the filters of /read from query params, idLessThanDefault is the upper bound of id when it is not set
+func filtersFromQuery(c echo.Context, idLessThanDefault int) (domain.Filters, error) {
 	var filters domain.Filters
 
 	idGreaterThanStr := c.QueryParam("idgt")
 	filters.IDMoreThan.Set(idGreaterThanStr)
 
 	idLessThanStr := c.QueryParam("idlt")
-	filters.IDLessThan.Set(idLessThanStr, (page*limit)+1)
+	filters.IDLessThan.Set(idLessThanStr, idLessThanDefault)
 
 	idEqualToStr := c.QueryParam("id")
 	idEqualTo, err := strconv.Atoi(idEqualToStr)
@@ -351,7 +604,7 @@ func (h *forecaster) ReadPersons(c echo.Context) error {
 		idEqualTo = -1
 	} else {
 		filters.IDMoreThan.Set(idEqualToStr)
-		filters.IDLessThan.Set(idEqualToStr, (page*limit)+1)
+		filters.IDLessThan.Set(idEqualToStr, idLessThanDefault)
 		filters.IDLessThan.Value++
 		filters.IDEqualTo = idEqualTo
 	}
@@ -388,35 +641,74 @@ func (h *forecaster) ReadPersons(c echo.Context) error {
 	nationalityStr := c.QueryParam("nationality")
 	filters.NationalityEqualTo.Set(nationalityStr)
 
+	for _, column := range []string{"name", "surname", "patronymic"} {
+		if value := c.QueryParam(column + "_like"); value != "" {
+			filters.TextMatches = append(filters.TextMatches, domain.TextMatch{Column: column, Value: value})
+		}
+
+		if value := c.QueryParam(column + "_prefix"); value != "" {
+			filters.TextMatches = append(filters.TextMatches, domain.TextMatch{Column: column, Prefix: true, Value: value})
+		}
+	}
+
+	for _, word := range strings.Fields(c.QueryParam("sounds_like")) {
+		key := phonetic.Key(word)
+		if key == "" {
+			return filters, appErrors.ErrIncorrectQueryParam
+		}
+
+		filters.SoundsLike = append(filters.SoundsLike, key)
+	}
+
+	filters.Query.Set(strings.TrimSpace(c.QueryParam("q")))
+
+	if expression := c.QueryParam("filter"); expression != "" {
+		if filters.Expression, err = domain.ParseFilterExpression(expression); err != nil {
+			return filters, err
+		}
+	}
+
 	if filters.IDLessThan.Value < filters.IDMoreThan.Value || filters.AgeLessThan.Value < filters.AgeMoreThan.Value {
-		c.Response().WriteHeader(http.StatusBadRequest)
-		logger.Logger().Infoln(appErrors.ErrIncorrectQueryParam)
-		return appErrors.ErrIncorrectQueryParam
+		return filters, appErrors.ErrIncorrectQueryParam
 	}
 
-	persons, err := h.srv.ReadPersons(c.Request().Context(), page, limit, filters)
+	return filters, nil
+}
 
-	if errors.Is(err, appErrors.ErrNoRowsFound) {
-		c.Response().WriteHeader(http.StatusNoContent)
+// @Tags Persons
+// @Summary Запрос получения исходных ответов источников
+// @Description Запрос для получения сохраненных ответов внешних источников, из которых были получены данные о сущности
+// @Produce json
+// @Param id path int true "id сущности" Example(1)
+// @Success 200 {array} domain.ProviderResponse
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /persons/{id}/provider-responses [get]
+func (h *forecaster) ReadProviderResponses(c echo.Context) error {
+	idStr := c.Param("id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
 		logger.Logger().Debugln(err)
 		return err
 	}
 
-	if err != nil {
-		c.Response().WriteHeader(http.StatusInternalServerError)
+	responses, err := h.srv.ReadProviderResponses(c.Request().Context(), id)
+
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNotFound)
 		logger.Logger().Debugln(err)
 		return err
 	}
 
-	c.Response().Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(c.Response()).Encode(persons)
 	if err != nil {
 		c.Response().WriteHeader(http.StatusInternalServerError)
 		logger.Logger().Debugln(err)
 		return err
 	}
 
-	logger.Logger().Infoln("successfully sent info about persons")
-	c.Response().WriteHeader(http.StatusOK)
-	return nil
+	logger.Logger().Infoln("successfully sent provider responses")
+	return c.JSON(http.StatusOK, responses)
 }
@@ -1,20 +1,29 @@
 package handler
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
 	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
 	"identity-forecaster/internal/app/forecaster/domain"
 	"identity-forecaster/internal/app/forecaster/domain/mocks"
+	"identity-forecaster/internal/app/forecaster/provider"
 	"identity-forecaster/internal/app/forecaster/service"
 	"identity-forecaster/internal/pkg/logger"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func testRouter(t *testing.T) *echo.Echo {
@@ -25,56 +34,49 @@ func testRouter(t *testing.T) *echo.Echo {
 
 	mockRepo := mocks.NewMockForecasterRepository(ctrl)
 
-	mockRepo.EXPECT().CreatePerson(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
+	mockRepo.EXPECT().CreatePerson(gomock.Any(), gomock.Any(), gomock.Any(), domain.DefaultConflictMode).Return(1, nil).MaxTimes(1)
 
-	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), gomock.Any()).Return(nil).MaxTimes(1)
-	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), gomock.Any()).Return(appErrors.ErrNoRowsAffected).MaxTimes(1)
+	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), gomock.Any(), 0).Return(nil).MaxTimes(1)
+	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), gomock.Any(), 0).Return(appErrors.ErrNoRowsAffected).MaxTimes(1)
 
-	mockRepo.EXPECT().UpdatePerson(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).MaxTimes(2)
+	mockRepo.EXPECT().UpdatePerson(gomock.Any(), gomock.Any(), gomock.Any(), 0).Return(domain.PersonFromDB{}, nil).MaxTimes(2)
 
-	mockRepo.EXPECT().ReadPersons(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(make([]domain.PersonFromDB, 0), appErrors.ErrNoRowsFound).MaxTimes(1)
-	mockRepo.EXPECT().ReadPersons(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(make([]domain.PersonFromDB, 0), nil).MaxTimes(2)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.PersonsPage{}, appErrors.ErrNoRowsFound).MaxTimes(1)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.PersonsPage{Items: make([]domain.PersonFromDB, 0)}, nil).MaxTimes(2)
+
+	mockRepo.EXPECT().ReadProviderResponses(gomock.Any(), 1).Return([]domain.ProviderResponse{{ID: 1, PersonID: 1, Provider: "agify",
+		StatusCode: http.StatusOK, Body: []byte("{\"age\": 20}")}}, nil).MaxTimes(1)
+	mockRepo.EXPECT().ReadProviderResponses(gomock.Any(), 2).Return(nil, appErrors.ErrNoRowsFound).MaxTimes(1)
+	mockRepo.EXPECT().ReadProviderResponses(gomock.Any(), 3).Return([]domain.ProviderResponse{}, nil).MaxTimes(1)
 
 	s := service.New(mockRepo)
 
 	var wg sync.WaitGroup
 
-	h := New(s, &wg, 1, 1, []string{"http://localhost:8080/agify", "http://localhost:8080/genderize", "http://localhost:8080/nationalize"})
+	h := New(s, &wg, testProviders(t), false, 0)
 
 	e.POST("/create", h.CreatePerson)
 	e.DELETE("/delete/:id", h.DeletePersonByID)
 	e.PUT("/update/:id", h.UpdatePerson)
 	e.GET("/read", h.ReadPersons)
+	e.GET("/persons/:id/provider-responses", h.ReadProviderResponses)
 
 	return e
 }
 
-func activateTestAPIs() {
-	e := echo.New()
-
-	e.GET("/agify", Age)
-	e.GET("/genderize", Gender)
-	e.GET("/nationalize", Nation)
-
-	go e.Start(":8080")
-}
-
-func Age(c echo.Context) error {
-	c.Response().Write([]byte("{\"age\": 20}"))
-	c.Response().WriteHeader(http.StatusOK)
-	return nil
-}
+func testProviders(t *testing.T) domain.ProviderRegistry {
+	transport, err := provider.NewTransport(provider.ModeReplay, "testdata/cassettes", nil)
+	require.NoError(t, err)
 
-func Gender(c echo.Context) error {
-	c.Response().Write([]byte("{\"gender\": \"male\"}"))
-	c.Response().WriteHeader(http.StatusOK)
-	return nil
-}
+	providers, err := provider.FromConfigs([]domain.ProviderConfig{
+		{Name: "agify", URL: "http://fake-providers/agify", Input: "name", Fields: map[string]string{domain.AttributeAge: "age"}},
+		{Name: "genderize", URL: "http://fake-providers/genderize", Input: "name", Fields: map[string]string{domain.AttributeGender: "gender"}},
+		{Name: "nationalize", URL: "http://fake-providers/nationalize", Input: "surname", QueryParam: "name",
+			Fields: map[string]string{domain.AttributeNationality: "country[0].country_id"}},
+	}, &http.Client{Transport: transport}, 1, 1)
+	require.NoError(t, err)
 
-func Nation(c echo.Context) error {
-	c.Response().Write([]byte("{\"country\": [{\"country_id\": \"QWE\",\"probability\": 0.2}]}"))
-	c.Response().WriteHeader(http.StatusOK)
-	return nil
+	return provider.NewStatic(providers)
 }
 
 func request(t *testing.T, ts *httptest.Server, code int, method, content, body, endpoint string) *http.Response {
@@ -98,7 +100,6 @@ func request(t *testing.T, ts *httptest.Server, code int, method, content, body,
 
 func TestCreate(t *testing.T) {
 	ts := httptest.NewServer(testRouter(t))
-	activateTestAPIs()
 
 	defer ts.Close()
 
@@ -145,9 +146,65 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateOnConflict(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	sidorov := domain.Person{Name: "Dmitriy", Surname: "Sidorov"}
+
+	// the person is stored together with the data from providers
+	gomock.InOrder(
+		mockRepo.EXPECT().CreatePerson(gomock.Any(), sidorov, gomock.Any(), domain.ConflictError).DoAndReturn(
+			func(_ context.Context, _ domain.Person, data domain.DataFromAPI, _ domain.ConflictMode) (int, error) {
+				require.NotZero(t, data.Age)
+				return 5, nil
+			}),
+		mockRepo.EXPECT().CreatePerson(gomock.Any(), sidorov, gomock.Any(), domain.ConflictError).Return(0,
+			appErrors.ErrUniqueViolation),
+	)
+
+	for _, mode := range []domain.ConflictMode{domain.ConflictIgnore, domain.ConflictUpdate, domain.ConflictRevive} {
+		mockRepo.EXPECT().CreatePerson(gomock.Any(), sidorov, gomock.Any(), mode).Return(0, nil).Times(1)
+	}
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.POST("/create", h.CreatePerson)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+	defer wg.Wait()
+
+	var testTable = []struct {
+		endpoint string
+		body     string
+		code     int
+		location string
+	}{
+		{"/create?on_conflict=error", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusCreated, "/persons/5"},
+		{"/create?on_conflict=error", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusConflict, ""},
+		// there are no responses of providers for Petrov
+		{"/create?on_conflict=error", `{"name": "Dmitriy", "surname": "Petrov"}`, http.StatusBadGateway, ""},
+		{"/create?on_conflict=ignore", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusAccepted, ""},
+		{"/create?on_conflict=update", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusAccepted, ""},
+		{"/create?on_conflict=revive", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusAccepted, ""},
+		{"/create?on_conflict=replace", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusBadRequest, ""},
+		{"/create?on_conflict=error", `{"name": "Dmitriy"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, testCase := range testTable {
+		resp := request(t, ts, testCase.code, http.MethodPost, "application/json", testCase.body, testCase.endpoint)
+		require.Equal(t, testCase.location, resp.Header.Get("Location"), testCase.endpoint)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	ts := httptest.NewServer(testRouter(t))
-	activateTestAPIs()
 
 	defer ts.Close()
 
@@ -189,7 +246,6 @@ func TestDelete(t *testing.T) {
 
 func TestUpdate(t *testing.T) {
 	ts := httptest.NewServer(testRouter(t))
-	activateTestAPIs()
 
 	defer ts.Close()
 
@@ -204,7 +260,7 @@ func TestUpdate(t *testing.T) {
 			"/update/1",
 			http.MethodPut,
 			"application/json",
-			http.StatusOK,
+			http.StatusBadRequest,
 			"{\"name\": \"Dmitriy\",\"surname\": \"тsщ\",\"patronymic\": \"\",\"gender\": \"helicopter\",\"nationality\": \"kitten\"}",
 		},
 		{
@@ -238,7 +294,6 @@ func TestUpdate(t *testing.T) {
 
 func TestRead(t *testing.T) {
 	ts := httptest.NewServer(testRouter(t))
-	activateTestAPIs()
 
 	defer ts.Close()
 
@@ -284,3 +339,1827 @@ func TestRead(t *testing.T) {
 		resp.Body.Close()
 	}
 }
+
+func TestReadCursor(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	sort, err := domain.ParseSort("-age")
+	require.NoError(t, err)
+
+	last := domain.PersonFromDB{ID: 7, Name: "Dmitriy", Surname: "Smirnov", Age: 42}
+	next := domain.CursorAfter(sort, last)
+
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 1, Limit: 1, Sort: sort, Count: domain.CountNone}, gomock.Any()).Return(
+		domain.PersonsPage{Items: []domain.PersonFromDB{last}, NextCursor: next.Encode()}, nil).Times(2)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 1, Limit: 1, Sort: sort, After: &next, Count: domain.CountNone}, gomock.Any()).Return(
+		domain.PersonsPage{Items: []domain.PersonFromDB{{ID: 3, Name: "Анна", Surname: "Иванова", Age: 42}}}, nil).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/read", h.ReadPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		endpoint string
+		code     int
+		body     string
+	}{
+		{"/read?sort=password", http.StatusBadRequest, ""},
+		{"/read?after=not-a-cursor", http.StatusBadRequest, ""},
+		{"/read?after=" + next.Encode(), http.StatusBadRequest, ""},
+		{"/read?after=&page=1", http.StatusBadRequest, ""},
+		{"/read?sort=-age&limit=1", http.StatusOK, "[{\"id\":7,\"name\":\"Dmitriy\",\"surname\":\"Smirnov\",\"age\":42,\"gender\":\"\",\"nationality\":\"\"}]\n"},
+		{"/read?sort=-age&limit=1&after=", http.StatusOK, "{\"items\":[{\"id\":7,\"name\":\"Dmitriy\",\"surname\":\"Smirnov\",\"age\":42," +
+			"\"gender\":\"\",\"nationality\":\"\"}],\"limit\":1,\"next\":\"/read?after=" + next.Encode() + "\\u0026limit=1\\u0026sort=-age\"," +
+			"\"next_cursor\":\"" + next.Encode() + "\"}\n"},
+		{"/read?sort=-age&limit=1&count=none&after=" + next.Encode(), http.StatusOK, "{\"items\":[{\"id\":3,\"name\":\"Анна\",\"surname\":\"Иванова\"," +
+			"\"age\":42,\"gender\":\"\",\"nationality\":\"\"}],\"limit\":1}\n"},
+	}
+
+	for _, testCase := range testTable {
+		resp, err := ts.Client().Get(ts.URL + testCase.endpoint)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint)
+
+		if testCase.body != "" {
+			require.Equal(t, testCase.body, string(b))
+		}
+	}
+}
+
+func TestReadMetadata(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	items := []domain.PersonFromDB{{ID: 3, Name: "Dmitriy", Surname: "Smirnov", Age: 42}}
+	total := int64(7)
+
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 2, Limit: 3, Sort: domain.DefaultSort, Count: domain.CountExact},
+		gomock.Any()).Return(domain.PersonsPage{Items: items, Total: &total, NextCursor: "c"}, nil).Times(2)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 2, Limit: 3, Sort: domain.DefaultSort, Count: domain.CountNone},
+		gomock.Any()).Return(domain.PersonsPage{Items: items, NextCursor: "c"}, nil).Times(1)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 3, Limit: 3, Sort: domain.DefaultSort, Count: domain.CountEstimate},
+		gomock.Any()).Return(domain.PersonsPage{Items: items, Total: &total, TotalEstimated: true}, nil).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/read", h.ReadPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	get := func(endpoint string, code int) (*http.Response, string) {
+		resp, err := ts.Client().Get(ts.URL + endpoint)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, code, resp.StatusCode, endpoint)
+		return resp, string(b)
+	}
+
+	get("/read?count=all", http.StatusBadRequest)
+	get("/read?envelope=maybe", http.StatusBadRequest)
+
+	// nothing is counted unless the total is requested
+	resp, body := get("/read?page=2&limit=3", http.StatusOK)
+	require.Equal(t, "[{\"id\":3,\"name\":\"Dmitriy\",\"surname\":\"Smirnov\",\"age\":42,\"gender\":\"\",\"nationality\":\"\"}]\n", body)
+	require.Empty(t, resp.Header.Get("X-Total-Count"))
+	require.Equal(t, "</read?limit=3&page=1>; rel=\"first\", </read?limit=3&page=3>; rel=\"next\", </read?limit=3&page=1>; rel=\"prev\"",
+		resp.Header.Get("Link"))
+
+	resp, _ = get("/read?page=2&limit=3&count=exact", http.StatusOK)
+	require.Equal(t, "7", resp.Header.Get("X-Total-Count"))
+	require.Empty(t, resp.Header.Get("X-Total-Count-Estimated"))
+	require.Equal(t, "</read?count=exact&limit=3&page=1>; rel=\"first\", </read?count=exact&limit=3&page=3>; rel=\"last\", "+
+		"</read?count=exact&limit=3&page=3>; rel=\"next\", </read?count=exact&limit=3&page=1>; rel=\"prev\"", resp.Header.Get("Link"))
+
+	_, body = get("/read?page=2&limit=3&envelope=true", http.StatusOK)
+	require.Equal(t, "{\"items\":[{\"id\":3,\"name\":\"Dmitriy\",\"surname\":\"Smirnov\",\"age\":42,\"gender\":\"\",\"nationality\":\"\"}],"+
+		"\"total\":7,\"page\":2,\"limit\":3,\"next\":\"/read?envelope=true\\u0026limit=3\\u0026page=3\",\"prev\":\"/read?envelope=true\\u0026limit=3\\u0026page=1\","+
+		"\"next_cursor\":\"c\"}\n", body)
+
+	resp, _ = get("/read?page=3&limit=3&count=estimate", http.StatusOK)
+	require.Equal(t, "7", resp.Header.Get("X-Total-Count"))
+	require.Equal(t, "true", resp.Header.Get("X-Total-Count-Estimated"))
+	require.Equal(t, "</read?count=estimate&limit=3&page=1>; rel=\"first\", </read?count=estimate&limit=3&page=2>; rel=\"prev\"",
+		resp.Header.Get("Link"))
+}
+
+func TestReadSort(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	sort := domain.Sort{{Column: "age", Desc: true}, {Column: "surname"}, {Column: "name"}}
+	require.Equal(t, append(sort, domain.SortField{Column: "id"}), sort.Keys())
+	require.Equal(t, domain.Sort{{Column: "id", Desc: true}, {Column: "age"}}, domain.Sort{{Column: "id", Desc: true}, {Column: "age"}}.Keys())
+
+	last := domain.PersonFromDB{ID: 7, Name: "Dmitriy", Surname: "Smirnov", Age: 42}
+	next := domain.CursorAfter(sort, last)
+	require.Equal(t, []interface{}{42, "Smirnov", "Dmitriy", 7}, next.Values)
+
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 1, Limit: 10, Sort: sort, Count: domain.CountNone}, gomock.Any()).Return(
+		domain.PersonsPage{Items: []domain.PersonFromDB{last}, NextCursor: next.Encode()}, nil).Times(1)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 1, Limit: 10, Sort: sort, After: &next, Count: domain.CountNone},
+		gomock.Any()).Return(domain.PersonsPage{}, appErrors.ErrNoRowsFound).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/read", h.ReadPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		endpoint string
+		code     int
+	}{
+		{"/read?sort=age,-age", http.StatusBadRequest},
+		{"/read?sort=-age,,name", http.StatusBadRequest},
+		{"/read?sort=-age,is_deleted", http.StatusBadRequest},
+		{"/read?sort=-age,surname&after=" + next.Encode(), http.StatusBadRequest},
+		{"/read?sort=-age,%20surname,name", http.StatusOK},
+		{"/read?sort=-age,surname,name&after=" + next.Encode(), http.StatusNoContent},
+	}
+
+	for _, testCase := range testTable {
+		resp, err := ts.Client().Get(ts.URL + testCase.endpoint)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint)
+	}
+}
+
+func TestReadFilter(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	var got domain.Filters
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ domain.Page, filters domain.Filters) (domain.PersonsPage, error) {
+			got = filters
+			return domain.PersonsPage{}, appErrors.ErrNoRowsFound
+		}).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/read", h.ReadPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		filter string
+		body   string
+	}{
+		{"password==1", `{"error":"unknown attribute of a person","position":1,"token":"password"}`},
+		{"age=lt=thirty", `{"error":"value of the attribute has wrong type","position":8,"token":"thirty"}`},
+		{"age=like=3", `{"error":"operator is not supported in a filter","position":4,"token":"=like="}`},
+		{"age==(1,2)", `{"error":"wrong number of arguments for the filter operator","position":4,"token":"=="}`},
+		{"name==Dmitriy;", `{"error":"unexpected end of expression","position":15,"token":""}`},
+		{"patronymic=isnull=maybe", `{"error":"value of the attribute has wrong type","position":19,"token":"maybe"}`},
+	}
+
+	for _, testCase := range testTable {
+		resp, err := ts.Client().Get(ts.URL + "/read?filter=" + url.QueryEscape(testCase.filter))
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, testCase.filter)
+		require.JSONEq(t, testCase.body, string(b), testCase.filter)
+	}
+
+	resp, err := ts.Client().Get(ts.URL + "/read?nationality=RU&filter=" +
+		url.QueryEscape("nationality=in=(RU,UA);(gender==female,age<30);patronymic=isnull=false"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.Equal(t, "RU", got.NationalityEqualTo.Value.String)
+	require.Equal(t, domain.FilterGroup{Children: []domain.FilterExpression{
+		domain.FilterCondition{Column: "nationality", Operator: domain.OpIn, Values: []interface{}{"RU", "UA"}},
+		domain.FilterGroup{Or: true, Children: []domain.FilterExpression{
+			domain.FilterCondition{Column: "gender", Operator: domain.OpEqual, Values: []interface{}{"female"}},
+			domain.FilterCondition{Column: "age", Operator: domain.OpLess, Values: []interface{}{30}},
+		}},
+		domain.FilterCondition{Column: "patronymic", Operator: domain.OpIsNull, Values: []interface{}{false}},
+	}}, got.Expression)
+}
+
+func TestReadSearch(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	score := 0.75
+	last := domain.PersonFromDB{ID: 7, Name: "Dmitriy", Surname: "Smirnov", Age: 42, Score: &score}
+	next := domain.CursorAfter(domain.RelevanceSort, last)
+	require.Equal(t, []interface{}{0.75, 7}, next.Values)
+
+	var got domain.Filters
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 1, Limit: 10, Sort: domain.RelevanceSort, Count: domain.CountNone},
+		gomock.Any()).DoAndReturn(func(_ context.Context, _ domain.Page, filters domain.Filters) (domain.PersonsPage, error) {
+		got = filters
+		return domain.PersonsPage{Items: []domain.PersonFromDB{last}, NextCursor: next.Encode()}, nil
+	}).Times(1)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 1, Limit: 10, Sort: domain.RelevanceSort, After: &next,
+		Count: domain.CountNone}, gomock.Any()).Return(domain.PersonsPage{}, appErrors.ErrNoRowsFound).Times(1)
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), domain.Page{Number: 1, Limit: 10, Sort: domain.Sort{{Column: "age"}, {Column: "score", Desc: true}},
+		Count: domain.CountNone}, gomock.Any()).Return(domain.PersonsPage{}, appErrors.ErrNoRowsFound).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/read", h.ReadPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		endpoint string
+		code     int
+		body     string
+	}{
+		{"/read?sort=-score", http.StatusBadRequest, ""},
+		{"/read?q=smirnv&name_like=" + url.QueryEscape("dmi%") + "&surname_prefix=Sm", http.StatusOK,
+			"[{\"id\":7,\"name\":\"Dmitriy\",\"surname\":\"Smirnov\",\"age\":42,\"gender\":\"\",\"nationality\":\"\",\"score\":0.75}]\n"},
+		{"/read?q=smirnv&after=" + next.Encode(), http.StatusNoContent, ""},
+		{"/read?q=smirnv&sort=age,-score", http.StatusNoContent, ""},
+	}
+
+	for _, testCase := range testTable {
+		resp, err := ts.Client().Get(ts.URL + testCase.endpoint)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint)
+
+		if testCase.body != "" {
+			require.Equal(t, testCase.body, string(b))
+		}
+	}
+
+	require.Equal(t, "smirnv", got.Query.Value.String)
+	require.Equal(t, []domain.TextMatch{{Column: "name", Value: "dmi%"}, {Column: "surname", Prefix: true, Value: "Sm"}}, got.TextMatches)
+}
+
+func TestReadSoundsLike(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	var got domain.Filters
+	mockRepo.EXPECT().ReadPersons(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ domain.Page, filters domain.Filters) (domain.PersonsPage, error) {
+			got = filters
+			return domain.PersonsPage{}, appErrors.ErrNoRowsFound
+		}).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/read", h.ReadPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/read?sounds_like=" + url.QueryEscape("Dmitry --"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = ts.Client().Get(ts.URL + "/read?sounds_like=" + url.QueryEscape(" Дмитрий  Smirnoff "))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.Equal(t, []string{"TMTR", "SMRNF"}, got.SoundsLike)
+}
+
+func TestReadProviderResponses(t *testing.T) {
+	ts := httptest.NewServer(testRouter(t))
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		endpoint string
+		code     int
+		body     string
+	}{
+		{
+			"/persons/1/provider-responses",
+			http.StatusOK,
+			"[{\"id\":1,\"person_id\":1,\"provider\":\"agify\",\"status_code\":200," +
+				"\"body\":{\"age\":20},\"received_at\":\"0001-01-01T00:00:00Z\"}]",
+		},
+		{
+			"/persons/3/provider-responses",
+			http.StatusOK,
+			"[]",
+		},
+		{
+			"/persons/2/provider-responses",
+			http.StatusNotFound,
+			"",
+		},
+		{
+			"/persons/abc/provider-responses",
+			http.StatusBadRequest,
+			"",
+		},
+	}
+
+	for _, testCase := range testTable {
+		resp, err := ts.Client().Get(ts.URL + testCase.endpoint)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint)
+		if testCase.body != "" {
+			require.JSONEq(t, testCase.body, string(b), testCase.endpoint)
+		} else {
+			require.Empty(t, b, testCase.endpoint)
+		}
+	}
+}
+
+func TestCreateBulk(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), []domain.PersonWithAPIData{{Name: "Dmitriy", Surname: "Sidorov"},
+		{Name: "Ivan", Surname: "Petrov"}}, false).Return([]int{1, 0}, nil).MaxTimes(1)
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), gomock.Any(), false).DoAndReturn(
+		func(_ context.Context, persons []domain.PersonWithAPIData, _ bool) ([]int, error) {
+			ids := make([]int, len(persons))
+			for i := range ids {
+				ids[i] = i + 1
+			}
+
+			return ids, nil
+		}).AnyTimes()
+	mockRepo.EXPECT().EnrichPerson(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	s := service.New(mockRepo)
+
+	var wg sync.WaitGroup
+
+	h := NewBulk(s, service.NewEnricher(s, testProviders(t), &wg, 1, 2), 3)
+	e.POST("/persons/bulk", h.CreatePersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+	defer wg.Wait()
+
+	var testTable = []struct {
+		content string
+		code    int
+		body    string
+		summary string
+	}{
+		{
+			"text/plain",
+			http.StatusBadRequest,
+			"[]",
+			"",
+		},
+		{
+			"application/json",
+			http.StatusBadRequest,
+			"[]",
+			"",
+		},
+		{
+			"application/json",
+			http.StatusBadRequest,
+			"{\"name\": \"Dmitriy\", \"surname\": \"Sidorov\"}",
+			"",
+		},
+		{
+			"application/json",
+			http.StatusRequestEntityTooLarge,
+			"[{\"name\": \"A\", \"surname\": \"B\"}, {\"name\": \"C\", \"surname\": \"D\"}, " +
+				"{\"name\": \"E\", \"surname\": \"F\"}, {\"name\": \"G\", \"surname\": \"H\"}]",
+			"",
+		},
+		{
+			"application/json",
+			http.StatusRequestEntityTooLarge,
+			"[{\"name\": \"A\", \"surname\": \"B\"}, {\"name\": \"C\", \"surname\": \"D\"}, " +
+				"{\"name\": \"E\", \"surname\": \"F\"}, {\"name\": \"G\", " + strings.Repeat("\"surname\": \"H\", ", 1000),
+			"",
+		},
+		{
+			"application/json",
+			http.StatusBadRequest,
+			"[{\"name\": \"Dmitriy\"}, {\"name\": \"Dmitriy\", \"surname\": \"Sidorov\", \"age\": 20}]",
+			"{\"accepted\":0,\"skipped\":0,\"rejected\":2,\"accepted_ids\":[],\"errors\":[{\"line\":1,\"error\":" +
+				"\"validation: surname: is required\",\"violations\":[{\"field\":\"surname\",\"code\":\"required\"," +
+				"\"message\":\"is required\"}]},{\"line\":2,\"error\":\"json: unknown field \\\"age\\\"\"}]}\n",
+		},
+		{
+			"application/json",
+			http.StatusAccepted,
+			"[{\"name\": \"Dmitriy\", \"surname\": \"Sidorov\"}, {\"name\": \"Ivan\", \"surname\": \"Petrov\"}, " +
+				"{\"name\": \"Ivan\", \"surname\": \"Petrov\"}]",
+			"{\"accepted\":1,\"skipped\":1,\"rejected\":1,\"accepted_ids\":[1],\"errors\":[{\"line\":3,\"error\":" +
+				"\"the same person is already present in the request: line 2\"}]}\n",
+		},
+		{
+			"application/x-ndjson",
+			http.StatusAccepted,
+			"{\"name\": \"Ivan\", \"surname\": \"Ivanov\"}\n\n{\"name\": \"Ivan\", \"name\": \"Ivan\"}\n" +
+				"{\"name\": \"Petr\", \"surname\": \"Petrov\"}",
+			"{\"accepted\":2,\"skipped\":0,\"rejected\":1,\"accepted_ids\":[1,2],\"errors\":[{\"line\":3,\"error\":" +
+				"\"duplicate field found in provided JSON\"}]}\n",
+		},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/persons/bulk", strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", testCase.content)
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode)
+
+		if testCase.summary != "" {
+			require.Equal(t, testCase.summary, string(b))
+		}
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), []domain.PersonWithAPIData{{Name: "Dmitriy", Surname: "Sidorov"}}, false).Return(
+		[]int{1}, nil).Times(1)
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), []domain.PersonWithAPIData{{Name: "Ivan", Surname: "Petrov"}}, false).Return(
+		nil, errors.New("connection refused")).Times(2)
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), []domain.PersonWithAPIData{{Name: "Petr", Surname: "Ivanov"}}, false).Return(
+		[]int{2}, nil).Times(2)
+	mockRepo.EXPECT().CreatePerson(gomock.Any(), domain.Person{Name: "Dmitriy", Surname: "Sidorov"}, gomock.Any(),
+		domain.DefaultConflictMode).Return(1, nil).Times(1)
+	mockRepo.EXPECT().EnrichPerson(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	type record struct {
+		token    string
+		hash     string
+		response *domain.IdempotentResponse
+	}
+
+	records := make(map[string]record)
+
+	mockIdempotencyRepo := mocks.NewMockIdempotencyRepository(ctrl)
+	mockIdempotencyRepo.EXPECT().BeginRequest(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), time.Hour).DoAndReturn(
+		func(_ context.Context, key string, token string, hash string, _ time.Duration) (*domain.IdempotentResponse, error) {
+			stored, ok := records[key]
+			if !ok {
+				records[key] = record{token: token, hash: hash}
+				return nil, nil
+			}
+
+			if stored.hash != hash {
+				return nil, appErrors.ErrIdempotencyKeyReused
+			}
+
+			if stored.response == nil {
+				return nil, appErrors.ErrIdempotencyKeyInProgress
+			}
+
+			return stored.response, nil
+		}).AnyTimes()
+	// only the request which claimed a key may complete or abandon it
+	mockIdempotencyRepo.EXPECT().CompleteRequest(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, key string, token string, response domain.IdempotentResponse) error {
+			if stored := records[key]; stored.token != token || stored.response != nil {
+				return appErrors.ErrNoRowsAffected
+			}
+
+			records[key] = record{token: token, hash: records[key].hash, response: &response}
+			return nil
+		}).AnyTimes()
+	mockIdempotencyRepo.EXPECT().AbandonRequest(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, key string, token string) error {
+			if stored := records[key]; stored.token == token && stored.response == nil {
+				delete(records, key)
+			}
+
+			return nil
+		}).AnyTimes()
+
+	inProgress := httptest.NewRequest(http.MethodPost, "/persons/bulk", nil)
+	inProgress.Header.Set("Content-Type", "application/json")
+	records["in-progress"] = record{token: "another-request", hash: requestHash(inProgress, nil)}
+
+	s := service.New(mockRepo)
+
+	var wg sync.WaitGroup
+
+	idempotent := Idempotency(service.NewIdempotency(mockIdempotencyRepo), time.Hour, 1024)
+	bh := NewBulk(s, service.NewEnricher(s, testProviders(t), &wg, 1, 2), 3)
+	h := New(s, &wg, testProviders(t), false, 0)
+	e.POST("/persons/bulk", bh.CreatePersons, idempotent)
+	e.POST("/create", h.CreatePerson, idempotent)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+	defer wg.Wait()
+
+	var testTable = []struct {
+		endpoint string
+		key      string
+		body     string
+		code     int
+		replayed bool
+		response string
+	}{
+		{"/persons/bulk", "first", `[{"name": "Dmitriy", "surname": "Sidorov"}]`, http.StatusAccepted, false,
+			`{"accepted": 1, "skipped": 0, "rejected": 0, "accepted_ids": [1], "errors": []}`},
+		{"/persons/bulk", "first", `[{"name": "Dmitriy", "surname": "Sidorov"}]`, http.StatusAccepted, true,
+			`{"accepted": 1, "skipped": 0, "rejected": 0, "accepted_ids": [1], "errors": []}`},
+		{"/persons/bulk", "first", `[{"name": "Ivan", "surname": "Petrov"}]`, http.StatusUnprocessableEntity, false, ""},
+		{"/persons/bulk?dry_run=true", "first", `[{"name": "Dmitriy", "surname": "Sidorov"}]`, http.StatusUnprocessableEntity, false, ""},
+		{"/persons/bulk", "failing", `[{"name": "Ivan", "surname": "Petrov"}]`, http.StatusInternalServerError, false, ""},
+		{"/persons/bulk", "failing", `[{"name": "Ivan", "surname": "Petrov"}]`, http.StatusInternalServerError, false, ""},
+		{"/persons/bulk", "rejected", `[{"name": "Ivan"}]`, http.StatusBadRequest, false, ""},
+		{"/persons/bulk", "rejected", `[{"name": "Ivan"}]`, http.StatusBadRequest, true, ""},
+		{"/persons/bulk", "in-progress", ``, http.StatusConflict, false, ""},
+		{"/persons/bulk", "too-large", `[{"name": "` + strings.Repeat("a", 1024) + `", "surname": "Ivanov"}]`,
+			http.StatusRequestEntityTooLarge, false, ""},
+		{"/persons/bulk", strings.Repeat("k", 256), `[{"name": "Petr", "surname": "Ivanov"}]`, http.StatusBadRequest, false, ""},
+		{"/persons/bulk", "", `[{"name": "Petr", "surname": "Ivanov"}]`, http.StatusAccepted, false, ""},
+		{"/persons/bulk", "", `[{"name": "Petr", "surname": "Ivanov"}]`, http.StatusAccepted, false, ""},
+		{"/create", "create", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusAccepted, false, ""},
+		{"/create", "create", `{"name": "Dmitriy", "surname": "Sidorov"}`, http.StatusAccepted, true, ""},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if testCase.key != "" {
+			req.Header.Set(domain.IdempotencyKeyHeader, testCase.key)
+		}
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint+" "+testCase.key+" "+testCase.body)
+		require.Equal(t, testCase.replayed, resp.Header.Get(idempotentReplayedHeader) == "true", testCase.key+" "+testCase.body)
+
+		if testCase.response != "" {
+			require.JSONEq(t, testCase.response, string(b))
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), []domain.PersonWithAPIData{{Name: "Дмитрий", Surname: "Смирнов", Patronymic: "Петрович"},
+		{Name: "Ivan", Surname: "Petrov", Age: 30, Gender: "male"}}, true).Return([]int{5, 0}, nil).MaxTimes(1)
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), []domain.PersonWithAPIData{{Name: "Дмитрий", Surname: "Смирнов"}}, false).
+		Return([]int{7}, nil).MaxTimes(1)
+	mockRepo.EXPECT().EnrichPerson(gomock.Any(), 7, gomock.Any()).Return(nil).AnyTimes()
+
+	s := service.New(mockRepo)
+
+	var wg sync.WaitGroup
+
+	h := NewImports(service.NewImporter(s, service.NewEnricher(s, testProviders(t), &wg, 1, 1), 10))
+	e.POST("/import/csv", h.ImportCSV)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+	defer wg.Wait()
+
+	windows1251, err := charmap.Windows1251.NewEncoder().String("Фамилия;Имя\nСмирнов;Дмитрий\n")
+	require.NoError(t, err)
+
+	var testTable = []struct {
+		endpoint string
+		content  string
+		code     int
+		body     string
+		report   string
+	}{
+		{
+			"/import/csv",
+			"application/json",
+			http.StatusBadRequest,
+			"name,surname\nDmitriy,Smirnov\n",
+			"",
+		},
+		{
+			"/import/csv?encoding=koi8-r",
+			"text/csv",
+			http.StatusBadRequest,
+			"name,surname\nDmitriy,Smirnov\n",
+			"",
+		},
+		{
+			"/import/csv",
+			"text/csv",
+			http.StatusBadRequest,
+			"name,age\nDmitriy,20\n",
+			"",
+		},
+		{
+			"/import/csv",
+			"text/csv",
+			http.StatusRequestEntityTooLarge,
+			"name,surname\n" + strings.Repeat("Dmitriy,Smirnov\n", 11),
+			"",
+		},
+		{
+			"/import/csv?dry_run=true&mapping=" + url.QueryEscape("Фамилия:surname,Имя:name,Отчество:patronymic"),
+			"text/csv",
+			http.StatusOK,
+			"\xef\xbb\xbfФамилия,Имя,Отчество,age,gender,Отдел\n" +
+				"Смирнов,Дмитрий,Петрович,,,ИТ\n" +
+				"Petrov,Ivan,,30,male,ИТ\n" +
+				"Petrov,Ivan,,31,male,ИТ\n" +
+				"Sidorov,,,,,ИТ\n" +
+				"Sidorov,Dmitriy,,-3,,ИТ\n" +
+				"Kuznetsov,Oleg,,,,ИТ,лишний\n" +
+				"Kuz\"netsov,Oleg,,,,ИТ\n" +
+				"\"Orlov,Pavel,,,,ИТ\n",
+			"line,status,id,name,surname,patronymic,error\n" +
+				"2,created,,Дмитрий,Смирнов,Петрович,\n" +
+				"3,skipped,,Ivan,Petrov,,the entity already exists in table\n" +
+				"4,skipped,,Ivan,Petrov,,the same person is already present in the request: line 3\n" +
+				"5,rejected,,,Sidorov,,required fields not provided\n" +
+				"6,rejected,,Dmitriy,Sidorov,,value of the attribute has wrong type: age\n" +
+				"7,rejected,,,,,wrong number of fields\n" +
+				"8,rejected,,Oleg,\"Kuz\"\"netsov\",,\"validation: surname: must consist of letters of one script of Latin, Cyrillic " +
+				"separated by single spaces, hyphens or apostrophes\"\n" +
+				"9,rejected,,,,,wrong number of fields\n",
+		},
+		{
+			"/import/csv?delimiter=%3B&encoding=windows-1251&mapping=" + url.QueryEscape("Фамилия:surname,Имя:name"),
+			"text/csv",
+			http.StatusAccepted,
+			windows1251,
+			"line,status,id,name,surname,patronymic,error\n" +
+				"2,created,7,Дмитрий,Смирнов,,\n",
+		},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", testCase.content)
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode)
+
+		if testCase.report != "" {
+			require.Equal(t, testCase.report, string(b))
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	persons := []domain.PersonFromDB{{ID: 1, Name: "Dmitriy", Surname: "Smirnov", Patronymic: "Petrovich", Age: 42, Gender: "male",
+		Nationality: "RU"}, {ID: 2, Name: "Анна", Surname: "Иванова, мл.", Age: 30, Gender: "female", Nationality: "UA"}}
+
+	mockRepo.EXPECT().StreamPersons(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ domain.Filters, fn func(domain.PersonFromDB) error) error {
+			for _, person := range persons {
+				if err := fn(person); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}).MaxTimes(3)
+	mockRepo.EXPECT().StreamPersons(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection refused")).MaxTimes(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/export", h.ExportPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		endpoint string
+		code     int
+		body     string
+	}{
+		{
+			"/export?format=xml",
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"/export?columns=id,password",
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"/export?columns=name,surname,name",
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"/export?agegt=30&agelt=20",
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"/export",
+			http.StatusOK,
+			"id,name,surname,patronymic,age,gender,nationality\n1,Dmitriy,Smirnov,Petrovich,42,male,RU\n" +
+				"2,Анна,\"Иванова, мл.\",,30,female,UA\n",
+		},
+		{
+			"/export?format=ndjson&columns=surname,age",
+			http.StatusOK,
+			"{\"surname\":\"Smirnov\",\"age\":42}\n{\"surname\":\"Иванова, мл.\",\"age\":30}\n",
+		},
+		{
+			"/export?format=csv&columns=name",
+			http.StatusOK,
+			"name\nDmitriy\nАнна\n",
+		},
+		{
+			"/export",
+			http.StatusInternalServerError,
+			"",
+		},
+	}
+
+	for _, testCase := range testTable {
+		resp, err := ts.Client().Get(ts.URL + testCase.endpoint)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode)
+
+		if testCase.body != "" {
+			require.Equal(t, testCase.body, string(b))
+		}
+	}
+}
+
+func TestExportSlowClient(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	streamed := make(chan error, 1)
+	mockRepo.EXPECT().StreamPersons(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ domain.Filters, fn func(domain.PersonFromDB) error) error {
+			person := domain.PersonFromDB{Name: "Dmitriy", Surname: "Smirnov", Patronymic: "Petrovich", Age: 42, Gender: "male",
+				Nationality: "RU"}
+
+			var err error
+			for id := 1; id <= 10000000 && err == nil; id++ {
+				person.ID = id
+				err = fn(person)
+			}
+
+			streamed <- err
+			return err
+		}).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 50*time.Millisecond)
+	e.GET("/export", h.ExportPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/export")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the body is not read, so the export has to give up instead of waiting for the client
+	select {
+	case err = <-streamed:
+		require.Error(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("export is still waiting for the client")
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	persons := []domain.PersonFromDB{{ID: 1, Name: "Dmitriy", Surname: "Smirnov", Age: 42, Gender: "male", Nationality: "RU"},
+		{ID: 2, Name: "Анна", Surname: "Иванова", Age: 30, Gender: "female", Nationality: "RU"},
+		{ID: 3, Name: "Taras", Surname: "Shevchenko", Gender: "male", Nationality: "UA"}}
+
+	mockRepo.EXPECT().StreamPersons(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ domain.Filters, fn func(domain.PersonFromDB) error) error {
+			for _, person := range persons {
+				if err := fn(person); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}).Times(2)
+	mockRepo.EXPECT().StreamPersons(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection refused")).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/export", h.ExportPersons)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	readSheets := func(endpoint string) map[string]string {
+		resp, err := ts.Client().Get(ts.URL + endpoint)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, xlsxContentType, resp.Header.Get("Content-Type"))
+		require.Contains(t, resp.Header.Get("Content-Disposition"), "persons.xlsx")
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		require.NoError(t, err)
+
+		sheets := make(map[string]string)
+		for _, f := range r.File {
+			if !strings.HasPrefix(f.Name, "xl/worksheets/") {
+				continue
+			}
+
+			rc, err := f.Open()
+			require.NoError(t, err)
+
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			rc.Close()
+
+			sheets[f.Name] = string(content)
+		}
+
+		return sheets
+	}
+
+	for _, endpoint := range []string{"/export?format=xlsx&stats=maybe", "/export?format=csv&stats=true"} {
+		resp, err := ts.Client().Get(ts.URL + endpoint)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, endpoint)
+	}
+
+	sheets := readSheets("/export?format=xlsx&columns=id,name,age")
+	require.Len(t, sheets, 1)
+
+	persons1 := sheets["xl/worksheets/sheet1.xml"]
+	require.Contains(t, persons1, `state="frozen"`)
+	require.Contains(t, persons1, `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	require.Contains(t, persons1, `<row r="2"><c r="A2"><v>1</v></c><c r="B2" t="inlineStr"><is><t xml:space="preserve">Dmitriy</t></is></c><c r="C2"><v>42</v></c></row>`)
+	// the unknown age is left empty
+	require.Contains(t, persons1, `<row r="4"><c r="A4"><v>3</v></c><c r="B4" t="inlineStr"><is><t xml:space="preserve">Taras</t></is></c></row>`)
+	require.Contains(t, persons1, `<autoFilter ref="A1:C4"/>`)
+
+	sheets = readSheets("/export?format=xlsx&stats=true")
+	require.Len(t, sheets, 2)
+
+	stats := sheets["xl/worksheets/sheet2.xml"]
+	for _, row := range [][2]string{{"total", "3"}, {"average age", "36"}, {"min age", "30"}, {"max age", "42"},
+		{"gender: male", "2"}, {"gender: female", "1"}, {"nationality: RU", "2"}, {"nationality: UA", "1"}} {
+		require.Regexp(t, `<t xml:space="preserve">`+row[0]+`</t></is></c><c r="B\d+"><v>`+row[1]+`</v>`, stats)
+	}
+
+	require.Less(t, strings.Index(stats, "gender: male"), strings.Index(stats, "gender: female"))
+
+	// nothing is sent before the query succeeds
+	resp, err := ts.Client().Get(ts.URL + "/export?format=xlsx")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+func TestVCard(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	person := domain.PersonFromDB{ID: 1, Name: "Дмитрий", Surname: "Смирнов", Patronymic: "Петрович", Age: 42, Gender: "male", Nationality: "RU"}
+
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 1).Return(person, nil).AnyTimes()
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 2).Return(domain.PersonFromDB{}, appErrors.ErrNoRowsFound).AnyTimes()
+	mockRepo.EXPECT().StreamPersons(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ domain.Filters, fn func(domain.PersonFromDB) error) error {
+			return fn(person)
+		}).AnyTimes()
+	mockRepo.EXPECT().CreatePersons(gomock.Any(), []domain.PersonWithAPIData{{Name: "Дмитрий", Surname: "Смирнов", Patronymic: "Петрович",
+		Age: 42, Gender: "male", Nationality: "RU"}, {Name: "Anna", Surname: "Ivanova"}}, true).Return([]int{0, 3}, nil).MaxTimes(1)
+
+	s := service.New(mockRepo)
+
+	var wg sync.WaitGroup
+
+	h := New(s, &wg, testProviders(t), false, 0)
+	ih := NewImports(service.NewImporter(s, service.NewEnricher(s, testProviders(t), &wg, 1, 1), 10))
+	e.GET("/export", h.ExportPersons)
+	e.GET("/persons/:id", h.ReadPerson)
+	e.POST("/import/vcard", ih.ImportVCard)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+	defer wg.Wait()
+
+	card := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Дмитрий Петрович Смирнов\r\nN:Смирнов;Дмитрий;Петрович;;\r\nGENDER:M\r\n" +
+		"X-PREDICTED-AGE:42\r\nX-NATIONALITY:RU\r\nX-IDENTITY-FORECASTER-ID:1\r\nEND:VCARD\r\n"
+
+	var testTable = []struct {
+		method   string
+		endpoint string
+		content  string
+		code     int
+		body     string
+		response string
+	}{
+		{
+			http.MethodGet,
+			"/persons/1.vcf",
+			"",
+			http.StatusOK,
+			"",
+			card,
+		},
+		{
+			http.MethodGet,
+			"/persons/2.vcf",
+			"",
+			http.StatusNotFound,
+			"",
+			"",
+		},
+		{
+			http.MethodGet,
+			"/persons/1",
+			"",
+			http.StatusOK,
+			"",
+			"",
+		},
+		{
+			http.MethodGet,
+			"/persons/abc.vcf",
+			"",
+			http.StatusBadRequest,
+			"",
+			"",
+		},
+		{
+			http.MethodGet,
+			"/export?format=vcf",
+			"",
+			http.StatusOK,
+			"",
+			card,
+		},
+		{
+			http.MethodPost,
+			"/import/vcard",
+			"text/plain",
+			http.StatusBadRequest,
+			card,
+			"",
+		},
+		{
+			http.MethodPost,
+			"/import/vcard",
+			"text/vcard",
+			http.StatusBadRequest,
+			"BEGIN:VCARD\r\nN:Ivanova;Anna\r\n",
+			"",
+		},
+		{
+			http.MethodPost,
+			"/import/vcard?dry_run=true",
+			"text/vcard",
+			http.StatusOK,
+			card + "BEGIN:VCARD\nFN:Anna\nEND:VCARD\nBEGIN:VCARD\nN:Ivanova;Anna;;;\nX-PREDICTED-AGE:many\nEND:VCARD\n" +
+				"BEGIN:VCARD\nN:Ivanova;\n Anna;;;\nEND:VCARD\n" + card,
+			"line,status,id,name,surname,patronymic,error\n" +
+				"1,skipped,,Дмитрий,Смирнов,Петрович,the entity already exists in table\n" +
+				"10,rejected,,,,,required fields not provided\n" +
+				"13,rejected,,Anna,Ivanova,,value of the attribute has wrong type\n" +
+				"17,created,,Anna,Ivanova,,\n" +
+				"21,skipped,,Дмитрий,Смирнов,Петрович,the same person is already present in the request: line 1\n",
+		},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(testCase.method, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", testCase.content)
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode)
+
+		if testCase.response != "" {
+			require.Equal(t, testCase.response, string(b))
+		}
+	}
+}
+
+func TestReadPerson(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	updatedAt := time.Date(2024, time.March, 1, 12, 30, 15, 500, time.UTC)
+	person := domain.PersonFromDB{ID: 1, Name: "Dmitriy", Surname: "Smirnov", Patronymic: "Petrovich", Age: 42, Gender: "male",
+		Nationality: "RU", Version: 3, UpdatedAt: updatedAt}
+
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 1).Return(person, nil).AnyTimes()
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 2).Return(person, nil).Times(1)
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 3).Return(domain.PersonFromDB{}, appErrors.ErrNoRowsFound).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/persons/:id", h.ReadPerson)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	get := func(endpoint string, header string, value string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+endpoint, nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp, string(b)
+	}
+
+	resp, body := get("/persons/1", "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.JSONEq(t, `{"id": 1, "name": "Dmitriy", "surname": "Smirnov", "patronymic": "Petrovich", "age": 42, "gender": "male",
+		"nationality": "RU", "version": 3}`, body)
+	require.Equal(t, "Fri, 01 Mar 2024 12:30:15 GMT", resp.Header.Get("Last-Modified"))
+
+	etag := resp.Header.Get("ETag")
+	require.Regexp(t, `^"3-[0-9a-f]{32}"$`, etag)
+
+	var testTable = []struct {
+		header string
+		value  string
+		code   int
+	}{
+		{"If-None-Match", etag, http.StatusNotModified},
+		{"If-None-Match", `"other", W/` + etag, http.StatusNotModified},
+		{"If-None-Match", "*", http.StatusNotModified},
+		{"If-None-Match", `"other"`, http.StatusOK},
+		{"If-Modified-Since", "Fri, 01 Mar 2024 12:30:15 GMT", http.StatusNotModified},
+		{"If-Modified-Since", "Fri, 01 Mar 2024 12:30:14 GMT", http.StatusOK},
+		{"If-Modified-Since", "yesterday", http.StatusOK},
+	}
+
+	for _, testCase := range testTable {
+		resp, body = get("/persons/1", testCase.header, testCase.value)
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.value)
+		require.Equal(t, etag, resp.Header.Get("ETag"))
+
+		if testCase.code == http.StatusNotModified {
+			require.Empty(t, body)
+		}
+	}
+
+	resp, _ = get("/persons/2", "", "")
+	require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	require.Equal(t, "/persons/1", resp.Header.Get("Location"))
+
+	resp, _ = get("/persons/3", "", "")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = get("/persons/abc", "", "")
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body = get("/persons/1.vcf", "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, body, "BEGIN:VCARD")
+}
+
+func TestVersions(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	current := domain.PersonFromDB{ID: 1, Name: "Dmitriy", Surname: "Smirnov", Age: 42, Version: 3}
+	updated := domain.PersonFromDB{ID: 1, Name: "Dmitriy", Surname: "Smirnov", Age: 43, Version: 4}
+
+	body, err := json.Marshal(current)
+	require.NoError(t, err)
+	etag := personETag(current.Version, body)
+
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 1).Return(current, nil).AnyTimes()
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 3).Return(domain.PersonFromDB{}, appErrors.ErrNoRowsFound).Times(1)
+	mockRepo.EXPECT().UpdatePerson(gomock.Any(), 1, domain.PersonWithAPIData{Age: 43}, 3).Return(updated, nil).Times(3)
+	mockRepo.EXPECT().UpdatePerson(gomock.Any(), 1, domain.PersonWithAPIData{Age: 43}, 2).Return(domain.PersonFromDB{},
+		appErrors.ErrVersionMismatch).Times(1)
+	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), 1, 4).Return(nil).Times(1)
+	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), 1, 3).Return(nil).Times(1)
+	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), 1, 2).Return(appErrors.ErrVersionMismatch).Times(1)
+	mockRepo.EXPECT().DeletePersonByID(gomock.Any(), 2, 0).Return(nil).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), true, 0)
+	e.PUT("/update/:id", h.UpdatePerson)
+	e.DELETE("/delete/:id", h.DeletePersonByID)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		method   string
+		endpoint string
+		ifMatch  string
+		body     string
+		code     int
+	}{
+		{http.MethodPut, "/update/1", "", `{"age": 43, "version": 3}`, http.StatusOK},
+		{http.MethodPut, "/update/1", etag, `{"age": 43}`, http.StatusOK},
+		{http.MethodPut, "/update/1", `"2-0123456789abcdef0123456789abcdef", ` + etag, `{"age": 43}`, http.StatusOK},
+		{http.MethodPut, "/update/1", "", `{"age": 43, "version": 2}`, http.StatusPreconditionFailed},
+		{http.MethodPut, "/update/1", "W/" + etag, `{"age": 43}`, http.StatusPreconditionFailed},
+		{http.MethodPut, "/update/1", `"3-0123456789abcdef0123456789abcdef"`, `{"age": 43}`, http.StatusPreconditionFailed},
+		{http.MethodPut, "/update/1", `"3-a", "4-b"`, `{"age": 43}`, http.StatusPreconditionFailed},
+		{http.MethodPut, "/update/1", etag, `{"age": 43, "version": 2}`, http.StatusBadRequest},
+		{http.MethodPut, "/update/1", "", `{"age": 43}`, http.StatusPreconditionRequired},
+		{http.MethodDelete, "/delete/1?version=4", "", "", http.StatusOK},
+		{http.MethodDelete, "/delete/1", etag, "", http.StatusOK},
+		{http.MethodDelete, "/delete/1?version=2", "", "", http.StatusPreconditionFailed},
+		{http.MethodDelete, "/delete/1", `"4-0123456789abcdef0123456789abcdef"`, "", http.StatusPreconditionFailed},
+		{http.MethodDelete, "/delete/3", etag, "", http.StatusPreconditionFailed},
+		{http.MethodDelete, "/delete/1?version=0", "", "", http.StatusPreconditionFailed},
+		{http.MethodDelete, "/delete/1?version=abc", "", "", http.StatusBadRequest},
+		{http.MethodDelete, "/delete/1", "", "", http.StatusPreconditionRequired},
+		{http.MethodDelete, "/delete/2", "*", "", http.StatusOK},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(testCase.method, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if testCase.ifMatch != "" {
+			req.Header.Set("If-Match", testCase.ifMatch)
+		}
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint+" "+testCase.ifMatch+" "+testCase.body)
+
+		if testCase.method == http.MethodPut && testCase.code == http.StatusOK {
+			require.JSONEq(t, `{"id": 1, "name": "Dmitriy", "surname": "Smirnov", "age": 43, "gender": "", "nationality": "",
+				"version": 4}`, string(b))
+			require.Regexp(t, `^"4-[0-9a-f]{32}"$`, resp.Header.Get("ETag"))
+		}
+	}
+}
+
+func TestPatchPerson(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	stored := domain.PersonFromDB{ID: 1, Name: "Dmitriy", Surname: "Smirnov", Patronymic: "Petrovich", Age: 42, Gender: "male",
+		Nationality: "RU", Version: 3}
+
+	mockRepo.EXPECT().ModifyPerson(gomock.Any(), 1, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ int, version int, modify func(domain.PersonFromDB) (domain.PersonFromDB, error)) (domain.PersonFromDB, error) {
+			if version != 0 && version != stored.Version {
+				return domain.PersonFromDB{}, appErrors.ErrVersionMismatch
+			}
+
+			modified, err := modify(stored)
+			if err != nil {
+				return domain.PersonFromDB{}, err
+			}
+
+			modified.Version++
+			return modified, nil
+		}).AnyTimes()
+	mockRepo.EXPECT().ModifyPerson(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(domain.PersonFromDB{},
+		appErrors.ErrNoRowsFound).Times(1)
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 1).Return(stored, nil).AnyTimes()
+
+	body, err := json.Marshal(stored)
+	require.NoError(t, err)
+	etag := personETag(stored.Version, body)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.PATCH("/persons/:id", h.PatchPerson)
+	e.PUT("/persons/:id", h.ReplacePerson)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	const mergePatch = "application/merge-patch+json"
+
+	var testTable = []struct {
+		method      string
+		endpoint    string
+		contentType string
+		ifMatch     string
+		body        string
+		code        int
+		response    string
+	}{
+		{
+			http.MethodPatch, "/persons/1", mergePatch, "", `{"age": 43, "patronymic": null}`, http.StatusOK,
+			`{"id": 1, "name": "Dmitriy", "surname": "Smirnov", "age": 43, "gender": "male", "nationality": "RU", "version": 4}`,
+		},
+		{
+			http.MethodPatch, "/persons/1", mergePatch, etag, `{"gender": null, "nationality": null}`, http.StatusOK,
+			`{"id": 1, "name": "Dmitriy", "surname": "Smirnov", "patronymic": "Petrovich", "age": 42, "gender": "", "nationality": "",
+				"version": 4}`,
+		},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{}`, http.StatusOK, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{"age": 43, "version": 2}`, http.StatusPreconditionFailed, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{"name": null}`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{"surname": ""}`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{"id": 5}`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{"height": 180}`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{"age": "43"}`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `[{"age": 43}]`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `null`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", mergePatch, "", `{"age": 43, "age": 44}`, http.StatusBadRequest, ""},
+		{http.MethodPatch, "/persons/1", "application/json", "", `{"age": 43}`, http.StatusUnsupportedMediaType, ""},
+		{http.MethodPatch, "/persons/2", mergePatch, "", `{"age": 43}`, http.StatusNotFound, ""},
+		{
+			http.MethodPut, "/persons/1", "application/json", "", `{"name": "Ivan", "surname": "Petrov", "patronymic": "", "age": 0,
+				"gender": "male", "nationality": "", "version": 3}`, http.StatusOK,
+			`{"id": 1, "name": "Ivan", "surname": "Petrov", "age": 0, "gender": "male", "nationality": "", "version": 4}`,
+		},
+		{http.MethodPut, "/persons/1", "application/json", "", `{"name": "Ivan", "surname": "Petrov"}`, http.StatusBadRequest, ""},
+		{
+			http.MethodPut, "/persons/1", "application/json", "", `{"name": "Ivan", "surname": "Petrov", "patronymic": "", "age": 0,
+				"gender": "male", "nationality": "", "height": 180}`, http.StatusBadRequest, "",
+		},
+		{
+			http.MethodPut, "/persons/1", "application/json", `"2-0123456789abcdef0123456789abcdef"`, `{"name": "Ivan", "surname": "Petrov",
+				"patronymic": "", "age": 0, "gender": "male", "nationality": ""}`, http.StatusPreconditionFailed, "",
+		},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(testCase.method, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", testCase.contentType)
+		if testCase.ifMatch != "" {
+			req.Header.Set("If-Match", testCase.ifMatch)
+		}
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.method+" "+testCase.endpoint+" "+testCase.body)
+
+		if testCase.code == http.StatusUnsupportedMediaType {
+			require.Equal(t, mergePatch, resp.Header.Get("Accept-Patch"))
+		}
+
+		if testCase.response != "" {
+			require.JSONEq(t, testCase.response, string(b))
+			require.Regexp(t, `^"4-[0-9a-f]{32}"$`, resp.Header.Get("ETag"))
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.POST("/create", h.CreatePerson)
+	e.PUT("/update/:id", h.UpdatePerson)
+	e.PATCH("/persons/:id", h.PatchPerson)
+	e.PUT("/persons/:id", h.ReplacePerson)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		method      string
+		endpoint    string
+		contentType string
+		body        string
+		response    string
+	}{
+		{
+			http.MethodPost, "/create", "application/json", `{"name": "Dm1triy", "patronymic": "` + strings.Repeat("а", 101) + `"}`,
+			`{"errors": [{"field": "name", "code": "wrong_script", "message": "must consist of letters of one script of Latin, ` +
+				`Cyrillic separated by single spaces, hyphens or apostrophes"}, {"field": "surname", "code": "required", ` +
+				`"message": "is required"}, {"field": "patronymic", "code": "too_long", "message": "must be at most 100 characters long"}]}`,
+		},
+		{
+			http.MethodPut, "/update/1", "application/json", `{"age": -3, "gender": "banana", "nationality": "Russia"}`,
+			`{"errors": [{"field": "age", "code": "too_small", "message": "must be at least 0"}, {"field": "gender", "code": ` +
+				`"not_allowed", "message": "must be one of: male, female"}, {"field": "nationality", "code": "wrong_country_code", ` +
+				`"message": "must be an ISO 3166-1 alpha-2 code in upper case, e.g. RU"}]}`,
+		},
+		{
+			http.MethodPatch, "/persons/1", "application/merge-patch+json", `{"age": 200, "gender": null}`,
+			`{"errors": [{"field": "age", "code": "too_large", "message": "must be at most 150"}]}`,
+		},
+		{
+			http.MethodPut, "/persons/1", "application/json", `{"name": "Ivan", "surname": "Petrov", "patronymic": "", "age": 0,
+				"gender": "", "nationality": "RUS"}`,
+			`{"errors": [{"field": "nationality", "code": "wrong_country_code", "message": "must be an ISO 3166-1 alpha-2 code ` +
+				`in upper case, e.g. RU"}]}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(testCase.method, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", testCase.contentType)
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, testCase.method+" "+testCase.endpoint)
+		require.JSONEq(t, testCase.response, string(b))
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockForecasterRepository(ctrl)
+
+	survivor := domain.PersonFromDB{ID: 1, Name: "Dmitriy", Surname: "Smirnov", Patronymic: "Petrovich", Age: 42, Gender: "male", Nationality: "RU"}
+	merged := domain.PersonFromDB{ID: 2, Name: "Дмитрий", Surname: "Смирнов", Patronymic: "Петрович"}
+
+	mockRepo.EXPECT().ReadDuplicates(gomock.Any(), 0.8, 5, 5).Return([]domain.DuplicatePair{{Person: survivor, Duplicate: merged,
+		Score: 0.78, NameScore: 0.9, AttributeScore: 0.5}}, nil).Times(1)
+	mockRepo.EXPECT().ReadDuplicates(gomock.Any(), 0.0, 10, 0).Return(nil, appErrors.ErrNoRowsFound).Times(1)
+
+	mergeRequest := domain.MergeRequest{SurvivorID: 1, MergedID: 2, Fields: map[string]string{"name": domain.MergeFromMerged}}
+	mockRepo.EXPECT().MergePersons(gomock.Any(), mergeRequest).Return(mergeRequest.Apply(survivor, merged), nil).Times(1)
+	mockRepo.EXPECT().MergePersons(gomock.Any(), domain.MergeRequest{SurvivorID: 1, MergedID: 3}).Return(domain.PersonFromDB{},
+		appErrors.ErrNoRowsFound).Times(1)
+	mockRepo.EXPECT().MergePersons(gomock.Any(), domain.MergeRequest{SurvivorID: 1, MergedID: 4}).Return(domain.PersonFromDB{},
+		appErrors.ErrUniqueViolation).Times(1)
+
+	mockRepo.EXPECT().ReadPersonByID(gomock.Any(), 2).Return(survivor, nil).Times(1)
+
+	var wg sync.WaitGroup
+
+	h := New(service.New(mockRepo), &wg, testProviders(t), false, 0)
+	e.GET("/duplicates", h.ReadDuplicates)
+	e.POST("/persons/merge", h.MergePersons)
+	e.GET("/persons/:id", h.ReadPerson)
+
+	ts := httptest.NewServer(e)
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		method   string
+		endpoint string
+		body     string
+		code     int
+		response string
+	}{
+		{
+			http.MethodGet,
+			"/duplicates?page=2&limit=5&min_score=0.8",
+			"",
+			http.StatusOK,
+			`[{"person": {"id": 1, "name": "Dmitriy", "surname": "Smirnov", "patronymic": "Petrovich", "age": 42, "gender": "male",
+				"nationality": "RU"}, "duplicate": {"id": 2, "name": "Дмитрий", "surname": "Смирнов", "patronymic": "Петрович",
+				"age": 0, "gender": "", "nationality": ""}, "score": 0.78, "name_score": 0.9, "attribute_score": 0.5,
+				"found_at": "0001-01-01T00:00:00Z"}]`,
+		},
+		{
+			http.MethodGet,
+			"/duplicates",
+			"",
+			http.StatusNoContent,
+			"",
+		},
+		{
+			http.MethodGet,
+			"/duplicates?min_score=1.5",
+			"",
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			http.MethodPost,
+			"/persons/merge",
+			`{"survivor_id": 1, "merged_id": 2, "fields": {"name": "merged"}}`,
+			http.StatusOK,
+			`{"id": 1, "name": "Дмитрий", "surname": "Smirnov", "patronymic": "Petrovich", "age": 42, "gender": "male", "nationality": "RU"}`,
+		},
+		{
+			http.MethodPost,
+			"/persons/merge",
+			`{"survivor_id": 1, "merged_id": 1}`,
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			http.MethodPost,
+			"/persons/merge",
+			`{"survivor_id": 1, "merged_id": 2, "fields": {"name": "both"}}`,
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			http.MethodPost,
+			"/persons/merge",
+			`{"survivor_id": 1, "merged_id": 2, "fields": {"id": "merged"}}`,
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			http.MethodPost,
+			"/persons/merge",
+			`{"survivor_id": 1, "merged_id": 3}`,
+			http.StatusNotFound,
+			"",
+		},
+		{
+			http.MethodPost,
+			"/persons/merge",
+			`{"survivor_id": 1, "merged_id": 4}`,
+			http.StatusConflict,
+			"",
+		},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(testCase.method, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint)
+
+		if testCase.response != "" {
+			require.JSONEq(t, testCase.response, string(b))
+		}
+	}
+
+	// the id of a merged person leads to the survivor
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(ts.URL + "/persons/2.vcf")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	require.Equal(t, "/persons/1.vcf", resp.Header.Get("Location"))
+}
+
+func testProvidersRouter(t *testing.T) *echo.Echo {
+	e := echo.New()
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockProvidersRepository(ctrl)
+
+	created := domain.StoredProvider{ID: 1, Position: 1, Enabled: true, ProviderConfig: domain.ProviderConfig{Name: "agify",
+		URL: "http://localhost:8080/agify", Input: "name"}}
+
+	mockRepo.EXPECT().CreateProvider(gomock.Any(), gomock.Any()).Return(created, nil).MaxTimes(1)
+	mockRepo.EXPECT().CreateProvider(gomock.Any(), gomock.Any()).Return(domain.StoredProvider{}, appErrors.ErrUniqueViolation).MaxTimes(1)
+
+	mockRepo.EXPECT().ReadProviders(gomock.Any()).Return([]domain.StoredProvider{created}, nil).MaxTimes(1)
+
+	broken := domain.StoredProvider{ID: 3, Position: 2, Enabled: false, ProviderConfig: domain.ProviderConfig{Name: "genderize",
+		Input: "name"}}
+
+	modifyStored := func(stored domain.StoredProvider) func(context.Context, int,
+		func(domain.StoredProvider) (domain.StoredProvider, error)) (domain.StoredProvider, error) {
+		return func(_ context.Context, _ int, modify func(domain.StoredProvider) (domain.StoredProvider, error)) (domain.StoredProvider, error) {
+			return modify(stored)
+		}
+	}
+
+	mockRepo.EXPECT().ModifyProvider(gomock.Any(), 1, gomock.Any()).DoAndReturn(modifyStored(created)).MaxTimes(1)
+	mockRepo.EXPECT().ModifyProvider(gomock.Any(), 2, gomock.Any()).Return(domain.StoredProvider{}, appErrors.ErrNoRowsFound).MaxTimes(1)
+	mockRepo.EXPECT().ModifyProvider(gomock.Any(), 3, gomock.Any()).DoAndReturn(modifyStored(broken)).MaxTimes(1)
+
+	mockRepo.EXPECT().DeleteProvider(gomock.Any(), 1).Return(nil).MaxTimes(1)
+
+	s := service.NewProviders(mockRepo)
+	h := NewProviders(s)
+
+	admin := e.Group("/admin", AdminAuth("secret"))
+	admin.POST("/providers", h.CreateProvider)
+	admin.GET("/providers", h.ReadProviders)
+	admin.PUT("/providers/:id", h.UpdateProvider)
+	admin.DELETE("/providers/:id", h.DeleteProvider)
+
+	return e
+}
+
+func TestAdminProviders(t *testing.T) {
+	ts := httptest.NewServer(testProvidersRouter(t))
+
+	defer ts.Close()
+
+	var testTable = []struct {
+		endpoint string
+		method   string
+		content  string
+		token    string
+		code     int
+		body     string
+	}{
+		{
+			"/admin/providers",
+			http.MethodGet,
+			"",
+			"",
+			http.StatusUnauthorized,
+			"",
+		},
+		{
+			"/admin/providers",
+			http.MethodPost,
+			"application/json",
+			"secret",
+			http.StatusBadRequest,
+			"{\"name\": \"agify\", \"url\": \"http://localhost:8080/agify\", \"input\": \"middle_name\"}",
+		},
+		{
+			"/admin/providers",
+			http.MethodPost,
+			"application/json",
+			"secret",
+			http.StatusBadRequest,
+			"{\"name\": \"agify\", \"type\": \"ftp\", \"url\": \"http://localhost:8080/agify\", \"input\": \"name\"}",
+		},
+		{
+			"/admin/providers",
+			http.MethodPost,
+			"application/json",
+			"secret",
+			http.StatusCreated,
+			"{\"name\": \"agify\", \"url\": \"http://localhost:8080/agify\", \"input\": \"name\", \"fields\": {\"age\": \"age\"}}",
+		},
+		{
+			"/admin/providers",
+			http.MethodPost,
+			"application/json",
+			"secret",
+			http.StatusConflict,
+			"{\"name\": \"agify\", \"url\": \"http://localhost:8080/agify\", \"input\": \"name\"}",
+		},
+		{
+			"/admin/providers",
+			http.MethodGet,
+			"",
+			"secret",
+			http.StatusOK,
+			"",
+		},
+		{
+			"/admin/providers/1",
+			http.MethodPut,
+			"application/json",
+			"secret",
+			http.StatusOK,
+			"{\"enabled\": false, \"retries\": 2}",
+		},
+		{
+			"/admin/providers/2",
+			http.MethodPut,
+			"application/json",
+			"secret",
+			http.StatusNotFound,
+			"{\"position\": 3}",
+		},
+		{
+			"/admin/providers/1",
+			http.MethodPut,
+			"application/json",
+			"secret",
+			http.StatusBadRequest,
+			"{\"url\": \"http://localhost:8080/genderize\"}",
+		},
+		{
+			"/admin/providers/3",
+			http.MethodPut,
+			"application/json",
+			"secret",
+			http.StatusBadRequest,
+			"{\"enabled\": true}",
+		},
+		{
+			"/admin/providers/1",
+			http.MethodDelete,
+			"",
+			"secret",
+			http.StatusOK,
+			"",
+		},
+	}
+
+	for _, testCase := range testTable {
+		req, err := http.NewRequest(testCase.method, ts.URL+testCase.endpoint, strings.NewReader(testCase.body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", testCase.content)
+		if testCase.token != "" {
+			req.Header.Set("Authorization", "Bearer "+testCase.token)
+		}
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, testCase.code, resp.StatusCode, testCase.endpoint)
+	}
+}
+
+func TestAdminWithoutToken(t *testing.T) {
+	e := echo.New()
+
+	admin := e.Group("/admin", AdminAuth(""))
+	admin.GET("/providers", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	for _, token := range []string{"", "Bearer ", "Bearer secret"} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/admin/providers", nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, token)
+	}
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+// idempotentReplayedHeader marks the responses stored for an earlier request with the same Idempotency-Key
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// Idempotency makes requests with the Idempotency-Key header safe to retry: the response to the first request with a key
+// is stored for ttl and sent again to the requests with the same key, method, URL and body, other requests with the key get 422.
+// Responses with 5xx statuses are not stored, so that the request can be retried. The body is read to be hashed before
+// the handler, so bodies longer than maxBodySize bytes are rejected with 413. Zero ttl turns the checks off
+func Idempotency(srv domain.IdempotencyService, ttl time.Duration, maxBodySize int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(domain.IdempotencyKeyHeader)
+			if key == "" || ttl <= 0 {
+				return next(c)
+			}
+
+			if !isIdempotencyKeyCorrect(key) {
+				c.Response().WriteHeader(http.StatusBadRequest)
+				logger.Logger().Debugln(appErrors.ErrWrongIdempotencyKey)
+				return appErrors.ErrWrongIdempotencyKey
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxBodySize))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.Response().WriteHeader(http.StatusRequestEntityTooLarge)
+				logger.Logger().Debugln(err)
+				return err
+			}
+
+			if err != nil {
+				c.Response().WriteHeader(http.StatusBadRequest)
+				logger.Logger().Debugln(err)
+				return err
+			}
+
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			token, err := newClaimToken()
+			if err != nil {
+				c.Response().WriteHeader(http.StatusInternalServerError)
+				logger.Logger().Debugln(err)
+				return err
+			}
+
+			stored, err := srv.BeginRequest(c.Request().Context(), key, token, requestHash(c.Request(), body), ttl)
+
+			if errors.Is(err, appErrors.ErrIdempotencyKeyReused) {
+				c.Response().WriteHeader(http.StatusUnprocessableEntity)
+				logger.Logger().Debugln(err)
+				return err
+			}
+
+			if errors.Is(err, appErrors.ErrIdempotencyKeyInProgress) {
+				c.Response().WriteHeader(http.StatusConflict)
+				logger.Logger().Debugln(err)
+				return err
+			}
+
+			if err != nil {
+				c.Response().WriteHeader(http.StatusInternalServerError)
+				logger.Logger().Debugln(err)
+				return err
+			}
+
+			if stored != nil {
+				logger.Logger().Infoln("replayed the response for Idempotency-Key", key)
+				c.Response().Header().Set(idempotentReplayedHeader, "true")
+				if stored.ContentType != "" {
+					c.Response().Header().Set(echo.HeaderContentType, stored.ContentType)
+				}
+
+				c.Response().WriteHeader(stored.Status)
+				_, err = c.Response().Write(stored.Body)
+				return err
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			handlerErr := next(c)
+			c.Response().Writer = recorder.ResponseWriter
+
+			// the request may be cancelled by the client, but its outcome has to be stored anyway
+			ctx := context.Background()
+			if !c.Response().Committed || c.Response().Status >= http.StatusInternalServerError {
+				if err = srv.AbandonRequest(ctx, key, token); err != nil {
+					logger.Logger().Errorln("couldn't release Idempotency-Key", key, err)
+				}
+
+				return handlerErr
+			}
+
+			response := domain.IdempotentResponse{
+				Status:      c.Response().Status,
+				ContentType: c.Response().Header().Get(echo.HeaderContentType),
+				Body:        recorder.body.Bytes(),
+			}
+
+			if err = srv.CompleteRequest(ctx, key, token, response); err != nil {
+				logger.Logger().Errorln("couldn't store the response for Idempotency-Key", key, err)
+			}
+
+			return handlerErr
+		}
+	}
+}
+
+func isIdempotencyKeyCorrect(key string) bool {
+	if len(key) > 255 {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newClaimToken identifies the request which claimed a key, so that only it can store the response or release the key
+func newClaimToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// requestHash identifies the request made with an Idempotency-Key, the query is a part of it as it may change the outcome
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), r.Header.Get(echo.HeaderContentType)} {
+		h.Write([]byte(part))
+		h.Write([]byte{'\n'})
+	}
+
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the body of a response while it is written to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
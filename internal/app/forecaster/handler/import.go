@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+	mimeChecker "identity-forecaster/pkg/json-mime-checker"
+	"identity-forecaster/pkg/vcard"
+)
+
+type imports struct {
+	importer domain.Importer
+}
+
+func NewImports(importer domain.Importer) *imports {
+	return &imports{importer: importer}
+}
+
+// @Tags Persons
+// @Summary Запрос импорта сущностей из CSV
+// @Description Запрос для добавления сущностей из CSV файла. Первая строка файла - заголовок, столбцы с названиями полей (name, surname, patronymic, age, gender, nationality) сопоставляются автоматически, остальные - через параметр mapping. Для сущностей без age, gender и nationality данные запрашиваются из источников в фоне. В ответе - отчет о каждой строке: создана, пропущена как дубликат (по имени, фамилии и отчеству) или отклонена
+// @Accept text/csv
+// @Produce text/csv,json
+// @Param input body string true "CSV файл"
+// @Param delimiter query string false "разделитель столбцов (один символ или tab)" default(,)
+// @Param encoding query string false "кодировка файла" Enums(utf-8, windows-1251) default(utf-8)
+// @Param mapping query string false "сопоставление заголовков и полей через запятую" Example(Фамилия:surname,Имя:name,Отчество:patronymic)
+// @Param dry_run query bool false "только проверить файл, ничего не добавляя" default(false)
+// @Param report query string false "формат отчета" Enums(csv, json) default(csv)
+// @Success 200 {object} domain.ImportReport "отчет пробного импорта"
+// @Success 202 {object} domain.ImportReport "отчет импорта"
+// @Header 200,202 {integer} X-Import-Created "число созданных сущностей"
+// @Header 200,202 {integer} X-Import-Skipped "число пропущенных строк"
+// @Header 200,202 {integer} X-Import-Rejected "число отклоненных строк"
+// @Failure 400
+// @Failure 413
+// @Failure 500
+// @Router /import/csv [post]
+func (h *imports) ImportCSV(c echo.Context) error {
+	if !mimeChecker.IsContentTypeCorrect(c.Request(), "text/csv") {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrWrongContentType)
+		return appErrors.ErrWrongContentType
+	}
+
+	dryRun, reportFormat, err := importQueryParams(c)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	opts, err := domain.ParseCSVImportOptions(c.QueryParam("delimiter"), c.QueryParam("encoding"), c.QueryParam("mapping"), dryRun)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	report, err := h.importer.ImportCSV(c.Request().Context(), c.Request().Body, opts)
+
+	var parseErr *csv.ParseError
+	if errors.Is(err, appErrors.ErrWrongCSVMapping) || errors.As(err, &parseErr) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	return writeImportReport(c, report, err, reportFormat)
+}
+
+// @Tags Persons
+// @Summary Запрос импорта сущностей из vCard
+// @Description Запрос для добавления сущностей из файла с одной или несколькими vCard: имя, фамилия и отчество берутся из N, гендер - из GENDER, возраст и национальность - из X-PREDICTED-AGE и X-NATIONALITY. Для сущностей без этих данных они запрашиваются из источников в фоне. В ответе - отчет о каждой vCard (номер строки - строка BEGIN:VCARD)
+// @Accept text/vcard
+// @Produce text/csv,json
+// @Param input body string true "vCard файл"
+// @Param dry_run query bool false "только проверить файл, ничего не добавляя" default(false)
+// @Param report query string false "формат отчета" Enums(csv, json) default(csv)
+// @Success 200 {object} domain.ImportReport "отчет пробного импорта"
+// @Success 202 {object} domain.ImportReport "отчет импорта"
+// @Header 200,202 {integer} X-Import-Created "число созданных сущностей"
+// @Header 200,202 {integer} X-Import-Skipped "число пропущенных vCard"
+// @Header 200,202 {integer} X-Import-Rejected "число отклоненных vCard"
+// @Failure 400
+// @Failure 413
+// @Failure 500
+// @Router /import/vcard [post]
+func (h *imports) ImportVCard(c echo.Context) error {
+	if !mimeChecker.IsContentTypeCorrect(c.Request(), "text/vcard") {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrWrongContentType)
+		return appErrors.ErrWrongContentType
+	}
+
+	dryRun, reportFormat, err := importQueryParams(c)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	report, err := h.importer.ImportVCard(c.Request().Context(), c.Request().Body, dryRun)
+
+	var parseErr *vcard.ParseError
+	if errors.As(err, &parseErr) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	return writeImportReport(c, report, err, reportFormat)
+}
+
+// importQueryParams reads the params shared by all the imports
+func importQueryParams(c echo.Context) (bool, string, error) {
+	dryRun := false
+	if dryRunStr := c.QueryParam("dry_run"); dryRunStr != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			return false, "", err
+		}
+	}
+
+	reportFormat := c.QueryParam("report")
+	if reportFormat != "" && reportFormat != "csv" && reportFormat != "json" {
+		return false, "", appErrors.ErrIncorrectQueryParam
+	}
+
+	return dryRun, reportFormat, nil
+}
+
+// writeImportReport answers with the report of an import or with the status matching the error of the import
+func writeImportReport(c echo.Context, report domain.ImportReport, err error, reportFormat string) error {
+	if errors.Is(err, appErrors.ErrTooManyItems) {
+		c.Response().WriteHeader(http.StatusRequestEntityTooLarge)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	status := http.StatusAccepted
+	if report.DryRun {
+		status = http.StatusOK
+	}
+
+	c.Response().Header().Set("X-Import-Created", strconv.Itoa(report.Created))
+	c.Response().Header().Set("X-Import-Skipped", strconv.Itoa(report.Skipped))
+	c.Response().Header().Set("X-Import-Rejected", strconv.Itoa(report.Rejected))
+
+	logger.Logger().Infoln("successfully imported:", report.Created, report.Skipped, report.Rejected)
+
+	if reportFormat == "json" {
+		return c.JSON(status, report)
+	}
+
+	c.Response().Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.Response().Header().Set("Content-Disposition", "attachment; filename=\"import-report.csv\"")
+	c.Response().WriteHeader(status)
+
+	return report.WriteCSV(c.Response())
+}
@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+	jsonDuplicateChecker "identity-forecaster/pkg/json-duplicate-checker"
+	mimeChecker "identity-forecaster/pkg/json-mime-checker"
+	"identity-forecaster/pkg/validation"
+)
+
+// @Tags Persons
+// @Summary Запрос получения сущности
+// @Description Запрос для получения сущности по id. В ответе есть заголовки ETag и Last-Modified, с которыми в If-None-Match или If-Modified-Since
+// @Description повторный запрос вернет 304 без тела, если сущность не изменилась. С суффиксом .vcf сущность возвращается в виде vCard
+// @Produce json
+// @Param id path int true "id сущности" Example(1)
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
+// @Param If-Modified-Since header string false "Last-Modified из предыдущего ответа"
+// @Success 200 {object} domain.PersonFromDB
+// @Header 200 {string} ETag "строгий валидатор представления сущности"
+// @Header 200 {string} Last-Modified "время последнего изменения сущности"
+// @Success 307
+// @Header 307 {string} Location "адрес сущности, с которой была объединена запрошенная"
+// @Success 304
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /persons/{id} [get]
+func (h *forecaster) ReadPerson(c echo.Context) error {
+	if strings.HasSuffix(c.Param("id"), ".vcf") {
+		return h.ReadPersonVCard(c)
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	person, err := h.srv.ReadPersonByID(c.Request().Context(), id)
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNotFound)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	// the person was merged into another one
+	if person.ID != id {
+		return c.Redirect(http.StatusTemporaryRedirect, "/persons/"+strconv.Itoa(person.ID))
+	}
+
+	body, err := setValidators(c, person)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-cache")
+
+	if notModified(c.Request(), c.Response().Header().Get("ETag"), person.UpdatedAt) {
+		c.Response().WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	logger.Logger().Infoln("successfully sent a person")
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// @Tags Persons
+// @Summary Запрос частичного изменения сущности
+// @Description Запрос для изменения сущности по JSON Merge Patch (RFC 7396): отсутствующие поля не меняются, null очищает значение
+// @Description (имя и фамилию очистить нельзя). Поле version и If-Match проверяются так же, как в PUT /update/{id}
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param input body domain.PersonReplacement true "изменяемые поля сущности"
+// @Param id path int true "id сущности" Example(1)
+// @Param If-Match header string false "ETag сущности, которую изменяет клиент"
+// @Success 200 {object} domain.PersonFromDB
+// @Header 200 {string} ETag "строгий валидатор сущности после изменения"
+// @Failure 400
+// @Failure 404
+// @Failure 409
+// @Failure 412
+// @Failure 415
+// @Failure 428
+// @Failure 500
+// @Router /persons/{id} [patch]
+func (h *forecaster) PatchPerson(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if !mimeChecker.IsMergePatchContentTypeCorrect(c.Request()) {
+		c.Response().Header().Set("Accept-Patch", "application/merge-patch+json")
+		c.Response().WriteHeader(http.StatusUnsupportedMediaType)
+		logger.Logger().Debugln(appErrors.ErrWrongContentType)
+		return appErrors.ErrWrongContentType
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	err = jsonDuplicateChecker.CheckDuplicatesInJSON(json.NewDecoder(bytes.NewReader(body)), nil)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	patch, err := domain.ParsePersonPatch(body)
+	if err != nil {
+		return writeValidationError(c, err)
+	}
+
+	versionMember, _ := patch.Version()
+	version, err := h.expectedVersion(c.Request(), id, versionMember)
+	if err != nil {
+		return writePreconditionError(c, err)
+	}
+
+	person, err := h.srv.PatchPerson(c.Request().Context(), id, patch, version)
+	return writeModifiedPerson(c, person, err)
+}
+
+// @Tags Persons
+// @Summary Запрос замены сущности
+// @Description Запрос для полной замены сущности: все поля обязательны, пустая строка или нулевой возраст очищают значение.
+// @Description Поле version и If-Match проверяются так же, как в PUT /update/{id}
+// @Accept json
+// @Produce json
+// @Param input body domain.PersonReplacement true "новое описание сущности"
+// @Param id path int true "id сущности" Example(1)
+// @Param If-Match header string false "ETag сущности, которую изменяет клиент"
+// @Success 200 {object} domain.PersonFromDB
+// @Header 200 {string} ETag "строгий валидатор сущности после замены"
+// @Failure 400
+// @Failure 404
+// @Failure 409
+// @Failure 412
+// @Failure 428
+// @Failure 500
+// @Router /persons/{id} [put]
+func (h *forecaster) ReplacePerson(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if !mimeChecker.IsJSONContentTypeCorrect(c.Request()) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrWrongContentType)
+		return appErrors.ErrWrongContentType
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	err = jsonDuplicateChecker.CheckDuplicatesInJSON(json.NewDecoder(bytes.NewReader(body)), nil)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.DisallowUnknownFields()
+
+	var replacement domain.PersonReplacement
+
+	if err = d.Decode(&replacement); err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	version, err := h.expectedVersion(c.Request(), id, replacement.Version)
+	if err != nil {
+		return writePreconditionError(c, err)
+	}
+
+	person, err := h.srv.ReplacePerson(c.Request().Context(), id, replacement, version)
+	return writeModifiedPerson(c, person, err)
+}
+
+// writeModifiedPerson answers a request changing a person with the person after the change or the status of the error
+func writeModifiedPerson(c echo.Context, person domain.PersonFromDB, err error) error {
+	var violations validation.Errors
+	if errors.Is(err, appErrors.ErrRequiredFieldsNotProvided) || errors.Is(err, appErrors.ErrWrongAttributeType) ||
+		errors.Is(err, appErrors.ErrUnknownAttribute) || errors.As(err, &violations) {
+		return writeValidationError(c, err)
+	}
+
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNotFound)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if errors.Is(err, appErrors.ErrUniqueViolation) {
+		c.Response().WriteHeader(http.StatusConflict)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if errors.Is(err, appErrors.ErrVersionMismatch) {
+		return writePreconditionError(c, err)
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	body, err := setValidators(c, person)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	logger.Logger().Infoln("successfully changed a person")
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// setValidators sets ETag and Last-Modified of the person and returns the body they describe
+func setValidators(c echo.Context, person domain.PersonFromDB) ([]byte, error) {
+	body, err := json.Marshal(person)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Response().Header().Set("ETag", personETag(person.Version, body))
+	if !person.UpdatedAt.IsZero() {
+		c.Response().Header().Set("Last-Modified", person.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	return body, nil
+}
+
+// personETag identifies the exact bytes of a representation, so it changes whenever any of them does.
+// It starts with the version of the person, so that the tags of different versions never collide
+func personETag(version int, body []byte) string {
+	sum := sha256.Sum256(body)
+	return "\"" + strconv.Itoa(version) + "-" + hex.EncodeToString(sum[:16]) + "\""
+}
+
+// expectedVersion returns the version a change of the person is made to, which comes from If-Match or the version field
+// of the request, 0 means any version. If-Match * only requires the person to exist, otherwise the header is a list of tags
+// compared with the current ETag of the person by the strong comparison, so a weak tag never matches
+func (h *forecaster) expectedVersion(r *http.Request, id int, field *int) (int, error) {
+	if field != nil && *field < 1 {
+		return 0, appErrors.ErrVersionMismatch
+	}
+
+	match := strings.TrimSpace(r.Header.Get("If-Match"))
+	if match == "" && field == nil {
+		if h.requireVersion {
+			return 0, appErrors.ErrPreconditionRequired
+		}
+
+		return 0, nil
+	}
+
+	if match == "" {
+		return *field, nil
+	}
+
+	if match == "*" {
+		if field != nil {
+			return *field, nil
+		}
+
+		return 0, nil
+	}
+
+	current, err := h.srv.ReadPersonByID(r.Context(), id)
+
+	// a deleted or merged person has no representation a tag could match
+	if errors.Is(err, appErrors.ErrNoRowsFound) || err == nil && current.ID != id {
+		return 0, appErrors.ErrVersionMismatch
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(current)
+	if err != nil {
+		return 0, err
+	}
+
+	etag := personETag(current.Version, body)
+	for _, tag := range strings.Split(match, ",") {
+		if strings.TrimSpace(tag) != etag {
+			continue
+		}
+
+		if field != nil && *field != current.Version {
+			return 0, appErrors.ErrWrongPrecondition
+		}
+
+		// the change itself is made only to this version, so a concurrent change is still detected
+		return current.Version, nil
+	}
+
+	return 0, appErrors.ErrVersionMismatch
+}
+
+func writePreconditionError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, appErrors.ErrVersionMismatch):
+		c.Response().WriteHeader(http.StatusPreconditionFailed)
+	case errors.Is(err, appErrors.ErrPreconditionRequired):
+		c.Response().WriteHeader(http.StatusPreconditionRequired)
+	case errors.Is(err, appErrors.ErrWrongPrecondition):
+		c.Response().WriteHeader(http.StatusBadRequest)
+	default:
+		c.Response().WriteHeader(http.StatusInternalServerError)
+	}
+
+	logger.Logger().Debugln(err)
+	return err
+}
+
+// notModified evaluates If-None-Match and, only when it is absent, If-Modified-Since as RFC 9110 says.
+// If-None-Match uses the weak comparison, so W/ prefixes of the client's tags are ignored
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+
+	// the header has a precision of seconds
+	return !lastModified.Truncate(time.Second).After(since)
+}
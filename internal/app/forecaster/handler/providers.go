@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+	jsonDuplicateChecker "identity-forecaster/pkg/json-duplicate-checker"
+	mimeChecker "identity-forecaster/pkg/json-mime-checker"
+)
+
+type providers struct {
+	srv domain.ProvidersService
+}
+
+func NewProviders(srv domain.ProvidersService) *providers {
+	return &providers{srv: srv}
+}
+
+// AdminAuth protects the admin API with a static token, an empty token closes the API
+func AdminAuth(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if token == "" {
+				c.Response().WriteHeader(http.StatusForbidden)
+				logger.Logger().Debugln(appErrors.ErrAdminAPIDisabled)
+				return appErrors.ErrAdminAPIDisabled
+			}
+
+			provided := []byte(c.Request().Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare(provided, []byte("Bearer "+token)) != 1 {
+				c.Response().WriteHeader(http.StatusUnauthorized)
+				logger.Logger().Debugln(appErrors.ErrWrongAdminToken)
+				return appErrors.ErrWrongAdminToken
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// @Tags Admin
+// @Summary Запрос добавления источника данных
+// @Description Запрос для добавления нового источника данных, изменения применяются без перезапуска сервиса
+// @Accept json
+// @Produce json
+// @Param input body domain.StoredProvider true "описание источника"
+// @Success 201 {object} domain.StoredProvider
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 409
+// @Failure 500
+// @Router /admin/providers [post]
+func (h *providers) CreateProvider(c echo.Context) error {
+	if !mimeChecker.IsJSONContentTypeCorrect(c.Request()) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrWrongContentType)
+		return appErrors.ErrWrongContentType
+	}
+
+	bytesToCheck, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	err = jsonDuplicateChecker.CheckDuplicatesInJSON(json.NewDecoder(bytes.NewReader(bytesToCheck)), nil)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(bytesToCheck))
+	d.DisallowUnknownFields()
+
+	provider := domain.StoredProvider{Enabled: true}
+
+	if err = d.Decode(&provider); err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	provider, err = h.srv.CreateProvider(c.Request().Context(), provider)
+
+	if errors.Is(err, appErrors.ErrWrongProviderConfig) || errors.Is(err, appErrors.ErrUnknownProviderType) ||
+		errors.Is(err, appErrors.ErrUnknownAttribute) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if errors.Is(err, appErrors.ErrUniqueViolation) {
+		c.Response().WriteHeader(http.StatusConflict)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	logger.Logger().Infoln("successfully created provider", provider.Name)
+	return c.JSON(http.StatusCreated, provider)
+}
+
+// @Tags Admin
+// @Summary Запрос получения источников данных
+// @Description Запрос для получения всех источников данных в порядке их опроса
+// @Produce json
+// @Success 200 {array} domain.StoredProvider
+// @Success 204
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /admin/providers [get]
+func (h *providers) ReadProviders(c echo.Context) error {
+	stored, err := h.srv.ReadProviders(c.Request().Context())
+
+	if errors.Is(err, appErrors.ErrNoRowsFound) {
+		c.Response().WriteHeader(http.StatusNoContent)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, stored)
+}
+
+// @Tags Admin
+// @Summary Запрос обновления источника данных
+// @Description Запрос для включения/выключения источника, изменения его позиции, таймаута и параметров повторных запросов
+// @Accept json
+// @Produce json
+// @Param input body domain.ProviderUpdate true "изменяемые параметры источника"
+// @Param id path int true "id источника" Example(1)
+// @Success 200 {object} domain.StoredProvider
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /admin/providers/{id} [put]
+func (h *providers) UpdateProvider(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if !mimeChecker.IsJSONContentTypeCorrect(c.Request()) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(appErrors.ErrWrongContentType)
+		return appErrors.ErrWrongContentType
+	}
+
+	bytesToCheck, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	err = jsonDuplicateChecker.CheckDuplicatesInJSON(json.NewDecoder(bytes.NewReader(bytesToCheck)), nil)
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(bytesToCheck))
+	d.DisallowUnknownFields()
+
+	var update domain.ProviderUpdate
+
+	if err = d.Decode(&update); err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	provider, err := h.srv.UpdateProvider(c.Request().Context(), id, update)
+
+	if errors.Is(err, appErrors.ErrNoRowsFound) || errors.Is(err, appErrors.ErrNoRowsAffected) {
+		c.Response().WriteHeader(http.StatusNotFound)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if errors.Is(err, appErrors.ErrWrongProviderConfig) || errors.Is(err, appErrors.ErrUnknownProviderType) ||
+		errors.Is(err, appErrors.ErrUnknownAttribute) {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	logger.Logger().Infoln("successfully updated provider", provider.Name)
+	return c.JSON(http.StatusOK, provider)
+}
+
+// @Tags Admin
+// @Summary Запрос удаления источника данных
+// @Description Запрос для удаления источника данных (чтобы временно отключить источник, лучше использовать обновление с enabled = false)
+// @Param id path int true "id источника" Example(1)
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /admin/providers/{id} [delete]
+func (h *providers) DeleteProvider(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Response().WriteHeader(http.StatusBadRequest)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	err = h.srv.DeleteProvider(c.Request().Context(), id)
+	if errors.Is(err, appErrors.ErrNoRowsAffected) {
+		c.Response().WriteHeader(http.StatusNotFound)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	if err != nil {
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		logger.Logger().Debugln(err)
+		return err
+	}
+
+	c.Response().WriteHeader(http.StatusOK)
+	return nil
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+)
+
+type ClientConfig struct {
+	ProxyURL            string
+	CAFile              string
+	ClientCertFile      string
+	ClientKeyFile       string
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	KeepAlive           time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	UserAgent           string
+}
+
+// NewClientTransport builds the transport shared by all HTTP providers, without a proxy in cfg the usual
+// HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables are respected
+func NewClientTransport(cfg ClientConfig) (http.RoundTripper, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: proxy url: %v", appErrors.ErrWrongClientConfig, err)
+		}
+
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificates found in %s", appErrors.ErrWrongClientConfig, cfg.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: client certificate: %v", appErrors.ErrWrongClientConfig, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: cfg.KeepAlive}
+
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:               proxy,
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		ForceAttemptHTTP2:   true,
+	}
+
+	if cfg.UserAgent != "" {
+		transport = &userAgentTransport{next: transport, userAgent: cfg.UserAgent}
+	}
+
+	return transport, nil
+}
+
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+)
+
+func TestClientTransport(t *testing.T) {
+	var userAgent, rawQuery, name string
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		rawQuery = r.URL.RawQuery
+		name = r.URL.Query().Get("name")
+		w.Write([]byte("{\"gender\": \"male\"}"))
+	}))
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o600)
+	require.NoError(t, err)
+
+	cfg := domain.ProviderConfig{Name: "genderize", URL: ts.URL + "/?apikey=a%26b", Input: "name",
+		Fields: map[string]string{domain.AttributeGender: "gender"}}
+
+	withoutCA, err := NewClientTransport(ClientConfig{})
+	require.NoError(t, err)
+
+	p, err := NewHTTP(cfg, &http.Client{Transport: withoutCA}, 1, 1)
+	require.NoError(t, err)
+
+	_, err = p.Fetch(context.Background(), domain.Person{Name: "Дмитрий Иван"})
+	require.Error(t, err)
+
+	withCA, err := NewClientTransport(ClientConfig{CAFile: caFile, UserAgent: "identity-forecaster/test"})
+	require.NoError(t, err)
+
+	p, err = NewHTTP(cfg, &http.Client{Transport: withCA}, 1, 1)
+	require.NoError(t, err)
+
+	data, err := p.Fetch(context.Background(), domain.Person{Name: "Дмитрий Иван"})
+	require.NoError(t, err)
+	require.Equal(t, "male", data.Gender)
+	require.Equal(t, "identity-forecaster/test", userAgent)
+	require.Equal(t, "Дмитрий Иван", name)
+	require.True(t, strings.Contains(rawQuery, "apikey=a%26b"), rawQuery)
+	require.True(t, strings.Contains(rawQuery, "name=%D0%94%D0%BC%D0%B8%D1%82%D1%80%D0%B8%D0%B9+%D0%98%D0%B2%D0%B0%D0%BD"), rawQuery)
+
+	_, err = NewClientTransport(ClientConfig{ClientCertFile: filepath.Join(t.TempDir(), "missing.pem")})
+	require.ErrorIs(t, err, appErrors.ErrWrongClientConfig)
+
+	_, err = NewClientTransport(ClientConfig{CAFile: caFile + ".missing"})
+	require.Error(t, err)
+}
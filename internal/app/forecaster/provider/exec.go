@@ -0,0 +1,373 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+const (
+	defaultExecTimeoutMilliseconds = 5000
+	defaultExecConcurrency         = 1
+	maxExecResponseSize            = 1024 * 1024
+	// maxExecTimeoutsInRow timeouts without a single line from the plugin in between mean it is stuck and has to be restarted
+	maxExecTimeoutsInRow = 3
+)
+
+var _ domain.Provider = (*execProvider)(nil)
+
+type execRequest struct {
+	ID     uint64        `json:"id"`
+	Person domain.Person `json:"person"`
+}
+
+type execResponse struct {
+	ID    uint64             `json:"id"`
+	Data  domain.DataFromAPI `json:"data"`
+	Error string             `json:"error,omitempty"`
+	raw   []byte
+}
+
+// execProvider keeps a single long-lived process of the plugin, requests and responses are matched by id,
+// so up to Concurrency requests may be in flight at once
+type execProvider struct {
+	cfg                        domain.ProviderConfig
+	retriesAmount              uint
+	millisecondsBetweenRetries uint
+	timeout                    time.Duration
+	semaphore                  chan struct{}
+	lastID                     atomic.Uint64
+	mu                         sync.Mutex
+	proc                       *execProcess
+	closed                     bool
+}
+
+type execProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	writeMu   sync.Mutex
+	pendingMu sync.Mutex
+	pending   map[uint64]chan execResponse
+	// stderrRead is closed once stderr is drained, the process may be waited for only after that
+	stderrRead chan struct{}
+	// timeouts counts the requests timed out since the plugin wrote anything
+	timeouts atomic.Int32
+	done     chan struct{}
+}
+
+func NewExec(cfg domain.ProviderConfig, retriesAmount uint, millisecondsBetweenRetries uint) (*execProvider, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("%w: command of provider %s is empty", appErrors.ErrWrongProviderConfig, cfg.Name)
+	}
+
+	if cfg.TimeoutMilliseconds == 0 {
+		cfg.TimeoutMilliseconds = defaultExecTimeoutMilliseconds
+	}
+
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = defaultExecConcurrency
+	}
+
+	return &execProvider{
+		cfg:                        cfg,
+		retriesAmount:              retriesAmount,
+		millisecondsBetweenRetries: millisecondsBetweenRetries,
+		timeout:                    time.Duration(cfg.TimeoutMilliseconds) * time.Millisecond,
+		semaphore:                  make(chan struct{}, cfg.Concurrency),
+	}, nil
+}
+
+func (p *execProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *execProvider) Fetch(ctx context.Context, person domain.Person) (domain.DataFromAPI, error) {
+	select {
+	case p.semaphore <- struct{}{}:
+		defer func() { <-p.semaphore }()
+	case <-ctx.Done():
+		return domain.DataFromAPI{}, ctx.Err()
+	}
+
+	var data domain.DataFromAPI
+
+	err := retry.Do(func() error {
+		logger.Logger().Infoln("attempt to get info from plugin", p.cfg.Name, "...")
+		proc, err := p.process()
+		if err != nil {
+			logger.Logger().Infoln(err)
+			return err
+		}
+
+		data, err = p.call(ctx, proc, person)
+		return err
+	}, retry.Attempts(p.retriesAmount), retry.Delay(time.Duration(p.millisecondsBetweenRetries)*time.Millisecond),
+		retry.Context(ctx), retry.LastErrorOnly(true))
+
+	if err != nil {
+		logger.Logger().Debugln(err)
+		return domain.DataFromAPI{}, err
+	}
+
+	logger.Logger().Infoln("successfully got info from", p.cfg.Name)
+	return data, nil
+}
+
+func (p *execProvider) call(ctx context.Context, proc *execProcess, person domain.Person) (domain.DataFromAPI, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	id := p.lastID.Add(1)
+	responseChan := proc.register(id)
+	defer proc.unregister(id)
+
+	if err := proc.send(ctx, execRequest{ID: id, Person: person}); err != nil {
+		p.timedOut(proc, err)
+		return domain.DataFromAPI{}, err
+	}
+
+	select {
+	case resp := <-responseChan:
+		if resp.Error != "" {
+			return domain.DataFromAPI{}, retry.Unrecoverable(fmt.Errorf("%w: %s", appErrors.ErrPluginFailed, resp.Error))
+		}
+
+		resp.Data.RawResponses = []domain.ProviderResponse{{Provider: p.cfg.Name, Body: resp.raw, ReceivedAt: time.Now()}}
+		return resp.Data, nil
+	case <-proc.done:
+		return domain.DataFromAPI{}, appErrors.ErrPluginExited
+	case <-ctx.Done():
+		p.timedOut(proc, ctx.Err())
+		return domain.DataFromAPI{}, ctx.Err()
+	}
+}
+
+// timedOut gives up only the caller's request, the shared process is killed, and so restarted on the next call,
+// only when it has not written anything during several timeouts in a row
+func (p *execProvider) timedOut(proc *execProcess, err error) {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	if proc.timeouts.Add(1) < maxExecTimeoutsInRow {
+		logger.Logger().Infoln("plugin", p.cfg.Name, "timed out")
+		return
+	}
+
+	logger.Logger().Infoln("plugin", p.cfg.Name, "timed out", maxExecTimeoutsInRow, "times in a row, killing it")
+	proc.kill()
+}
+
+// process returns the running plugin process, starting a new one if there is none or the previous one has exited
+func (p *execProvider) process() (*execProcess, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, retry.Unrecoverable(appErrors.ErrPluginExited)
+	}
+
+	if p.proc != nil && !p.proc.exited() {
+		return p.proc, nil
+	}
+
+	if p.proc != nil {
+		logger.Logger().Infoln("restarting plugin", p.cfg.Name)
+	}
+
+	proc, err := startExecProcess(p.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p.proc = proc
+	return proc, nil
+}
+
+func (p *execProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	if p.proc == nil || p.proc.exited() {
+		return nil
+	}
+
+	// closing stdin lets a well-behaved plugin finish on its own
+	err := p.proc.stdin.Close()
+
+	select {
+	case <-p.proc.done:
+	case <-time.After(p.timeout):
+		p.proc.kill()
+		<-p.proc.done
+	}
+
+	return err
+}
+
+func startExecProcess(cfg domain.ProviderConfig) (*execProcess, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	proc := &execProcess{cmd: cmd, stdin: stdin, pending: make(map[uint64]chan execResponse), stderrRead: make(chan struct{}),
+		done: make(chan struct{})}
+
+	go func() {
+		defer close(proc.stderrRead)
+
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Logger().Debugln("plugin", cfg.Name, "stderr:", scanner.Text())
+		}
+	}()
+
+	go proc.readResponses(cfg.Name, stdout)
+
+	return proc, nil
+}
+
+func (proc *execProcess) readResponses(name string, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxExecResponseSize)
+
+	for scanner.Scan() {
+		proc.timeouts.Store(0)
+
+		var resp execResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			logger.Logger().Infoln("plugin", name, "sent malformed response:", err)
+			continue
+		}
+
+		resp.raw = bytes.Clone(scanner.Bytes())
+
+		// only the first response to a request is delivered, a plugin repeating an id must not block the reading
+		proc.pendingMu.Lock()
+		responseChan, ok := proc.pending[resp.ID]
+		delete(proc.pending, resp.ID)
+		proc.pendingMu.Unlock()
+
+		if !ok {
+			logger.Logger().Infoln("plugin", name, "sent a response to an unknown request", resp.ID)
+			continue
+		}
+
+		select {
+		case responseChan <- resp:
+		default:
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		logger.Logger().Infoln("plugin", name, "output could not be read:", err)
+		proc.kill()
+	}
+
+	// os/exec requires all the reads from the pipes to finish before Wait
+	<-proc.stderrRead
+
+	err := proc.cmd.Wait()
+	logger.Logger().Infoln("plugin", name, "exited:", err)
+	close(proc.done)
+}
+
+func (proc *execProcess) register(id uint64) chan execResponse {
+	responseChan := make(chan execResponse, 1)
+
+	proc.pendingMu.Lock()
+	proc.pending[id] = responseChan
+	proc.pendingMu.Unlock()
+
+	return responseChan
+}
+
+func (proc *execProcess) unregister(id uint64) {
+	proc.pendingMu.Lock()
+	delete(proc.pending, id)
+	proc.pendingMu.Unlock()
+}
+
+// send writes the request in the background, so that a plugin which stopped reading its stdin blocks nobody past ctx
+func (proc *execProcess) send(ctx context.Context, req execRequest) error {
+	line, err := json.Marshal(req)
+	if err != nil {
+		return retry.Unrecoverable(err)
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		proc.writeMu.Lock()
+		defer proc.writeMu.Unlock()
+
+		// the caller may have given up while an earlier write held the lock
+		if err := ctx.Err(); err != nil {
+			written <- err
+			return
+		}
+
+		_, err := proc.stdin.Write(append(line, '\n'))
+		written <- err
+	}()
+
+	select {
+	case err = <-written:
+		if err != nil && ctx.Err() == nil {
+			return errors.Join(appErrors.ErrPluginExited, err)
+		}
+
+		return err
+	case <-proc.done:
+		return appErrors.ErrPluginExited
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (proc *execProcess) exited() bool {
+	select {
+	case <-proc.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (proc *execProcess) kill() {
+	if err := proc.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		logger.Logger().Infoln(err)
+	}
+}
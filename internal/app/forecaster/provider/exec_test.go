@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"identity-forecaster/internal/app/forecaster/domain"
+)
+
+// TestPluginProcess is not a real test, it is started by the tests below as the plugin executable
+func TestPluginProcess(t *testing.T) {
+	mode := os.Getenv("FORECASTER_TEST_PLUGIN")
+	if mode == "" {
+		return
+	}
+
+	// a deaf plugin never reads its stdin, so the writes to it block once the pipe is full
+	if mode == "deaf" {
+		select {}
+	}
+
+	var stdoutMu sync.Mutex
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req execRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+
+		stdoutMu.Lock()
+		switch {
+		case mode == "crash" && req.Person.Name == "Crash":
+			os.Exit(1)
+		case mode == "crash" && req.Person.Name == "Hang":
+			select {}
+		case req.Person.Name == "Slow":
+			go func(id uint64) {
+				time.Sleep(700 * time.Millisecond)
+
+				stdoutMu.Lock()
+				defer stdoutMu.Unlock()
+				fmt.Printf("{\"id\": %d, \"data\": {\"age\": 1}}\n", id)
+			}(req.ID)
+		case req.Person.Name == "Repeat":
+			for i := 0; i < 3; i++ {
+				fmt.Printf("{\"id\": %d, \"data\": {\"age\": %d}}\n", req.ID, i+1)
+			}
+		case req.Person.Name == "Error":
+			fmt.Printf("{\"id\": %d, \"error\": \"unknown name\"}\n", req.ID)
+		default:
+			fmt.Printf("{\"id\": %d, \"data\": {\"age\": %d, \"gender\": \"male\", \"probabilities\": {\"gender\": 0.9}}}\n", req.ID, len(req.Person.Name))
+		}
+		stdoutMu.Unlock()
+	}
+
+	os.Exit(0)
+}
+
+func testPlugin(t *testing.T, mode string, concurrency uint) *execProvider {
+	t.Setenv("FORECASTER_TEST_PLUGIN", mode)
+
+	p, err := NewExec(domain.ProviderConfig{
+		Name:                "test-plugin",
+		Type:                domain.ProviderTypeExec,
+		Command:             os.Args[0],
+		Args:                []string{"-test.run=TestPluginProcess"},
+		TimeoutMilliseconds: 500,
+		Concurrency:         concurrency,
+	}, 3, 1)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, p.Close())
+	})
+
+	return p
+}
+
+func TestExecProvider(t *testing.T) {
+	p := testPlugin(t, "ok", 4)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"Ivan", "Dmitriy", "Anna", "Pyotr", "Ilya", "Olga"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			data, err := p.Fetch(context.Background(), domain.Person{Name: name, Surname: "Smirnov"})
+			assert.NoError(t, err)
+			assert.Equal(t, len(name), data.Age)
+			assert.Equal(t, "male", data.Gender)
+			assert.Equal(t, 0.9, data.Probabilities[domain.AttributeGender])
+		}(name)
+	}
+	wg.Wait()
+
+	_, err := p.Fetch(context.Background(), domain.Person{Name: "Error", Surname: "Smirnov"})
+	require.Error(t, err)
+}
+
+func TestExecProviderRestart(t *testing.T) {
+	p := testPlugin(t, "crash", 1)
+
+	_, err := p.Fetch(context.Background(), domain.Person{Name: "Crash", Surname: "Smirnov"})
+	require.Error(t, err)
+
+	data, err := p.Fetch(context.Background(), domain.Person{Name: "Ivan", Surname: "Smirnov"})
+	require.NoError(t, err)
+	require.Equal(t, 4, data.Age)
+
+	_, err = p.Fetch(context.Background(), domain.Person{Name: "Hang", Surname: "Smirnov"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	data, err = p.Fetch(context.Background(), domain.Person{Name: "Olga", Surname: "Smirnov"})
+	require.NoError(t, err)
+	require.Equal(t, 4, data.Age)
+}
+
+func TestExecProviderRepeatedResponse(t *testing.T) {
+	p := testPlugin(t, "ok", 1)
+
+	data, err := p.Fetch(context.Background(), domain.Person{Name: "Repeat", Surname: "Smirnov"})
+	require.NoError(t, err)
+	require.Equal(t, 1, data.Age)
+
+	// the repeated responses are dropped and do not block the following ones
+	for _, name := range []string{"Ivan", "Olga", "Dmitriy"} {
+		data, err = p.Fetch(context.Background(), domain.Person{Name: name, Surname: "Smirnov"})
+		require.NoError(t, err)
+		require.Equal(t, len(name), data.Age)
+	}
+}
+
+func TestExecProviderTimeoutKeepsProcess(t *testing.T) {
+	p := testPlugin(t, "ok", 2)
+
+	_, err := p.Fetch(context.Background(), domain.Person{Name: "Ivan", Surname: "Smirnov"})
+	require.NoError(t, err)
+	proc := p.proc
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		_, err := p.Fetch(context.Background(), domain.Person{Name: "Slow", Surname: "Smirnov"})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}()
+
+	// the other requests in flight are answered while the slow one times out
+	for i := 0; i < 10; i++ {
+		data, err := p.Fetch(context.Background(), domain.Person{Name: "Olga", Surname: "Smirnov"})
+		require.NoError(t, err)
+		require.Equal(t, 4, data.Age)
+
+		time.Sleep(100 * time.Millisecond)
+	}
+	wg.Wait()
+
+	// the plugin kept answering, so it is not restarted
+	require.Same(t, proc, p.proc)
+	require.False(t, proc.exited())
+}
+
+func TestExecProviderDeafPlugin(t *testing.T) {
+	p := testPlugin(t, "deaf", 1)
+
+	// the request is larger than the pipe buffer, so writing it blocks until the plugin is killed
+	start := time.Now()
+	_, err := p.Fetch(context.Background(), domain.Person{Name: strings.Repeat("a", 1<<20), Surname: "Smirnov"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	select {
+	case <-p.proc.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stuck plugin is not killed")
+	}
+}
@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+	jsonErrors "identity-forecaster/json-errors"
+	jsonPathExtractor "identity-forecaster/pkg/json-path-extractor"
+)
+
+var _ domain.Provider = (*httpProvider)(nil)
+
+var defaultRecordHeaders = []string{"Content-Type", "Date", "X-Rate-Limit-Limit", "X-Rate-Limit-Remaining", "X-Rate-Limit-Reset"}
+
+type httpProvider struct {
+	cfg                        domain.ProviderConfig
+	client                     *http.Client
+	retriesAmount              uint
+	millisecondsBetweenRetries uint
+}
+
+func NewHTTP(cfg domain.ProviderConfig, client *http.Client, retriesAmount uint, millisecondsBetweenRetries uint) (*httpProvider, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%w: url of provider %s is empty", appErrors.ErrWrongProviderConfig, cfg.Name)
+	}
+
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("%w: %v", appErrors.ErrWrongProviderConfig, err)
+	}
+
+	if _, ok := (domain.Person{}).FieldByName(cfg.Input); !ok {
+		return nil, fmt.Errorf("%w: unknown input field %s of provider %s", appErrors.ErrWrongProviderConfig, cfg.Input, cfg.Name)
+	}
+
+	if cfg.QueryParam == "" {
+		cfg.QueryParam = cfg.Input
+	}
+
+	for attribute := range cfg.Fields {
+		if !domain.IsKnownAttribute(attribute) {
+			return nil, fmt.Errorf("%w: %s", appErrors.ErrUnknownAttribute, attribute)
+		}
+	}
+
+	for attribute := range cfg.Probabilities {
+		if !domain.IsKnownAttribute(attribute) {
+			return nil, fmt.Errorf("%w: %s", appErrors.ErrUnknownAttribute, attribute)
+		}
+	}
+
+	return &httpProvider{cfg: cfg, client: client, retriesAmount: retriesAmount, millisecondsBetweenRetries: millisecondsBetweenRetries}, nil
+}
+
+func (p *httpProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *httpProvider) Fetch(ctx context.Context, person domain.Person) (domain.DataFromAPI, error) {
+	input, _ := person.FieldByName(p.cfg.Input)
+
+	requestURL, err := p.buildURL(input)
+	if err != nil {
+		return domain.DataFromAPI{}, err
+	}
+
+	ctx = withRedactedParams(ctx, p.staticParams())
+
+	var body interface{}
+	var raw domain.ProviderResponse
+
+	err = retry.Do(func() error {
+		logger.Logger().Infoln("attempt to get info from external api", p.cfg.Name, "...")
+		attemptCtx := ctx
+		if p.cfg.TimeoutMilliseconds != 0 {
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(p.cfg.TimeoutMilliseconds)*time.Millisecond)
+			defer cancel()
+		}
+
+		req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, requestURL, nil)
+		if err != nil {
+			return retry.Unrecoverable(err)
+		}
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			logger.Logger().Infoln(err)
+			return err
+		}
+		defer resp.Body.Close()
+
+		logger.Logger().Infoln(resp.StatusCode)
+		if !(resp.StatusCode > 199 && resp.StatusCode < 400) {
+			return appErrors.ErrWrongStatusCode
+		}
+
+		content, err := io.ReadAll(resp.Body)
+		if err != nil {
+			logger.Logger().Infoln(err)
+			return err
+		}
+
+		err = json.Unmarshal(content, &body)
+		if err != nil {
+			logger.Logger().Infoln(err)
+			return err
+		}
+
+		raw = domain.ProviderResponse{Provider: p.cfg.Name, URL: redactedURL(req).String(), StatusCode: resp.StatusCode,
+			Headers: p.headersOfInterest(resp.Header), Body: content, ReceivedAt: time.Now()}
+
+		return nil
+	}, retry.Attempts(p.retriesAmount), retry.Delay(time.Duration(p.millisecondsBetweenRetries)*time.Millisecond), retry.Context(ctx))
+
+	if err != nil {
+		logger.Logger().Debugln(err)
+		return domain.DataFromAPI{}, err
+	}
+
+	data, err := p.mapResponse(body)
+	if err != nil {
+		return domain.DataFromAPI{}, err
+	}
+
+	data.RawResponses = []domain.ProviderResponse{raw}
+
+	logger.Logger().Infoln("successfully got info from", p.cfg.Name)
+	return data, nil
+}
+
+func (p *httpProvider) headersOfInterest(header http.Header) map[string]string {
+	names := p.cfg.RecordHeaders
+	if len(names) == 0 {
+		names = defaultRecordHeaders
+	}
+
+	headers := make(map[string]string, len(names))
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			headers[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+
+	return headers
+}
+
+// staticParams are the names of the params set from the config, they may hold credentials
+func (p *httpProvider) staticParams() []string {
+	names := make([]string, 0, len(p.cfg.Params))
+	for param := range p.cfg.Params {
+		if param != p.cfg.QueryParam {
+			names = append(names, param)
+		}
+	}
+
+	return names
+}
+
+func (p *httpProvider) buildURL(input string) (string, error) {
+	u, err := url.Parse(p.cfg.URL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for param, value := range p.cfg.Params {
+		query.Set(param, value)
+	}
+
+	query.Set(p.cfg.QueryParam, input)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func (p *httpProvider) mapResponse(body interface{}) (domain.DataFromAPI, error) {
+	var data domain.DataFromAPI
+
+	for attribute, path := range p.cfg.Fields {
+		value, err := extract(body, path)
+		if err != nil {
+			return domain.DataFromAPI{}, fmt.Errorf("provider %s, field %s: %w", p.cfg.Name, attribute, err)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		if err = data.SetAttribute(attribute, value); err != nil {
+			return domain.DataFromAPI{}, fmt.Errorf("provider %s, field %s: %w", p.cfg.Name, attribute, err)
+		}
+	}
+
+	for attribute, path := range p.cfg.Probabilities {
+		value, err := extract(body, path)
+		if err != nil {
+			return domain.DataFromAPI{}, fmt.Errorf("provider %s, probability of %s: %w", p.cfg.Name, attribute, err)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		if err = data.SetProbability(attribute, value); err != nil {
+			return domain.DataFromAPI{}, fmt.Errorf("provider %s, probability of %s: %w", p.cfg.Name, attribute, err)
+		}
+	}
+
+	return data, nil
+}
+
+// extract treats paths that lead nowhere (e.g. an empty country list of nationalize) as missing values,
+// a malformed path is still an error
+func extract(body interface{}, path string) (interface{}, error) {
+	value, err := jsonPathExtractor.Extract(body, path)
+	if errors.Is(err, jsonErrors.ErrPathNotFound) || errors.Is(err, jsonErrors.ErrIndexOutOfRange) {
+		return nil, nil
+	}
+
+	return value, err
+}
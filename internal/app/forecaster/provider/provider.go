@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+// New builds a provider described by cfg, retries set in cfg take precedence over the ones passed as arguments
+func New(cfg domain.ProviderConfig, client *http.Client, retriesAmount uint, millisecondsBetweenRetries uint) (domain.Provider, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("%w: name of provider is empty", appErrors.ErrWrongProviderConfig)
+	}
+
+	if cfg.Retries != 0 {
+		retriesAmount = cfg.Retries
+	}
+
+	if cfg.RetryIntervalMilliseconds != 0 {
+		millisecondsBetweenRetries = cfg.RetryIntervalMilliseconds
+	}
+
+	switch cfg.Type {
+	case domain.ProviderTypeHTTP, "":
+		p, err := NewHTTP(cfg, client, retriesAmount, millisecondsBetweenRetries)
+		if err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	case domain.ProviderTypeExec:
+		p, err := NewExec(cfg, retriesAmount, millisecondsBetweenRetries)
+		if err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrUnknownProviderType, cfg.Type)
+	}
+}
+
+func Validate(cfg domain.ProviderConfig) error {
+	p, err := New(cfg, http.DefaultClient, 1, 0)
+	if err != nil {
+		return err
+	}
+
+	CloseAll([]domain.Provider{p})
+	return nil
+}
+
+func FromConfigs(cfgs []domain.ProviderConfig, client *http.Client, retriesAmount uint, millisecondsBetweenRetries uint) ([]domain.Provider, error) {
+	providers := make([]domain.Provider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		p, err := New(cfg, client, retriesAmount, millisecondsBetweenRetries)
+		if err != nil {
+			CloseAll(providers)
+			return nil, err
+		}
+
+		providers = append(providers, p)
+	}
+
+	return providers, nil
+}
+
+// FetchAll asks providers one by one and merges their answers, the first failed provider stops the process
+func FetchAll(ctx context.Context, providers []domain.Provider, person domain.Person) (domain.DataFromAPI, error) {
+	var resultData domain.DataFromAPI
+
+	for _, p := range providers {
+		data, err := p.Fetch(ctx, person)
+		if err != nil {
+			return domain.DataFromAPI{}, err
+		}
+
+		resultData.Merge(data)
+	}
+
+	return resultData, nil
+}
+
+// CloseAll releases resources held by providers, e.g. stops plugin processes
+func CloseAll(providers []domain.Provider) {
+	for _, p := range providers {
+		closer, ok := p.(io.Closer)
+		if !ok {
+			continue
+		}
+
+		if err := closer.Close(); err != nil {
+			logger.Logger().Infoln(err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+const (
+	ModeLive   = "live"
+	ModeRecord = "record"
+	ModeReplay = "replay"
+
+	redactedValue = "REDACTED"
+)
+
+type redactedParamsKey struct{}
+
+// withRedactedParams marks the query params of the requests made with ctx whose values, e.g. API keys,
+// must not get into cassettes and stored responses
+func withRedactedParams(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, redactedParamsKey{}, names)
+}
+
+// redactedURL is the URL of req with the values of the marked params replaced and the query params sorted
+func redactedURL(req *http.Request) *url.URL {
+	u := *req.URL
+	query := u.Query()
+
+	names, _ := req.Context().Value(redactedParamsKey{}).([]string)
+	for _, name := range names {
+		if query.Has(name) {
+			query.Set(name, redactedValue)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+type cassette struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// NewTransport wraps next according to the mode: live passes requests through, record saves every response
+// to dir and replay answers only from the responses saved before, without touching the network
+func NewTransport(mode string, dir string, next http.RoundTripper) (http.RoundTripper, error) {
+	switch mode {
+	case ModeLive, "":
+		return next, nil
+	case ModeRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+
+		return &recordingTransport{next: next, dir: dir}, nil
+	case ModeReplay:
+		return &replayingTransport{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrUnknownProviderMode, mode)
+	}
+}
+
+type recordingTransport struct {
+	next http.RoundTripper
+	dir  string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c := cassette{Method: req.Method, URL: redactedURL(req).String(), StatusCode: resp.StatusCode, Header: resp.Header, Body: string(body)}
+
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	path := cassettePath(t.dir, req)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	if err = os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+
+	logger.Logger().Debugln("recorded response of", c.URL, "to", path)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+type replayingTransport struct {
+	dir string
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	path := cassettePath(t.dir, req)
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", appErrors.ErrNoRecordedResponse, req.Method, redactedURL(req).String())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var c cassette
+	if err = json.Unmarshal(content, &c); err != nil {
+		return nil, err
+	}
+
+	header := c.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}, nil
+}
+
+// cassettePath keys responses by method and redacted URL, query params are sorted, so their order does not matter,
+// and a cassette recorded with one API key is replayed with any other
+func cassettePath(dir string, req *http.Request) string {
+	u := redactedURL(req)
+
+	hash := sha256.Sum256([]byte(req.Method + " " + u.String()))
+
+	host := strings.NewReplacer(":", "_", "/", "_").Replace(u.Host)
+	return filepath.Join(dir, host, strings.ToLower(req.Method)+"-"+hex.EncodeToString(hash[:8])+".json")
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\"name\": \"" + r.URL.Query().Get("name") + "\", \"age\": 42}"))
+	}))
+
+	cfg := domain.ProviderConfig{Name: "agify", URL: ts.URL + "/agify", Input: "name", Params: map[string]string{"apikey": "secret"},
+		Fields: map[string]string{domain.AttributeAge: "age"}}
+
+	recording, err := NewTransport(ModeRecord, dir, http.DefaultTransport)
+	require.NoError(t, err)
+
+	recorder, err := NewHTTP(cfg, &http.Client{Transport: recording}, 1, 1)
+	require.NoError(t, err)
+
+	data, err := recorder.Fetch(context.Background(), domain.Person{Name: "Дмитрий Иван", Surname: "Smirnov"})
+	require.NoError(t, err)
+	require.Equal(t, 42, data.Age)
+
+	ts.Close()
+
+	// the cassettes are meant to be checked in, so the API key is not written to them
+	cassettes, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, cassettes, 1)
+
+	content, err := os.ReadFile(cassettes[0])
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "secret")
+	require.Contains(t, string(content), "apikey=REDACTED")
+
+	cfg.Params = map[string]string{"apikey": "another"}
+
+	replaying, err := NewTransport(ModeReplay, dir, nil)
+	require.NoError(t, err)
+
+	replayer, err := NewHTTP(cfg, &http.Client{Transport: replaying}, 1, 1)
+	require.NoError(t, err)
+
+	data, err = replayer.Fetch(context.Background(), domain.Person{Name: "Дмитрий Иван", Surname: "Smirnov"})
+	require.NoError(t, err)
+	require.Equal(t, 42, data.Age)
+	require.Len(t, data.RawResponses, 1)
+	require.Equal(t, "agify", data.RawResponses[0].Provider)
+	require.Contains(t, data.RawResponses[0].URL, "apikey=REDACTED")
+	require.Equal(t, http.StatusOK, data.RawResponses[0].StatusCode)
+	require.Equal(t, "application/json", data.RawResponses[0].Headers["Content-Type"])
+	require.JSONEq(t, "{\"name\": \"Дмитрий Иван\", \"age\": 42}", string(data.RawResponses[0].Body))
+
+	_, err = replayer.Fetch(context.Background(), domain.Person{Name: "Pyotr", Surname: "Smirnov"})
+	require.ErrorIs(t, err, appErrors.ErrNoRecordedResponse)
+
+	_, err = NewTransport("proxy", dir, nil)
+	require.ErrorIs(t, err, appErrors.ErrUnknownProviderMode)
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+var (
+	_ domain.ProviderRegistry = (*registry)(nil)
+	_ domain.ProviderRegistry = static(nil)
+)
+
+type registryEntry struct {
+	cfg      domain.ProviderConfig
+	provider domain.Provider
+}
+
+// registry keeps the enabled providers from the database, every instance of the service reloads them
+// periodically, so changes made through the admin API reach all of them without a restart
+type registry struct {
+	repo                       domain.ProvidersRepository
+	client                     *http.Client
+	retriesAmount              uint
+	millisecondsBetweenRetries uint
+	current                    atomic.Pointer[[]domain.Provider]
+	mu                         sync.Mutex
+	entries                    map[string]registryEntry
+}
+
+func NewRegistry(repo domain.ProvidersRepository, client *http.Client, retriesAmount uint, millisecondsBetweenRetries uint) *registry {
+	r := &registry{repo: repo, client: client, retriesAmount: retriesAmount, millisecondsBetweenRetries: millisecondsBetweenRetries,
+		entries: make(map[string]registryEntry)}
+	r.current.Store(&[]domain.Provider{})
+
+	return r
+}
+
+func (r *registry) Providers() []domain.Provider {
+	return *r.current.Load()
+}
+
+// Reload rebuilds only the providers whose config has changed, the others (e.g. running plugins) are kept as is
+func (r *registry) Reload(ctx context.Context) error {
+	stored, err := r.repo.ReadProviders(ctx)
+	if err != nil && !errors.Is(err, appErrors.ErrNoRowsFound) {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make(map[string]registryEntry, len(stored))
+	providers := make([]domain.Provider, 0, len(stored))
+
+	for _, s := range stored {
+		if !s.Enabled {
+			continue
+		}
+
+		entry, ok := r.entries[s.Name]
+		if !ok || !reflect.DeepEqual(entry.cfg, s.ProviderConfig) {
+			p, err := New(s.ProviderConfig, r.client, r.retriesAmount, r.millisecondsBetweenRetries)
+			if err != nil {
+				logger.Logger().Errorln("provider", s.Name, "is skipped:", err)
+				continue
+			}
+
+			entry = registryEntry{cfg: s.ProviderConfig, provider: p}
+		}
+
+		entries[s.Name] = entry
+		providers = append(providers, entry.provider)
+	}
+
+	previous := r.Providers()
+	changed := len(previous) != len(providers)
+	for i := 0; !changed && i < len(providers); i++ {
+		changed = previous[i] != providers[i]
+	}
+
+	if !changed {
+		return nil
+	}
+
+	r.current.Store(&providers)
+
+	stale := make([]domain.Provider, 0)
+	for name, entry := range r.entries {
+		if current, ok := entries[name]; !ok || current.provider != entry.provider {
+			stale = append(stale, entry.provider)
+		}
+	}
+
+	r.entries = entries
+	CloseAll(stale)
+
+	logger.Logger().Infoln("providers reloaded, enabled:", len(providers))
+	return nil
+}
+
+// Watch reloads the providers every interval until ctx is done, a non-positive interval turns reloading off
+func (r *registry) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				logger.Logger().Errorln("couldn't reload providers:", err)
+			}
+		}
+	}
+}
+
+func (r *registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	CloseAll(r.Providers())
+	r.entries = make(map[string]registryEntry)
+	r.current.Store(&[]domain.Provider{})
+
+	return nil
+}
+
+type static []domain.Provider
+
+func NewStatic(providers []domain.Provider) static {
+	return providers
+}
+
+func (s static) Providers() []domain.Provider {
+	return s
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/app/forecaster/domain/mocks"
+)
+
+func TestRegistryReload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agify := domain.StoredProvider{ID: 1, Position: 1, Enabled: true, ProviderConfig: domain.ProviderConfig{Name: "agify",
+		URL: "http://localhost/agify", Input: "name", Fields: map[string]string{domain.AttributeAge: "age"}}}
+	genderize := domain.StoredProvider{ID: 2, Position: 2, Enabled: true, ProviderConfig: domain.ProviderConfig{Name: "genderize",
+		URL: "http://localhost/genderize", Input: "name", Fields: map[string]string{domain.AttributeGender: "gender"}}}
+
+	disabledGenderize := genderize
+	disabledGenderize.Enabled = false
+
+	slowAgify := agify
+	slowAgify.TimeoutMilliseconds = 10000
+
+	mockRepo := mocks.NewMockProvidersRepository(ctrl)
+	gomock.InOrder(
+		mockRepo.EXPECT().ReadProviders(gomock.Any()).Return([]domain.StoredProvider{agify, genderize}, nil),
+		mockRepo.EXPECT().ReadProviders(gomock.Any()).Return([]domain.StoredProvider{agify, disabledGenderize}, nil),
+		mockRepo.EXPECT().ReadProviders(gomock.Any()).Return([]domain.StoredProvider{slowAgify, disabledGenderize}, nil),
+	)
+
+	r := NewRegistry(mockRepo, http.DefaultClient, 1, 1)
+
+	require.NoError(t, r.Reload(context.Background()))
+	providers := r.Providers()
+	require.Len(t, providers, 2)
+	require.Equal(t, "agify", providers[0].Name())
+	require.Equal(t, "genderize", providers[1].Name())
+
+	require.NoError(t, r.Reload(context.Background()))
+	afterDisabling := r.Providers()
+	require.Len(t, afterDisabling, 1)
+	require.Same(t, providers[0], afterDisabling[0])
+
+	require.NoError(t, r.Reload(context.Background()))
+	afterChanging := r.Providers()
+	require.Len(t, afterChanging, 1)
+	require.NotSame(t, providers[0], afterChanging[0])
+}
+
+func TestRegistryWatchWithoutInterval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r := NewRegistry(mocks.NewMockProvidersRepository(ctrl), http.DefaultClient, 1, 1)
+
+	// no ticker is started and nothing is read from the repository
+	r.Watch(context.Background(), 0)
+}
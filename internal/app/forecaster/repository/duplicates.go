@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+// qualifiedFullName is fullNameExpression over the columns of the table, so that persons can be compared with each other
+func qualifiedFullName(table string) string {
+	return fmt.Sprintf("(%[1]s.surname || ' ' || %[1]s.name || ' ' || %[1]s.patronymic)", table)
+}
+
+// maxDuplicateCandidates bounds the number of candidates looked up for a person by each of the indexes
+const maxDuplicateCandidates = 20
+
+// duplicateCandidates are the pairs of persons worth scoring: the ones with similar full names, which are looked up for each
+// person in persons_full_name_trgm_idx, and the ones whose names and surnames sound alike, looked up in persons_surname_key_idx.
+// Both lookups match the predicates of the partial indexes, so the persons are not compared with each other one by one
+var duplicateCandidates = fmt.Sprintf("SELECT a.id AS person_id, similar.id AS duplicate_id FROM persons a CROSS JOIN LATERAL "+
+	"(SELECT b.id FROM persons b WHERE %[3]s %% %[1]s AND b.is_deleted != TRUE "+
+	"AND b.id > a.id LIMIT %[2]d) AS similar WHERE a.is_deleted != TRUE UNION "+
+	"SELECT a.id, alike.id FROM persons a CROSS JOIN LATERAL (SELECT b.id FROM persons b WHERE b.surname_key = a.surname_key "+
+	"AND b.name_key = a.name_key AND b.is_deleted != TRUE AND b.id > a.id LIMIT %[2]d) AS alike WHERE a.is_deleted != TRUE",
+	qualifiedFullName("a"), maxDuplicateCandidates, qualifiedFullName("b"))
+
+// duplicateScores computes the scores described by domain.DuplicatePair for the candidates,
+// an attribute agrees when it is known for both persons and is the same, ages may differ by two years
+var duplicateScores = "SELECT candidates.person_id, candidates.duplicate_id, GREATEST(similarity(" + qualifiedFullName("a") +
+	", " + qualifiedFullName("b") + "), CASE WHEN a.name_key = b.name_key AND a.surname_key = b.surname_key AND " +
+	"a.patronymic_key = b.patronymic_key THEN 0.9 ELSE 0 END) AS name_score, (SELECT COALESCE(AVG(agreement), 0.5) FROM " +
+	"(VALUES (CASE WHEN a.age > 0 AND b.age > 0 THEN (abs(a.age - b.age) <= 2)::INT END), (CASE WHEN a.gender != '' AND " +
+	"b.gender != '' THEN (a.gender = b.gender)::INT END), (CASE WHEN a.nationality != '' AND b.nationality != '' THEN " +
+	"(a.nationality = b.nationality)::INT END)) AS attributes(agreement)) AS attribute_score FROM (" + duplicateCandidates +
+	") AS candidates JOIN persons a ON a.id = candidates.person_id JOIN persons b ON b.id = candidates.duplicate_id"
+
+const duplicateScore = "0.7 * name_score + 0.3 * attribute_score"
+
+// FindDuplicates stores the pairs scoring at least minScore and returns their number. The pairs found before keep
+// their found_at and get the new scores, the ones not found anymore are deleted
+func (r *forecaster) FindDuplicates(ctx context.Context, minScore float64) (int64, error) {
+	var found int64
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("FindDuplicates with min score:", minScore)
+		tag, err := tx.Exec(ctx, "CREATE TEMPORARY TABLE found_duplicates ON COMMIT DROP AS SELECT person_id, duplicate_id, "+
+			duplicateScore+" AS score, name_score, attribute_score FROM ("+duplicateScores+") AS scores WHERE "+
+			duplicateScore+" >= $1", minScore)
+		if err != nil {
+			return err
+		}
+
+		found = tag.RowsAffected()
+
+		_, err = tx.Exec(ctx, "DELETE FROM person_duplicates d WHERE NOT EXISTS (SELECT 1 FROM found_duplicates f "+
+			"WHERE f.person_id = d.person_id AND f.duplicate_id = d.duplicate_id)")
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO person_duplicates(person_id, duplicate_id, score, name_score, attribute_score) "+
+			"SELECT person_id, duplicate_id, score, name_score, attribute_score FROM found_duplicates "+
+			"ON CONFLICT (person_id, duplicate_id) DO UPDATE SET score = EXCLUDED.score, name_score = EXCLUDED.name_score, "+
+			"attribute_score = EXCLUDED.attribute_score WHERE (person_duplicates.score, person_duplicates.name_score, "+
+			"person_duplicates.attribute_score) IS DISTINCT FROM (EXCLUDED.score, EXCLUDED.name_score, EXCLUDED.attribute_score)")
+
+		return err
+	})
+
+	return found, err
+}
+
+// ReadDuplicates returns the found pairs with the highest scores first, pairs with merged or deleted persons are skipped
+func (r *forecaster) ReadDuplicates(ctx context.Context, minScore float64, limit int, offset int) ([]domain.DuplicatePair, error) {
+	pairs := make([]domain.DuplicatePair, 0)
+
+	logger.Logger().Debugln("ReadDuplicates with args:", minScore, limit, offset)
+	err := r.WithConnection(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT d.score, d.name_score, d.attribute_score, d.found_at, a.id, a.name, a.surname, "+
+			"a.patronymic, a.age, a.gender, a.nationality, b.id, b.name, b.surname, b.patronymic, b.age, b.gender, b.nationality "+
+			"FROM person_duplicates d JOIN persons a ON a.id = d.person_id JOIN persons b ON b.id = d.duplicate_id "+
+			"WHERE d.score >= $1 AND a.is_deleted != TRUE AND b.is_deleted != TRUE "+
+			"ORDER BY d.score DESC, d.person_id, d.duplicate_id OFFSET $2 LIMIT $3", minScore, offset, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var pair domain.DuplicatePair
+
+			err = rows.Scan(&pair.Score, &pair.NameScore, &pair.AttributeScore, &pair.FoundAt, &pair.Person.ID, &pair.Person.Name,
+				&pair.Person.Surname, &pair.Person.Patronymic, &pair.Person.Age, &pair.Person.Gender, &pair.Person.Nationality,
+				&pair.Duplicate.ID, &pair.Duplicate.Name, &pair.Duplicate.Surname, &pair.Duplicate.Patronymic, &pair.Duplicate.Age,
+				&pair.Duplicate.Gender, &pair.Duplicate.Nationality)
+			if err != nil {
+				return err
+			}
+
+			pairs = append(pairs, pair)
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if len(pairs) == 0 {
+			return appErrors.ErrNoRowsFound
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
+
+// MergePersons stores both persons in person_merges, deletes the merged person pointing its id and the ids merged into it
+// before to the survivor, and updates the survivor with the chosen values
+func (r *forecaster) MergePersons(ctx context.Context, request domain.MergeRequest) (domain.PersonFromDB, error) {
+	var result domain.PersonFromDB
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("MergePersons with request:", request)
+		rows, err := tx.Query(ctx, "SELECT id, name, surname, patronymic, age, gender, nationality FROM persons "+
+			"WHERE id IN ($1, $2) AND is_deleted != TRUE ORDER BY id FOR UPDATE", request.SurvivorID, request.MergedID)
+		if err != nil {
+			return err
+		}
+
+		persons := make(map[int]domain.PersonFromDB, 2)
+		for rows.Next() {
+			var person domain.PersonFromDB
+
+			err = rows.Scan(&person.ID, &person.Name, &person.Surname, &person.Patronymic, &person.Age, &person.Gender, &person.Nationality)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			persons[person.ID] = person
+		}
+
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		survivor, ok := persons[request.SurvivorID]
+		merged, mergedOK := persons[request.MergedID]
+		if !ok || !mergedOK {
+			return appErrors.ErrNoRowsFound
+		}
+
+		survivorJSON, err := json.Marshal(survivor)
+		if err != nil {
+			return err
+		}
+
+		mergedJSON, err := json.Marshal(merged)
+		if err != nil {
+			return err
+		}
+
+		fieldsJSON, err := json.Marshal(request.Fields)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO person_merges(survivor_id, merged_id, survivor, merged, fields) VALUES ($1, $2, $3, $4, $5)",
+			survivor.ID, merged.ID, survivorJSON, mergedJSON, fieldsJSON)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE persons SET is_deleted = TRUE, merged_into = $1, version = version + 1, updated_at = now() "+
+			"WHERE id = $2 OR merged_into = $2", survivor.ID, merged.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM person_duplicates WHERE person_id = $1 OR duplicate_id = $1", merged.ID)
+		if err != nil {
+			return err
+		}
+
+		// the merged person keeps its name, so taking all of its name makes the survivor clash with it
+		result = request.Apply(survivor, merged)
+		nameKey, surnameKey, patronymicKey := phoneticKeys(domain.Person{Name: result.Name, Surname: result.Surname, Patronymic: result.Patronymic})
+		err = tx.QueryRow(ctx, "UPDATE persons SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5, nationality = $6,"+
+			" name_key = $7, surname_key = $8, patronymic_key = $9, version = version + 1, updated_at = now() WHERE id = $10"+
+			" RETURNING version, updated_at",
+			result.Name, result.Surname, result.Patronymic, result.Age, result.Gender, result.Nationality, nameKey, surnameKey,
+			patronymicKey, survivor.ID).Scan(&result.Version, &result.UpdatedAt)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return appErrors.ErrUniqueViolation
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return domain.PersonFromDB{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"identity-forecaster/internal/app/forecaster/domain"
+)
+
+var comparisonOperators = map[domain.FilterOperator]string{domain.OpEqual: "=", domain.OpNotEqual: "!=", domain.OpLess: "<",
+	domain.OpLessOrEqual: "<=", domain.OpGreater: ">", domain.OpGreaterOrEqual: ">="}
+
+// compileFilterExpression turns the expression into a condition, its values are appended to args and referenced by their numbers.
+// Column names are not escaped as domain.ParseFilterExpression accepts only the columns of a person
+func compileFilterExpression(expression domain.FilterExpression, args *[]interface{}) string {
+	arg := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	switch expression := expression.(type) {
+	case domain.FilterGroup:
+		separator := " AND "
+		if expression.Or {
+			separator = " OR "
+		}
+
+		conditions := make([]string, len(expression.Children))
+		for i, child := range expression.Children {
+			conditions[i] = compileFilterExpression(child, args)
+		}
+
+		return "(" + strings.Join(conditions, separator) + ")"
+	case domain.FilterCondition:
+		column := expression.Column
+
+		switch expression.Operator {
+		case domain.OpIn, domain.OpOut:
+			placeholders := make([]string, len(expression.Values))
+			for i, value := range expression.Values {
+				placeholders[i] = arg(value)
+			}
+
+			operator := "IN"
+			if expression.Operator == domain.OpOut {
+				operator = "NOT IN"
+			}
+
+			return fmt.Sprintf("(%s %s (%s))", column, operator, strings.Join(placeholders, ", "))
+		case domain.OpIsNull:
+			empty := "''"
+			typed, _ := (domain.PersonFromDB{}).Column(column)
+			if _, ok := typed.(int); ok {
+				empty = "0"
+			}
+
+			condition := fmt.Sprintf("(%s IS NULL OR %s = %s)", column, column, empty)
+			if isNull, _ := expression.Values[0].(bool); !isNull {
+				condition = "NOT " + condition
+			}
+
+			return condition
+		default:
+			return fmt.Sprintf("(%s %s %s)", column, comparisonOperators[expression.Operator], arg(expression.Values[0]))
+		}
+	default:
+		return "FALSE"
+	}
+}
@@ -2,7 +2,12 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -16,8 +21,61 @@ import (
 
 var (
 	_ domain.ForecasterRepository = (*forecaster)(nil)
+
+	// errDryRun makes WithTransaction roll back the changes of a dry run
+	errDryRun = errors.New("dry run")
 )
 
+// personsFilterCondition applies domain.Filters except the expression passed as the first nine arguments, see filterCondition.
+// The bounds are BIGINT, since the exclusive upper bound of id=2147483647 does not fit the INTEGER columns
+const personsFilterCondition = "(id >= $1::BIGINT AND id < $2::BIGINT) AND (age >= $3::BIGINT AND age < $4::BIGINT) AND ($5::TEXT IS NULL OR name = $5::TEXT) AND " +
+	"($6::TEXT IS NULL OR surname = $6::TEXT) AND ($7::TEXT IS NULL OR patronymic = $7::TEXT) AND ($8::TEXT " +
+	"IS NULL OR gender = $8::TEXT) AND ($9::TEXT IS NULL OR nationality = $9::TEXT) AND is_deleted != TRUE"
+
+// fullNameExpression is the full name fuzzy search works on, persons_full_name_trgm_idx is built over the same expression
+const fullNameExpression = "(surname || ' ' || name || ' ' || patronymic)"
+
+// likeEscaper makes the wildcards of LIKE match literally
+var likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+// filterCondition returns the condition applying all the filters and its arguments, numbered from 1.
+// The columns of text matches are name, surname or patronymic, they are not escaped
+func filterCondition(filters domain.Filters) (string, []interface{}) {
+	args := []interface{}{filters.IDMoreThan.Value, filters.IDLessThan.Value, filters.AgeMoreThan.Value, filters.AgeLessThan.Value,
+		filters.NameEqualTo.Value, filters.SurnameEqualTo.Value, filters.PatronymicEqualTo.Value, filters.GenderEqualTo.Value,
+		filters.NationalityEqualTo.Value}
+
+	condition := personsFilterCondition
+
+	for _, match := range filters.TextMatches {
+		pattern := likeEscaper.Replace(match.Value) + "%"
+		if !match.Prefix {
+			pattern = "%" + pattern
+		}
+
+		args = append(args, pattern)
+		condition += fmt.Sprintf(" AND %s ILIKE $%d", match.Column, len(args))
+	}
+
+	// every word has to sound like one of the parts of the full name
+	for _, key := range filters.SoundsLike {
+		args = append(args, key)
+		condition += fmt.Sprintf(" AND $%d IN (name_key, surname_key, patronymic_key)", len(args))
+	}
+
+	// <% is true when the query is similar enough to some part of the full name, see pg_trgm.word_similarity_threshold
+	if filters.Query.Value.Valid {
+		args = append(args, filters.Query.Value.String)
+		condition += fmt.Sprintf(" AND $%d <%% %s", len(args), fullNameExpression)
+	}
+
+	if filters.Expression != nil {
+		condition += " AND " + compileFilterExpression(filters.Expression, &args)
+	}
+
+	return condition, args
+}
+
 type forecaster struct {
 	*postgres
 }
@@ -25,26 +83,109 @@ type forecaster struct {
 func New(pg *postgres) *forecaster {
 	return &forecaster{pg}
 }
-func (r *forecaster) CreatePerson(ctx context.Context, person domain.Person, apiData domain.DataFromAPI) error {
-	return r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
-		logger.Logger().Debugln("CreatePerson with args:", person, apiData)
-		_, err := tx.Exec(ctx, "INSERT INTO persons(name, surname, patronymic, age, gender, nationality, is_deleted)"+
-			" VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT ON CONSTRAINT persons_pkey DO UPDATE SET age ="+
-			" EXCLUDED.age, gender = EXCLUDED.gender, nationality = EXCLUDED.nationality, is_deleted = "+
-			"EXCLUDED.is_deleted WHERE persons.is_deleted = TRUE", person.Name, person.Surname, person.Patronymic, apiData.Age, apiData.Gender,
-			apiData.Nationality, false)
+
+// onConflict is the conflict clause of the insert of a person for the mode, it returns the id only when the person is changed
+func onConflict(mode domain.ConflictMode) string {
+	switch mode {
+	case domain.ConflictIgnore:
+		return "ON CONFLICT ON CONSTRAINT persons_pkey DO NOTHING"
+	case domain.ConflictUpdate:
+		return "ON CONFLICT ON CONSTRAINT persons_pkey DO UPDATE SET age = EXCLUDED.age, gender = EXCLUDED.gender, nationality = " +
+			"EXCLUDED.nationality, is_deleted = EXCLUDED.is_deleted, merged_into = NULL, version = persons.version + 1, updated_at = now()"
+	default:
+		// a revived person keeps the stored attributes the new data has no values for
+		return "ON CONFLICT ON CONSTRAINT persons_pkey DO UPDATE SET age = COALESCE(NULLIF(EXCLUDED.age, 0), persons.age), " +
+			"gender = COALESCE(NULLIF(EXCLUDED.gender, ''), persons.gender), nationality = COALESCE(NULLIF(EXCLUDED.nationality, ''), " +
+			"persons.nationality), is_deleted = EXCLUDED.is_deleted, merged_into = NULL, version = persons.version + 1, " +
+			"updated_at = now() WHERE persons.is_deleted = TRUE"
+	}
+}
+
+func (r *forecaster) CreatePerson(ctx context.Context, person domain.Person, apiData domain.DataFromAPI, mode domain.ConflictMode) (int, error) {
+	var id int
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("CreatePerson with args:", person, apiData, mode)
+		nameKey, surnameKey, patronymicKey := phoneticKeys(person)
+		err := tx.QueryRow(ctx, "INSERT INTO persons(name, surname, patronymic, age, gender, nationality, is_deleted, name_key,"+
+			" surname_key, patronymic_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) "+onConflict(mode)+" RETURNING id",
+			person.Name, person.Surname, person.Patronymic, apiData.Age, apiData.Gender, apiData.Nationality, false, nameKey,
+			surnameKey, patronymicKey).Scan(&id)
+
+		// nothing is returned when the stored person is kept, the responses did not affect anything then
+		if errors.Is(err, pgx.ErrNoRows) && mode == domain.ConflictError {
+			return appErrors.ErrUniqueViolation
+		}
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+
 		if err != nil {
 			return err
 		}
 
+		return insertProviderResponses(ctx, tx, id, apiData.RawResponses)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// CreatePersons inserts persons in a single transaction, the returned slice holds ids in the order of persons,
+// 0 means the person already exists and is not deleted. A dry run rolls the transaction back after the inserts
+func (r *forecaster) CreatePersons(ctx context.Context, persons []domain.PersonWithAPIData, dryRun bool) ([]int, error) {
+	ids := make([]int, len(persons))
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("CreatePersons with persons amount:", len(persons), dryRun)
+		batch := &pgx.Batch{}
+		for _, person := range persons {
+			nameKey, surnameKey, patronymicKey := phoneticKeys(person.Person())
+			batch.Queue("INSERT INTO persons(name, surname, patronymic, age, gender, nationality, is_deleted, name_key,"+
+				" surname_key, patronymic_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) "+onConflict(domain.ConflictRevive)+
+				" RETURNING id", person.Name, person.Surname, person.Patronymic, person.Age, person.Gender, person.Nationality, false,
+				nameKey, surnameKey, patronymicKey)
+		}
+
+		results := tx.SendBatch(ctx, batch)
+
+		for i := range persons {
+			err := results.QueryRow().Scan(&ids[i])
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				results.Close()
+				return err
+			}
+		}
+
+		if err := results.Close(); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+
 		return nil
 	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	return ids, nil
 }
 
-func (r *forecaster) DeletePersonByID(ctx context.Context, id int) error {
+// EnrichPerson fills the attributes which are still empty with the data from providers, imported values are kept
+func (r *forecaster) EnrichPerson(ctx context.Context, id int, apiData domain.DataFromAPI) error {
 	return r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
-		logger.Logger().Debugln("DeletePersonByID with id:", id)
-		tag, err := tx.Exec(ctx, "UPDATE persons SET is_deleted = true WHERE id = $1 AND is_deleted != true", id)
+		logger.Logger().Debugln("EnrichPerson with args:", id, apiData)
+		tag, err := tx.Exec(ctx, "UPDATE persons SET age = COALESCE(NULLIF(age, 0), $1), gender = COALESCE(NULLIF(gender, ''), $2), "+
+			"nationality = COALESCE(NULLIF(nationality, ''), $3), version = version + 1, updated_at = now() WHERE id = $4 AND is_deleted != TRUE",
+			apiData.Age, apiData.Gender, apiData.Nationality, id)
 		if err != nil {
 			return err
 		}
@@ -53,17 +194,62 @@ func (r *forecaster) DeletePersonByID(ctx context.Context, id int) error {
 			return appErrors.ErrNoRowsAffected
 		}
 
-		return nil
+		return insertProviderResponses(ctx, tx, id, apiData.RawResponses)
 	})
 }
 
-func (r *forecaster) UpdatePerson(ctx context.Context, id int, data domain.PersonWithAPIData) error {
+func insertProviderResponses(ctx context.Context, tx pgx.Tx, personID int, responses []domain.ProviderResponse) error {
+	for _, raw := range responses {
+		headers, err := json.Marshal(raw.Headers)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO provider_responses(person_id, provider, url, status_code, headers, body, received_at)"+
+			" VALUES ($1, $2, $3, $4, $5, $6, $7)", personID, raw.Provider, raw.URL, raw.StatusCode, headers, []byte(raw.Body), raw.ReceivedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeletePersonByID deletes the person if it has the version, version 0 is not checked
+func (r *forecaster) DeletePersonByID(ctx context.Context, id int, version int) error {
 	return r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
-		logger.Logger().Debugln("UpdatePersons with args:", id, data)
+		logger.Logger().Debugln("DeletePersonByID with args:", id, version)
+		var currentVersion int
+		err := tx.QueryRow(ctx, "SELECT version FROM persons WHERE id = $1 AND is_deleted != true FOR UPDATE", id).Scan(&currentVersion)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return appErrors.ErrNoRowsAffected
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && version != currentVersion {
+			return appErrors.ErrVersionMismatch
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE persons SET is_deleted = true, version = version + 1, updated_at = now() WHERE id = $1", id)
+		return err
+	})
+}
+
+// UpdatePerson changes the person if it has the version, version 0 is not checked. The person is returned as it is after the update
+func (r *forecaster) UpdatePerson(ctx context.Context, id int, data domain.PersonWithAPIData, version int) (domain.PersonFromDB, error) {
+	var person domain.PersonFromDB
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("UpdatePersons with args:", id, data, version)
 		var previousValues domain.PersonWithAPIData
-		err := tx.QueryRow(ctx, "SELECT name, surname, patronymic, age, gender, nationality, is_deleted FROM persons WHERE id = $1",
-			id).Scan(&previousValues.Name, &previousValues.Surname, &previousValues.Patronymic,
-			&previousValues.Age, &previousValues.Gender, &previousValues.Nationality, &previousValues.IsDeleted)
+		var currentVersion int
+		err := tx.QueryRow(ctx, "SELECT name, surname, patronymic, age, gender, nationality, is_deleted, version FROM persons "+
+			"WHERE id = $1 FOR UPDATE", id).Scan(&previousValues.Name, &previousValues.Surname, &previousValues.Patronymic,
+			&previousValues.Age, &previousValues.Gender, &previousValues.Nationality, &previousValues.IsDeleted, &currentVersion)
 
 		if errors.Is(err, pgx.ErrNoRows) {
 			return appErrors.ErrNoRowsFound
@@ -73,47 +259,282 @@ func (r *forecaster) UpdatePerson(ctx context.Context, id int, data domain.Perso
 			return err
 		}
 
+		if version != 0 && version != currentVersion {
+			return appErrors.ErrVersionMismatch
+		}
+
 		data.ReplaceDefaultValuesWithFieldsOfStruct(previousValues)
 
-		tag, err := tx.Exec(ctx, "UPDATE persons SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5,"+
-			" nationality = $6, is_deleted = $7 WHERE id = $8", data.Name, data.Surname, data.Patronymic, data.Age,
-			data.Gender, data.Nationality, *data.IsDeleted, id)
+		nameKey, surnameKey, patronymicKey := phoneticKeys(data.Person())
+		err = tx.QueryRow(ctx, "UPDATE persons SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5,"+
+			" nationality = $6, is_deleted = $7, name_key = $8, surname_key = $9, patronymic_key = $10, version = version + 1,"+
+			" updated_at = now() WHERE id = $11 RETURNING id, name, surname, patronymic, age, gender, nationality, version, updated_at",
+			data.Name, data.Surname, data.Patronymic, data.Age, data.Gender, data.Nationality, *data.IsDeleted, nameKey,
+			surnameKey, patronymicKey, id).Scan(&person.ID, &person.Name, &person.Surname, &person.Patronymic, &person.Age,
+			&person.Gender, &person.Nationality, &person.Version, &person.UpdatedAt)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return appErrors.ErrUniqueViolation
+		}
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return appErrors.ErrNoRowsAffected
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return domain.PersonFromDB{}, err
+	}
+
+	return person, nil
+}
+
+// ModifyPerson locks the person, checks the version as UpdatePerson does and stores the result of modify.
+// Nothing is written when modify does not change the person
+func (r *forecaster) ModifyPerson(ctx context.Context, id int, version int,
+	modify func(domain.PersonFromDB) (domain.PersonFromDB, error)) (domain.PersonFromDB, error) {
+	var person domain.PersonFromDB
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("ModifyPerson with args:", id, version)
+		var current domain.PersonFromDB
+		err := tx.QueryRow(ctx, "SELECT id, name, surname, patronymic, age, gender, nationality, version, updated_at FROM persons "+
+			"WHERE id = $1 AND is_deleted != TRUE FOR UPDATE", id).Scan(&current.ID, &current.Name, &current.Surname,
+			&current.Patronymic, &current.Age, &current.Gender, &current.Nationality, &current.Version, &current.UpdatedAt)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return appErrors.ErrNoRowsFound
+		}
 
 		if err != nil {
-			var pgErr *pgconn.PgError
-			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-				return appErrors.ErrUniqueViolation
+			return err
+		}
+
+		if version != 0 && version != current.Version {
+			return appErrors.ErrVersionMismatch
+		}
+
+		modified, err := modify(current)
+		if err != nil {
+			return err
+		}
+
+		if modified == current {
+			person = current
+			return nil
+		}
+
+		nameKey, surnameKey, patronymicKey := phoneticKeys(domain.Person{Name: modified.Name, Surname: modified.Surname,
+			Patronymic: modified.Patronymic})
+		err = tx.QueryRow(ctx, "UPDATE persons SET name = $1, surname = $2, patronymic = $3, age = $4, gender = $5,"+
+			" nationality = $6, name_key = $7, surname_key = $8, patronymic_key = $9, version = version + 1, updated_at = now()"+
+			" WHERE id = $10 RETURNING id, name, surname, patronymic, age, gender, nationality, version, updated_at",
+			modified.Name, modified.Surname, modified.Patronymic, modified.Age, modified.Gender, modified.Nationality, nameKey,
+			surnameKey, patronymicKey, id).Scan(&person.ID, &person.Name, &person.Surname, &person.Patronymic, &person.Age,
+			&person.Gender, &person.Nationality, &person.Version, &person.UpdatedAt)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return appErrors.ErrUniqueViolation
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return domain.PersonFromDB{}, err
+	}
+
+	return person, nil
+}
+
+func (r *forecaster) ReadPersons(ctx context.Context, page domain.Page, filters domain.Filters) (domain.PersonsPage, error) {
+	result := domain.PersonsPage{Items: make([]domain.PersonFromDB, 0)}
+
+	logger.Logger().Debugln("ReadPersons with args:", page, filters)
+	err := r.WithConnection(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		condition, args := filterCondition(filters)
+
+		score := "NULL::REAL"
+		if filters.Query.Value.Valid {
+			args = append(args, filters.Query.Value.String)
+			score = fmt.Sprintf("word_similarity($%d, %s)", len(args), fullNameExpression)
+		}
+
+		// the subquery names the score, so that the cursor condition and the sort can refer to it like to any other column
+		query := "SELECT id, name, surname, patronymic, age, gender, nationality, version, score FROM (SELECT id, name, surname, " +
+			"patronymic, age, gender, nationality, version, " + score + " AS score FROM persons WHERE " + condition + ") AS persons"
+
+		offset := (page.Number - 1) * page.Limit
+		if page.After != nil {
+			cursorCondition, cursorArgs := afterCursor(page.Sort, *page.After, len(args)+1)
+			query += " WHERE " + cursorCondition
+			args = append(args, cursorArgs...)
+			offset = 0
+		}
+
+		// one more row tells whether there is a next page
+		query += fmt.Sprintf(" ORDER BY %s OFFSET $%d LIMIT $%d", orderBy(page.Sort), len(args)+1, len(args)+2)
+		rows, err := conn.Query(ctx, query, append(args, offset, page.Limit+1)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var person domain.PersonFromDB
+
+			err = rows.Scan(&person.ID, &person.Name, &person.Surname, &person.Patronymic, &person.Age, &person.Gender,
+				&person.Nationality, &person.Version, &person.Score)
+			if err != nil {
+				return err
 			}
 
+			result.Items = append(result.Items, person)
+		}
+
+		if err = rows.Err(); err != nil {
 			return err
 		}
 
-		if tag.RowsAffected() == 0 {
-			return appErrors.ErrNoRowsAffected
+		if len(result.Items) == 0 {
+			return appErrors.ErrNoRowsFound
+		}
+
+		if len(result.Items) > page.Limit {
+			result.Items = result.Items[:page.Limit]
+			result.NextCursor = domain.CursorAfter(page.Sort, result.Items[page.Limit-1]).Encode()
 		}
 
+		if page.Count == domain.CountNone {
+			return nil
+		}
+
+		total, err := countPersons(ctx, conn, filters, page.Count == domain.CountEstimate)
+		if err != nil {
+			return err
+		}
+
+		result.Total, result.TotalEstimated = &total, page.Count == domain.CountEstimate
 		return nil
 	})
+
+	if err != nil {
+		return domain.PersonsPage{}, err
+	}
+
+	return result, nil
 }
 
-func (r *forecaster) ReadPersons(ctx context.Context, page int, limit int, filters domain.Filters) ([]domain.PersonFromDB, error) {
-	persons := make([]domain.PersonFromDB, 0)
+// countPersons counts the persons matching the filters, an estimated count is the number of rows the planner
+// expects from the statistics in pg_class and pg_statistic, so it stays cheap on large tables but may lag behind
+func countPersons(ctx context.Context, conn *pgxpool.Conn, filters domain.Filters, estimated bool) (int64, error) {
+	var total int64
+
+	condition, args := filterCondition(filters)
+	if !estimated {
+		err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM persons WHERE "+condition, args...).Scan(&total)
+		return total, err
+	}
+
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+
+	var explained []byte
+	err := conn.QueryRow(ctx, "EXPLAIN (FORMAT JSON) SELECT id FROM persons WHERE "+condition, args...).Scan(&explained)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = json.Unmarshal(explained, &plan); err != nil {
+		return 0, err
+	}
+
+	if len(plan) == 0 {
+		return 0, appErrors.ErrNoRowsFound
+	}
+
+	return int64(plan[0].Plan.Rows), nil
+}
+
+// orderBy returns the ORDER BY list for the keys of the sort, the columns are the ones checked by domain.ParseSort
+func orderBy(sort domain.Sort) string {
+	keys := sort.Keys()
+
+	fields := make([]string, len(keys))
+	for i, key := range keys {
+		fields[i] = key.Column
+		if key.Desc {
+			fields[i] += " DESC"
+		}
+	}
+
+	return strings.Join(fields, ", ")
+}
 
-	logger.Logger().Debugln("ReadPersons with args:", page, limit, filters)
+// afterCursor returns the condition matching the rows that follow the cursor in the order of the sort,
+// (a, b) after (x, y) becomes a > x OR (a = x AND b > y) as the directions of the keys may differ.
+// The arguments of the condition are numbered starting from firstArg
+func afterCursor(sort domain.Sort, cursor domain.Cursor, firstArg int) (string, []interface{}) {
+	keys := sort.Keys()
+
+	alternatives := make([]string, len(keys))
+	for i, key := range keys {
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", keys[j].Column, firstArg+j))
+		}
+
+		operator := ">"
+		if key.Desc {
+			operator = "<"
+		}
+
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", key.Column, operator, firstArg+i))
+		alternatives[i] = "(" + strings.Join(conditions, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", cursor.Values
+}
+
+// ReadPersonByID returns the survivor when the person was merged into another one, so the returned id may differ
+func (r *forecaster) ReadPersonByID(ctx context.Context, id int) (domain.PersonFromDB, error) {
+	var person domain.PersonFromDB
+
+	logger.Logger().Debugln("ReadPersonByID with id:", id)
 	err := r.WithConnection(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
-		rows, err := conn.Query(ctx, "SELECT id, name, surname, patronymic, age, gender, nationality FROM persons "+
-			"WHERE (id >= $1 AND id < $2) AND (age >= $3 AND age < $4) AND ($5::TEXT IS NULL OR name = $5::TEXT) AND "+
-			"($6::TEXT IS NULL OR surname = $6::TEXT) AND ($7::TEXT IS NULL OR patronymic = $7::TEXT) AND ($8::TEXT "+
-			"IS NULL OR gender = $8::TEXT) AND ($9::TEXT IS NULL OR nationality = $9::TEXT) AND is_deleted != TRUE "+
-			"ORDER BY id OFFSET $10 LIMIT $11", filters.IDMoreThan.Value, filters.IDLessThan.Value,
-			filters.AgeMoreThan.Value, filters.AgeLessThan.Value, filters.NameEqualTo.Value, filters.SurnameEqualTo.Value,
-			filters.PatronymicEqualTo.Value, filters.GenderEqualTo.Value, filters.NationalityEqualTo.Value, (page-1)*limit, limit)
+		err := conn.QueryRow(ctx, "SELECT id, name, surname, patronymic, age, gender, nationality, version, updated_at FROM persons "+
+			"WHERE id = COALESCE((SELECT merged_into FROM persons WHERE id = $1), $1) AND is_deleted != TRUE", id).Scan(&person.ID,
+			&person.Name, &person.Surname, &person.Patronymic, &person.Age, &person.Gender, &person.Nationality, &person.Version,
+			&person.UpdatedAt)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return appErrors.ErrNoRowsFound
+		}
 
+		return err
+	})
+
+	return person, err
+}
+
+// StreamPersons passes every person matching the filters to fn as soon as the row is received, without collecting them
+func (r *forecaster) StreamPersons(ctx context.Context, filters domain.Filters, fn func(domain.PersonFromDB) error) error {
+	logger.Logger().Debugln("StreamPersons with filters:", filters)
+	return r.WithConnection(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		condition, args := filterCondition(filters)
+		rows, err := conn.Query(ctx, "SELECT id, name, surname, patronymic, age, gender, nationality FROM persons "+
+			"WHERE "+condition+" ORDER BY id", args...)
 		if err != nil {
 			return err
 		}
-
-		var i int
+		defer rows.Close()
 
 		for rows.Next() {
 			var person domain.PersonFromDB
@@ -123,20 +544,78 @@ func (r *forecaster) ReadPersons(ctx context.Context, page int, limit int, filte
 				return err
 			}
 
-			persons = append(persons, person)
-			i++
+			if err = fn(person); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	})
+}
+
+func (r *forecaster) ReadProviderResponses(ctx context.Context, personID int) ([]domain.ProviderResponse, error) {
+	responses := make([]domain.ProviderResponse, 0)
+
+	logger.Logger().Debugln("ReadProviderResponses with person id:", personID)
+	err := r.WithConnection(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM persons WHERE id = $1 AND is_deleted != TRUE)", personID).
+			Scan(&exists)
+		if err != nil {
+			return err
 		}
 
-		if i == 0 {
+		if !exists {
 			return appErrors.ErrNoRowsFound
 		}
 
-		return nil
+		rows, err := conn.Query(ctx, "SELECT id, person_id, provider, url, status_code, headers, body, received_at "+
+			"FROM provider_responses WHERE person_id = $1 ORDER BY received_at, id", personID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var response domain.ProviderResponse
+			var headers []byte
+
+			err = rows.Scan(&response.ID, &response.PersonID, &response.Provider, &response.URL, &response.StatusCode,
+				&headers, &response.Body, &response.ReceivedAt)
+			if err != nil {
+				return err
+			}
+
+			if err = json.Unmarshal(headers, &response.Headers); err != nil {
+				return err
+			}
+
+			responses = append(responses, response)
+		}
+
+		return rows.Err()
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return persons, nil
+	return responses, nil
+}
+
+func (r *forecaster) DeleteProviderResponsesOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	var deleted int64
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("DeleteProviderResponsesOlderThan with age:", age)
+		tag, err := tx.Exec(ctx, "DELETE FROM provider_responses WHERE received_at < $1", time.Now().Add(-age))
+		if err != nil {
+			return err
+		}
+
+		deleted = tag.RowsAffected()
+		return nil
+	})
+
+	return deleted, err
 }
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+var (
+	_ domain.IdempotencyRepository = (*idempotency)(nil)
+)
+
+// idempotencyLockTimeout is how long a key may stay claimed by a request without its response, after that the instance
+// processing the request is considered gone and the key may be claimed again. The token of the claim changes then,
+// so a request still running after the timeout can neither store its response nor release the key of another one
+const idempotencyLockTimeout = 5 * time.Minute
+
+type idempotency struct {
+	*postgres
+}
+
+func NewIdempotency(pg *postgres) *idempotency {
+	return &idempotency{pg}
+}
+
+func (r *idempotency) BeginRequest(ctx context.Context, key string, token string, requestHash string,
+	ttl time.Duration) (*domain.IdempotentResponse, error) {
+	var response *domain.IdempotentResponse
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("BeginRequest with args:", key, requestHash, ttl)
+		now := time.Now()
+		tag, err := tx.Exec(ctx, "INSERT INTO idempotency_keys(key, token, request_hash, expires_at) VALUES ($1, $2, $3, $4) "+
+			"ON CONFLICT (key) DO UPDATE SET token = EXCLUDED.token, request_hash = EXCLUDED.request_hash, status = NULL, "+
+			"content_type = '', body = NULL, created_at = now(), expires_at = EXCLUDED.expires_at WHERE "+
+			"idempotency_keys.expires_at <= now() OR (idempotency_keys.status IS NULL AND idempotency_keys.created_at < $5)",
+			key, token, requestHash, now.Add(ttl), now.Add(-idempotencyLockTimeout))
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 1 {
+			return nil
+		}
+
+		var storedHash string
+		var status *int
+		var stored domain.IdempotentResponse
+
+		err = tx.QueryRow(ctx, "SELECT request_hash, status, content_type, body FROM idempotency_keys WHERE key = $1", key).Scan(
+			&storedHash, &status, &stored.ContentType, &stored.Body)
+		if err != nil {
+			return err
+		}
+
+		if storedHash != requestHash {
+			return appErrors.ErrIdempotencyKeyReused
+		}
+
+		if status == nil {
+			return appErrors.ErrIdempotencyKeyInProgress
+		}
+
+		stored.Status = *status
+		response = &stored
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (r *idempotency) CompleteRequest(ctx context.Context, key string, token string, response domain.IdempotentResponse) error {
+	return r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("CompleteRequest with args:", key, response.Status, response.ContentType)
+		tag, err := tx.Exec(ctx, "UPDATE idempotency_keys SET status = $1, content_type = $2, body = $3 "+
+			"WHERE key = $4 AND token = $5 AND status IS NULL", response.Status, response.ContentType, response.Body, key, token)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return appErrors.ErrNoRowsAffected
+		}
+
+		return nil
+	})
+}
+
+func (r *idempotency) AbandonRequest(ctx context.Context, key string, token string) error {
+	return r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("AbandonRequest with key:", key)
+		_, err := tx.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND token = $2 AND status IS NULL", key, token)
+		return err
+	})
+}
+
+func (r *idempotency) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	var deleted int64
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("DeleteExpiredIdempotencyKeys")
+		tag, err := tx.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= now()")
+		if err != nil {
+			return err
+		}
+
+		deleted = tag.RowsAffected()
+		return nil
+	})
+
+	return deleted, err
+}
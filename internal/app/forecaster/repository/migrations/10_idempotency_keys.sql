@@ -0,0 +1,10 @@
+-- +goose Up
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS idempotency_keys(key VARCHAR(255) primary key, request_hash TEXT not null, status INTEGER, content_type TEXT not null default '', body BYTEA, created_at TIMESTAMPTZ not null default now(), expires_at TIMESTAMPTZ not null);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys(expires_at);
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+DROP TABLE IF EXISTS idempotency_keys;
+COMMIT;
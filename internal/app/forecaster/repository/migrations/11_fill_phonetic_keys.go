@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+
+	"identity-forecaster/pkg/phonetic"
+)
+
+func init() {
+	goose.AddMigration(upFillPhoneticKeys, downFillPhoneticKeys)
+}
+
+type personNames struct {
+	id                        int
+	name, surname, patronymic string
+}
+
+// upFillPhoneticKeys computes the keys of the persons stored before the keys were introduced,
+// new persons get their keys when they are written
+func upFillPhoneticKeys(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, name, surname, patronymic FROM persons WHERE name_key IS NULL")
+	if err != nil {
+		return err
+	}
+
+	persons := make([]personNames, 0)
+	for rows.Next() {
+		var p personNames
+		if err = rows.Scan(&p.id, &p.name, &p.surname, &p.patronymic); err != nil {
+			rows.Close()
+			return err
+		}
+
+		persons = append(persons, p)
+	}
+
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE persons SET name_key = $1, surname_key = $2, patronymic_key = $3 WHERE id = $4")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range persons {
+		_, err = stmt.Exec(phonetic.Key(p.name), phonetic.Key(p.surname), phonetic.Key(p.patronymic), p.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// downFillPhoneticKeys keeps the keys, they are dropped together with their columns by the migration adding them
+func downFillPhoneticKeys(*sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+BEGIN TRANSACTION;
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS token TEXT not null default '';
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS token;
+COMMIT;
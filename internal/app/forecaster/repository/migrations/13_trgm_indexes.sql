@@ -0,0 +1,12 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_name_trgm_idx ON persons USING GIN (name gin_trgm_ops) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_surname_trgm_idx ON persons USING GIN (surname gin_trgm_ops) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_patronymic_trgm_idx ON persons USING GIN (patronymic gin_trgm_ops) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_full_name_trgm_idx ON persons USING GIN ((surname || ' ' || name || ' ' || patronymic) gin_trgm_ops) WHERE is_deleted != TRUE;
+
+-- +goose Down
+DROP INDEX CONCURRENTLY IF EXISTS persons_name_trgm_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_surname_trgm_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_patronymic_trgm_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_full_name_trgm_idx;
@@ -0,0 +1,9 @@
+-- +goose Up
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS providers(id serial primary key, name TEXT unique not null, position INTEGER not null default 0, enabled BOOLEAN not null default true, config JSONB not null, updated_at TIMESTAMPTZ not null default now());
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+DROP TABLE IF EXISTS providers;
+COMMIT;
@@ -0,0 +1,11 @@
+-- +goose Up
+BEGIN TRANSACTION;
+CREATE TABLE IF NOT EXISTS provider_responses(id serial primary key, person_id INTEGER not null references persons(id), provider TEXT not null, url TEXT, status_code INTEGER, headers JSONB, body JSONB not null, received_at TIMESTAMPTZ not null default now());
+CREATE INDEX IF NOT EXISTS provider_responses_person_id_idx ON provider_responses(person_id);
+CREATE INDEX IF NOT EXISTS provider_responses_received_at_idx ON provider_responses(received_at);
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+DROP TABLE IF EXISTS provider_responses;
+COMMIT;
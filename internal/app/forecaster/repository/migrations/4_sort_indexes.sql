@@ -0,0 +1,18 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_age_id_idx ON persons(age, id) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_age_desc_surname_name_id_idx ON persons(age DESC, surname, name, id) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_surname_name_id_idx ON persons(surname, name, id) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_name_id_idx ON persons(name, id) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_patronymic_id_idx ON persons(patronymic, id) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_gender_id_idx ON persons(gender, id) WHERE is_deleted != TRUE;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS persons_nationality_id_idx ON persons(nationality, id) WHERE is_deleted != TRUE;
+
+-- +goose Down
+DROP INDEX CONCURRENTLY IF EXISTS persons_age_id_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_age_desc_surname_name_id_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_surname_name_id_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_name_id_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_patronymic_id_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_gender_id_idx;
+DROP INDEX CONCURRENTLY IF EXISTS persons_nationality_id_idx;
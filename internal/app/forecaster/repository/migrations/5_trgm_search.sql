@@ -0,0 +1,9 @@
+-- +goose Up
+BEGIN TRANSACTION;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+DROP EXTENSION IF EXISTS pg_trgm;
+COMMIT;
@@ -0,0 +1,15 @@
+-- +goose Up
+BEGIN TRANSACTION;
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS name_key TEXT, ADD COLUMN IF NOT EXISTS surname_key TEXT, ADD COLUMN IF NOT EXISTS patronymic_key TEXT;
+CREATE INDEX IF NOT EXISTS persons_name_key_idx ON persons(name_key) WHERE is_deleted != TRUE;
+CREATE INDEX IF NOT EXISTS persons_surname_key_idx ON persons(surname_key) WHERE is_deleted != TRUE;
+CREATE INDEX IF NOT EXISTS persons_patronymic_key_idx ON persons(patronymic_key) WHERE is_deleted != TRUE;
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+DROP INDEX IF EXISTS persons_name_key_idx;
+DROP INDEX IF EXISTS persons_surname_key_idx;
+DROP INDEX IF EXISTS persons_patronymic_key_idx;
+ALTER TABLE persons DROP COLUMN IF EXISTS name_key, DROP COLUMN IF EXISTS surname_key, DROP COLUMN IF EXISTS patronymic_key;
+COMMIT;
@@ -0,0 +1,18 @@
+-- +goose Up
+BEGIN TRANSACTION;
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS merged_into INTEGER references persons(id);
+CREATE INDEX IF NOT EXISTS persons_merged_into_idx ON persons(merged_into) WHERE merged_into IS NOT NULL;
+CREATE TABLE IF NOT EXISTS person_duplicates(person_id INTEGER not null references persons(id), duplicate_id INTEGER not null references persons(id), score REAL not null, name_score REAL not null, attribute_score REAL not null, found_at TIMESTAMPTZ not null default now(), primary key (person_id, duplicate_id));
+CREATE INDEX IF NOT EXISTS person_duplicates_score_idx ON person_duplicates(score DESC, person_id, duplicate_id);
+CREATE TABLE IF NOT EXISTS person_merges(id serial primary key, survivor_id INTEGER not null references persons(id), merged_id INTEGER not null references persons(id), survivor JSONB not null, merged JSONB not null, fields JSONB, merged_at TIMESTAMPTZ not null default now());
+CREATE INDEX IF NOT EXISTS person_merges_survivor_id_idx ON person_merges(survivor_id);
+CREATE INDEX IF NOT EXISTS person_merges_merged_id_idx ON person_merges(merged_id);
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+DROP TABLE IF EXISTS person_merges;
+DROP TABLE IF EXISTS person_duplicates;
+DROP INDEX IF EXISTS persons_merged_into_idx;
+ALTER TABLE persons DROP COLUMN IF EXISTS merged_into;
+COMMIT;
@@ -0,0 +1,9 @@
+-- +goose Up
+BEGIN TRANSACTION;
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ not null default now();
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+ALTER TABLE persons DROP COLUMN IF EXISTS updated_at;
+COMMIT;
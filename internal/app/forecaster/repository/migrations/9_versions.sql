@@ -0,0 +1,9 @@
+-- +goose Up
+BEGIN TRANSACTION;
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS version INTEGER not null default 1;
+COMMIT;
+
+-- +goose Down
+BEGIN TRANSACTION;
+ALTER TABLE persons DROP COLUMN IF EXISTS version;
+COMMIT;
@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/pkg/phonetic"
+)
+
+// phoneticKeys returns the keys stored in name_key, surname_key and patronymic_key for the person
+func phoneticKeys(person domain.Person) (string, string, string) {
+	return phonetic.Key(person.Name), phonetic.Key(person.Surname), phonetic.Key(person.Patronymic)
+}
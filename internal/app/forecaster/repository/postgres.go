@@ -10,6 +10,8 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose"
 
+	// Go migrations register themselves in goose
+	_ "identity-forecaster/internal/app/forecaster/repository/migrations"
 	"identity-forecaster/internal/pkg/logger"
 )
 
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+var (
+	_ domain.ProvidersRepository = (*providers)(nil)
+)
+
+type providers struct {
+	*postgres
+}
+
+func NewProviders(pg *postgres) *providers {
+	return &providers{pg}
+}
+
+func (r *providers) CreateProvider(ctx context.Context, provider domain.StoredProvider) (domain.StoredProvider, error) {
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("CreateProvider with args:", provider)
+		config, err := json.Marshal(provider.ProviderConfig)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, "INSERT INTO providers(name, position, enabled, config) VALUES ($1, $2, $3, $4) RETURNING id",
+			provider.Name, provider.Position, provider.Enabled, config).Scan(&provider.ID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+				return appErrors.ErrUniqueViolation
+			}
+
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return domain.StoredProvider{}, err
+	}
+
+	return provider, nil
+}
+
+func (r *providers) ModifyProvider(ctx context.Context, id int,
+	modify func(domain.StoredProvider) (domain.StoredProvider, error)) (domain.StoredProvider, error) {
+	var provider domain.StoredProvider
+
+	err := r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("ModifyProvider with id:", id)
+		var config []byte
+		err := tx.QueryRow(ctx, "SELECT id, name, position, enabled, config FROM providers WHERE id = $1 FOR UPDATE", id).
+			Scan(&provider.ID, &provider.Name, &provider.Position, &provider.Enabled, &config)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return appErrors.ErrNoRowsFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = unmarshalProviderConfig(config, &provider); err != nil {
+			return err
+		}
+
+		provider, err = modify(provider)
+		if err != nil {
+			return err
+		}
+
+		config, err = json.Marshal(provider.ProviderConfig)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, "UPDATE providers SET position = $1, enabled = $2, config = $3, updated_at = now() WHERE id = $4",
+			provider.Position, provider.Enabled, config, id)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return appErrors.ErrNoRowsAffected
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return domain.StoredProvider{}, err
+	}
+
+	return provider, nil
+}
+
+func (r *providers) DeleteProvider(ctx context.Context, id int) error {
+	return r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		logger.Logger().Debugln("DeleteProvider with id:", id)
+		tag, err := tx.Exec(ctx, "DELETE FROM providers WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return appErrors.ErrNoRowsAffected
+		}
+
+		return nil
+	})
+}
+
+func (r *providers) ReadProviders(ctx context.Context) ([]domain.StoredProvider, error) {
+	stored := make([]domain.StoredProvider, 0)
+
+	err := r.WithConnection(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT id, name, position, enabled, config FROM providers ORDER BY position, id")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var provider domain.StoredProvider
+			var config []byte
+
+			err = rows.Scan(&provider.ID, &provider.Name, &provider.Position, &provider.Enabled, &config)
+			if err != nil {
+				return err
+			}
+
+			if err = unmarshalProviderConfig(config, &provider); err != nil {
+				return err
+			}
+
+			stored = append(stored, provider)
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if len(stored) == 0 {
+			return appErrors.ErrNoRowsFound
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// SeedProviders fills the empty table with the providers from config, so the first start works the same way as before
+func (r *providers) SeedProviders(ctx context.Context, cfgs []domain.ProviderConfig) error {
+	return r.WithTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		// the lock keeps several instances started at once from seeding the table twice
+		_, err := tx.Exec(ctx, "LOCK TABLE providers IN SHARE ROW EXCLUSIVE MODE")
+		if err != nil {
+			return err
+		}
+
+		var alreadySeeded bool
+		err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM providers)").Scan(&alreadySeeded)
+		if err != nil {
+			return err
+		}
+
+		if alreadySeeded {
+			return nil
+		}
+
+		logger.Logger().Infoln("seeding providers table with", len(cfgs), "providers from config")
+		for i, cfg := range cfgs {
+			config, err := json.Marshal(cfg)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, "INSERT INTO providers(name, position, enabled, config) VALUES ($1, $2, TRUE, $3)", cfg.Name, i+1, config)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func unmarshalProviderConfig(config []byte, provider *domain.StoredProvider) error {
+	name := provider.Name
+	if err := json.Unmarshal(config, &provider.ProviderConfig); err != nil {
+		return err
+	}
+
+	provider.Name = name
+	return nil
+}
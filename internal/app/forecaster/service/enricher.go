@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/app/forecaster/provider"
+	"identity-forecaster/internal/pkg/logger"
+)
+
+var _ domain.Enricher = (*enricher)(nil)
+
+// enricher fetches API data for already stored persons in batches, limiting the amount of batches processed at once
+type enricher struct {
+	srv       domain.ForecasterService
+	providers domain.ProviderRegistry
+	batchSize int
+	workers   chan struct{}
+	*sync.WaitGroup
+}
+
+func NewEnricher(srv domain.ForecasterService, providers domain.ProviderRegistry, wg *sync.WaitGroup, batchSize int, workers int) *enricher {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &enricher{srv: srv, providers: providers, batchSize: batchSize, workers: make(chan struct{}, workers), WaitGroup: wg}
+}
+
+func (e *enricher) Enqueue(persons []domain.IdentifiedPerson) {
+	for start := 0; start < len(persons); start += e.batchSize {
+		end := start + e.batchSize
+		if end > len(persons) {
+			end = len(persons)
+		}
+
+		batch := persons[start:end]
+
+		e.Add(1)
+		go func() {
+			defer e.Done()
+
+			e.workers <- struct{}{}
+			defer func() { <-e.workers }()
+
+			e.enrich(batch)
+		}()
+	}
+}
+
+func (e *enricher) enrich(batch []domain.IdentifiedPerson) {
+	providers := e.providers.Providers()
+
+	for _, person := range batch {
+		data, err := provider.FetchAll(context.Background(), providers, person.Person)
+		if err != nil {
+			logger.Logger().Debugln(err)
+			continue
+		}
+
+		if err = e.srv.EnrichPerson(context.Background(), person.ID, data); err != nil {
+			logger.Logger().Debugln(err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"identity-forecaster/internal/app/forecaster/domain"
 )
@@ -16,18 +17,70 @@ func New(repo domain.ForecasterRepository) *forecaster {
 	return &forecaster{repo: repo}
 }
 
-func (s *forecaster) CreatePerson(ctx context.Context, person domain.Person, apiData domain.DataFromAPI) error {
-	return s.repo.CreatePerson(ctx, person, apiData)
+func (s *forecaster) CreatePerson(ctx context.Context, person domain.Person, apiData domain.DataFromAPI, mode domain.ConflictMode) (int, error) {
+	return s.repo.CreatePerson(ctx, person, apiData, mode)
 }
 
-func (s *forecaster) DeletePersonByID(ctx context.Context, id int) error {
-	return s.repo.DeletePersonByID(ctx, id)
+func (s *forecaster) CreatePersons(ctx context.Context, persons []domain.PersonWithAPIData, dryRun bool) ([]int, error) {
+	return s.repo.CreatePersons(ctx, persons, dryRun)
 }
 
-func (s *forecaster) UpdatePerson(ctx context.Context, id int, data domain.PersonWithAPIData) error {
-	return s.repo.UpdatePerson(ctx, id, data)
+func (s *forecaster) EnrichPerson(ctx context.Context, id int, apiData domain.DataFromAPI) error {
+	return s.repo.EnrichPerson(ctx, id, apiData)
 }
 
-func (s *forecaster) ReadPersons(ctx context.Context, page int, limit int, filters domain.Filters) ([]domain.PersonFromDB, error) {
-	return s.repo.ReadPersons(ctx, page, limit, filters)
+func (s *forecaster) DeletePersonByID(ctx context.Context, id int, version int) error {
+	return s.repo.DeletePersonByID(ctx, id, version)
+}
+
+func (s *forecaster) UpdatePerson(ctx context.Context, id int, data domain.PersonWithAPIData, version int) (domain.PersonFromDB, error) {
+	return s.repo.UpdatePerson(ctx, id, data, version)
+}
+
+func (s *forecaster) PatchPerson(ctx context.Context, id int, patch domain.PersonPatch, version int) (domain.PersonFromDB, error) {
+	return s.repo.ModifyPerson(ctx, id, version, patch.Apply)
+}
+
+func (s *forecaster) ReplacePerson(ctx context.Context, id int, replacement domain.PersonReplacement, version int) (domain.PersonFromDB, error) {
+	if err := replacement.Validate(); err != nil {
+		return domain.PersonFromDB{}, err
+	}
+
+	return s.repo.ModifyPerson(ctx, id, version, replacement.Apply)
+}
+
+func (s *forecaster) ReadPersons(ctx context.Context, page domain.Page, filters domain.Filters) (domain.PersonsPage, error) {
+	return s.repo.ReadPersons(ctx, page, filters)
+}
+
+func (s *forecaster) ReadPersonByID(ctx context.Context, id int) (domain.PersonFromDB, error) {
+	return s.repo.ReadPersonByID(ctx, id)
+}
+
+func (s *forecaster) StreamPersons(ctx context.Context, filters domain.Filters, fn func(domain.PersonFromDB) error) error {
+	return s.repo.StreamPersons(ctx, filters, fn)
+}
+
+func (s *forecaster) ReadProviderResponses(ctx context.Context, personID int) ([]domain.ProviderResponse, error) {
+	return s.repo.ReadProviderResponses(ctx, personID)
+}
+
+func (s *forecaster) DeleteProviderResponsesOlderThan(ctx context.Context, age time.Duration) (int64, error) {
+	return s.repo.DeleteProviderResponsesOlderThan(ctx, age)
+}
+
+func (s *forecaster) FindDuplicates(ctx context.Context, minScore float64) (int64, error) {
+	return s.repo.FindDuplicates(ctx, minScore)
+}
+
+func (s *forecaster) ReadDuplicates(ctx context.Context, minScore float64, limit int, offset int) ([]domain.DuplicatePair, error) {
+	return s.repo.ReadDuplicates(ctx, minScore, limit, offset)
+}
+
+func (s *forecaster) MergePersons(ctx context.Context, request domain.MergeRequest) (domain.PersonFromDB, error) {
+	if err := request.Validate(); err != nil {
+		return domain.PersonFromDB{}, err
+	}
+
+	return s.repo.MergePersons(ctx, request)
 }
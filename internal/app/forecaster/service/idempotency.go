@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"identity-forecaster/internal/app/forecaster/domain"
+)
+
+var _ domain.IdempotencyService = (*idempotency)(nil)
+
+type idempotency struct {
+	repo domain.IdempotencyRepository
+}
+
+func NewIdempotency(repo domain.IdempotencyRepository) *idempotency {
+	return &idempotency{repo: repo}
+}
+
+func (s *idempotency) BeginRequest(ctx context.Context, key string, token string, requestHash string,
+	ttl time.Duration) (*domain.IdempotentResponse, error) {
+	return s.repo.BeginRequest(ctx, key, token, requestHash, ttl)
+}
+
+func (s *idempotency) CompleteRequest(ctx context.Context, key string, token string, response domain.IdempotentResponse) error {
+	return s.repo.CompleteRequest(ctx, key, token, response)
+}
+
+func (s *idempotency) AbandonRequest(ctx context.Context, key string, token string) error {
+	return s.repo.AbandonRequest(ctx, key, token)
+}
+
+func (s *idempotency) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredIdempotencyKeys(ctx)
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+
+	appErrors "identity-forecaster/internal/app/forecaster/app-errors"
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/pkg/validation"
+	"identity-forecaster/pkg/vcard"
+)
+
+var _ domain.Importer = (*importer)(nil)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+type importer struct {
+	srv      domain.ForecasterService
+	enricher domain.Enricher
+	maxRows  int
+}
+
+func NewImporter(srv domain.ForecasterService, enricher domain.Enricher, maxRows int) *importer {
+	return &importer{srv: srv, enricher: enricher, maxRows: maxRows}
+}
+
+// importedRow is a row which passed validation and is going to be inserted
+type importedRow struct {
+	reportIndex int
+	person      domain.PersonWithAPIData
+}
+
+// importBatch collects the rows of an import before they are inserted together
+type importBatch struct {
+	report domain.ImportReport
+	rows   []importedRow
+	seen   map[domain.Person]int
+}
+
+func newImportBatch(dryRun bool) *importBatch {
+	return &importBatch{report: domain.ImportReport{DryRun: dryRun, Rows: make([]domain.ImportRow, 0)},
+		rows: make([]importedRow, 0), seen: make(map[domain.Person]int)}
+}
+
+// add rejects a row which failed to parse or to validate and skips a repeated one, the rest wait for insertion
+func (b *importBatch) add(line int, person domain.PersonWithAPIData, err error) {
+	row := domain.ImportRow{Line: line, Name: person.Name, Surname: person.Surname, Patronymic: person.Patronymic}
+
+	if err == nil {
+		err = person.Validate()
+	}
+
+	if err != nil {
+		row.Status, row.Error = domain.ImportStatusRejected, err.Error()
+
+		var violations validation.Errors
+		if errors.As(err, &violations) {
+			row.Violations = violations
+		}
+
+		b.report.Add(row)
+		return
+	}
+
+	if previousLine, ok := b.seen[person.Person()]; ok {
+		row.Status, row.Error = domain.ImportStatusSkipped, fmt.Sprintf("%s: line %d", appErrors.ErrDuplicateItem, previousLine)
+		b.report.Add(row)
+		return
+	}
+
+	b.seen[person.Person()] = line
+	b.rows = append(b.rows, importedRow{reportIndex: len(b.report.Rows), person: person})
+	b.report.Rows = append(b.report.Rows, row)
+}
+
+// ImportCSV creates persons from the rows of a CSV file, rows are rejected one by one, even the ones which could not be parsed
+func (i *importer) ImportCSV(ctx context.Context, r io.Reader, opts domain.CSVImportOptions) (domain.ImportReport, error) {
+	if opts.Encoding == domain.EncodingWindows1251 {
+		r = charmap.Windows1251.NewDecoder().Reader(r)
+	}
+
+	reader := csv.NewReader(skipBOM(r))
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return domain.ImportReport{}, appErrors.ErrWrongCSVMapping
+	}
+
+	if err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	columns, err := mapColumns(header, opts.Mapping)
+	if err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	batch := newImportBatch(opts.DryRun)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return domain.ImportReport{}, err
+		}
+
+		if len(batch.report.Rows) == i.maxRows {
+			return domain.ImportReport{}, appErrors.ErrTooManyItems
+		}
+
+		if parseErr != nil {
+			batch.add(parseErr.StartLine, domain.PersonWithAPIData{}, parseErr)
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		person, err := personFromRecord(record, columns)
+		batch.add(line, person, err)
+	}
+
+	if err = i.create(ctx, batch, opts.DryRun); err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	return batch.report, nil
+}
+
+// ImportVCard creates persons from a stream of vCards, a card without a name is rejected, while a syntax error fails the whole import
+func (i *importer) ImportVCard(ctx context.Context, r io.Reader, dryRun bool) (domain.ImportReport, error) {
+	batch := newImportBatch(dryRun)
+
+	decoder := vcard.NewDecoder(r)
+	for {
+		card, err := decoder.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return domain.ImportReport{}, err
+		}
+
+		if len(batch.report.Rows) == i.maxRows {
+			return domain.ImportReport{}, appErrors.ErrTooManyItems
+		}
+
+		person, err := domain.PersonFromVCard(card)
+		batch.add(card.Line, person, err)
+	}
+
+	if err := i.create(ctx, batch, dryRun); err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	return batch.report, nil
+}
+
+// create inserts the accepted rows, fills their statuses in the report and enqueues enrichment for the rows missing some of the API data
+func (i *importer) create(ctx context.Context, batch *importBatch, dryRun bool) error {
+	if len(batch.rows) == 0 {
+		return nil
+	}
+
+	persons := make([]domain.PersonWithAPIData, 0, len(batch.rows))
+	for _, row := range batch.rows {
+		persons = append(persons, row.person)
+	}
+
+	ids, err := i.srv.CreatePersons(ctx, persons, dryRun)
+	if err != nil {
+		return err
+	}
+
+	toEnrich := make([]domain.IdentifiedPerson, 0, len(batch.rows))
+	for j, row := range batch.rows {
+		reportRow := &batch.report.Rows[row.reportIndex]
+
+		if ids[j] == 0 {
+			reportRow.Status, reportRow.Error = domain.ImportStatusSkipped, appErrors.ErrUniqueViolation.Error()
+			batch.report.Skipped++
+			continue
+		}
+
+		reportRow.Status = domain.ImportStatusCreated
+		batch.report.Created++
+
+		if dryRun {
+			continue
+		}
+
+		reportRow.ID = ids[j]
+		if !row.person.HasAPIData() {
+			toEnrich = append(toEnrich, domain.IdentifiedPerson{ID: ids[j], Person: row.person.Person()})
+		}
+	}
+
+	if len(toEnrich) != 0 {
+		i.enricher.Enqueue(toEnrich)
+	}
+
+	return nil
+}
+
+// mapColumns returns the field of a person for every column of the file, empty for the columns which are not imported
+func mapColumns(header []string, mapping map[string]string) ([]string, error) {
+	columns := make([]string, len(header))
+	mapped := make(map[string]bool, len(header))
+	usedHeaders := 0
+
+	for j, title := range header {
+		title = strings.TrimSpace(title)
+
+		field, ok := mapping[title]
+		if ok {
+			usedHeaders++
+		} else if domain.IsImportField(strings.ToLower(title)) {
+			field = strings.ToLower(title)
+		}
+
+		if field == "" {
+			continue
+		}
+
+		if mapped[field] {
+			return nil, fmt.Errorf("%w: %s is mapped twice", appErrors.ErrWrongCSVMapping, field)
+		}
+
+		mapped[field] = true
+		columns[j] = field
+	}
+
+	if usedHeaders != len(mapping) {
+		return nil, fmt.Errorf("%w: some of the mapped columns are missing", appErrors.ErrWrongCSVMapping)
+	}
+
+	if !mapped["name"] || !mapped["surname"] {
+		return nil, fmt.Errorf("%w: name and surname are required", appErrors.ErrWrongCSVMapping)
+	}
+
+	return columns, nil
+}
+
+func personFromRecord(record []string, columns []string) (domain.PersonWithAPIData, error) {
+	var person domain.PersonWithAPIData
+
+	if len(record) != len(columns) {
+		return person, csv.ErrFieldCount
+	}
+
+	for j, value := range record {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		switch columns[j] {
+		case "name":
+			person.Name = value
+		case "surname":
+			person.Surname = value
+		case "patronymic":
+			person.Patronymic = value
+		case domain.AttributeAge:
+			age, err := strconv.Atoi(value)
+			if err != nil || age < 0 {
+				return person, fmt.Errorf("%w: %s", appErrors.ErrWrongAttributeType, domain.AttributeAge)
+			}
+
+			person.Age = age
+		case domain.AttributeGender:
+			person.Gender = value
+		case domain.AttributeNationality:
+			person.Nationality = value
+		}
+	}
+
+	if person.Name == "" || person.Surname == "" {
+		return person, appErrors.ErrRequiredFieldsNotProvided
+	}
+
+	return person, nil
+}
+
+// skipBOM drops the byte order mark spreadsheet editors like to put at the start of UTF-8 files
+func skipBOM(r io.Reader) io.Reader {
+	start := make([]byte, len(utf8BOM))
+
+	n, err := io.ReadFull(r, start)
+	if err != nil {
+		return bytes.NewReader(start[:n])
+	}
+
+	if bytes.Equal(start, utf8BOM) {
+		return r
+	}
+
+	return io.MultiReader(bytes.NewReader(start), r)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+
+	"identity-forecaster/internal/app/forecaster/domain"
+	"identity-forecaster/internal/app/forecaster/provider"
+)
+
+var _ domain.ProvidersService = (*providers)(nil)
+
+type providers struct {
+	repo domain.ProvidersRepository
+}
+
+func NewProviders(repo domain.ProvidersRepository) *providers {
+	return &providers{repo: repo}
+}
+
+func (s *providers) CreateProvider(ctx context.Context, stored domain.StoredProvider) (domain.StoredProvider, error) {
+	if err := provider.Validate(stored.ProviderConfig); err != nil {
+		return domain.StoredProvider{}, err
+	}
+
+	return s.repo.CreateProvider(ctx, stored)
+}
+
+func (s *providers) UpdateProvider(ctx context.Context, id int, update domain.ProviderUpdate) (domain.StoredProvider, error) {
+	return s.repo.ModifyProvider(ctx, id, func(stored domain.StoredProvider) (domain.StoredProvider, error) {
+		update.Apply(&stored)
+
+		if err := provider.Validate(stored.ProviderConfig); err != nil {
+			return domain.StoredProvider{}, err
+		}
+
+		return stored, nil
+	})
+}
+
+func (s *providers) DeleteProvider(ctx context.Context, id int) error {
+	return s.repo.DeleteProvider(ctx, id)
+}
+
+func (s *providers) ReadProviders(ctx context.Context) ([]domain.StoredProvider, error) {
+	return s.repo.ReadProviders(ctx)
+}
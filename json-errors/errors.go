@@ -4,4 +4,8 @@ import "errors"
 
 var (
 	ErrDuplicateFieldInJSON = errors.New("duplicate field found in provided JSON")
+	ErrEmptyPath            = errors.New("empty JSON path provided")
+	ErrMalformedPath        = errors.New("malformed JSON path provided")
+	ErrPathNotFound         = errors.New("nothing found by the JSON path")
+	ErrIndexOutOfRange      = errors.New("index from the JSON path is out of range")
 )
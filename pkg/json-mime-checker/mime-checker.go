@@ -3,12 +3,24 @@ package json_mime_checker
 import "net/http"
 
 func IsJSONContentTypeCorrect(r *http.Request) bool {
+	return IsContentTypeCorrect(r, "application/json")
+}
+
+func IsNDJSONContentTypeCorrect(r *http.Request) bool {
+	return IsContentTypeCorrect(r, "application/x-ndjson")
+}
+
+func IsMergePatchContentTypeCorrect(r *http.Request) bool {
+	return IsContentTypeCorrect(r, "application/merge-patch+json")
+}
+
+func IsContentTypeCorrect(r *http.Request, expected string) bool {
 	if len(r.Header.Values("Content-Type")) == 0 {
 		return false
 	}
 
 	for contentTypeCurrentIndex, contentType := range r.Header.Values("Content-Type") {
-		if contentType == "application/json" {
+		if contentType == expected {
 			break
 		}
 		if contentTypeCurrentIndex == len(r.Header.Values("Content-Type"))-1 {
@@ -0,0 +1,101 @@
+package json_path_extractor
+
+import (
+	"strconv"
+	"strings"
+
+	jsonErrors "identity-forecaster/json-errors"
+)
+
+// Extract walks through a value decoded by encoding/json into an interface{} and returns the value located by
+// the path. The path consists of object keys separated by dots, array elements are addressed with [index]
+// (e.g. country[0].country_id). A JSON null at the end of the path is returned as nil without an error.
+func Extract(data interface{}, path string) (interface{}, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := data
+	for _, step := range steps {
+		if current == nil {
+			return nil, jsonErrors.ErrPathNotFound
+		}
+
+		if step.isIndex {
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, jsonErrors.ErrPathNotFound
+			}
+
+			if step.index >= len(arr) {
+				return nil, jsonErrors.ErrIndexOutOfRange
+			}
+
+			current = arr[step.index]
+			continue
+		}
+
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, jsonErrors.ErrPathNotFound
+		}
+
+		current, ok = obj[step.key]
+		if !ok {
+			return nil, jsonErrors.ErrPathNotFound
+		}
+	}
+
+	return current, nil
+}
+
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func parsePath(path string) ([]pathStep, error) {
+	if path == "" {
+		return nil, jsonErrors.ErrEmptyPath
+	}
+
+	steps := make([]pathStep, 0)
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, jsonErrors.ErrMalformedPath
+		}
+
+		if key != "" {
+			steps = append(steps, pathStep{key: key})
+		}
+
+		for rest != "" {
+			indexStr, after, found := strings.Cut(rest, "]")
+			if !found {
+				return nil, jsonErrors.ErrMalformedPath
+			}
+
+			index, err := strconv.Atoi(indexStr)
+			if err != nil || index < 0 {
+				return nil, jsonErrors.ErrMalformedPath
+			}
+
+			steps = append(steps, pathStep{index: index, isIndex: true})
+
+			if after == "" {
+				break
+			}
+
+			if !strings.HasPrefix(after, "[") {
+				return nil, jsonErrors.ErrMalformedPath
+			}
+
+			rest = after[1:]
+		}
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,42 @@
+package json_path_extractor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	jsonErrors "identity-forecaster/json-errors"
+)
+
+func TestExtract(t *testing.T) {
+	var body interface{}
+	err := json.Unmarshal([]byte("{\"count\": 3, \"name\": \"Dmitriy\", \"age\": null, \"country\": [{\"country_id\": \"RU\", \"probability\": 0.4}, {\"country_id\": \"UA\", \"probability\": 0.2}], \"matrix\": [[1, 2], [3, 4]]}"), &body)
+	require.NoError(t, err)
+
+	var testTable = []struct {
+		path  string
+		value interface{}
+		err   error
+	}{
+		{"name", "Dmitriy", nil},
+		{"count", float64(3), nil},
+		{"age", nil, nil},
+		{"country[0].country_id", "RU", nil},
+		{"country[1].probability", 0.2, nil},
+		{"matrix[1][0]", float64(3), nil},
+		{"country[2].country_id", nil, jsonErrors.ErrIndexOutOfRange},
+		{"surname", nil, jsonErrors.ErrPathNotFound},
+		{"name.first", nil, jsonErrors.ErrPathNotFound},
+		{"country[a]", nil, jsonErrors.ErrMalformedPath},
+		{"country[0", nil, jsonErrors.ErrMalformedPath},
+		{"country..probability", nil, jsonErrors.ErrMalformedPath},
+		{"", nil, jsonErrors.ErrEmptyPath},
+	}
+
+	for _, testCase := range testTable {
+		value, err := Extract(body, testCase.path)
+		require.ErrorIs(t, err, testCase.err, testCase.path)
+		require.Equal(t, testCase.value, value, testCase.path)
+	}
+}
@@ -0,0 +1,93 @@
+// Package phonetic builds keys that are equal for the spellings of a name which sound alike, including
+// Cyrillic spellings and their transliterations: Dmitriy, Dmitry, Dmitrii and Дмитрий all get TMTR
+package phonetic
+
+import (
+	"strings"
+	"unicode"
+)
+
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i", 'й': "i",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// Transliterate lowercases the string and replaces Cyrillic letters with Latin ones, other characters are kept
+func Transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if latin, ok := cyrillic[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// digraphs are replaced before single letters, longer ones go first. The upper case results are the sounds
+// written with several letters: X for ж and ш, C for ц and ч, H for х
+var digraphs = strings.NewReplacer(
+	"shch", "X", "sch", "X", "tch", "C",
+	"sh", "X", "zh", "X", "ch", "C", "ts", "C", "tz", "C", "kh", "H", "ph", "f", "th", "t", "ck", "k",
+	"ce", "se", "ci", "si", "cy", "sy",
+)
+
+// classes merge voiced consonants with their voiceless pairs, as they are often indistinguishable at the end of
+// Russian words and in transliterations (Smirnov and Smirnoff)
+var classes = map[rune]rune{
+	'b': 'P', 'p': 'P', 'v': 'F', 'f': 'F', 'w': 'F', 'g': 'K', 'k': 'K', 'c': 'K', 'q': 'K', 'd': 'T', 't': 'T',
+	'z': 'S', 's': 'S', 'h': 'H', 'l': 'L', 'm': 'M', 'n': 'N', 'r': 'R',
+	'X': 'X', 'C': 'C', 'H': 'H',
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyj", r)
+}
+
+// Key returns the phonetic key of a word: the first letter is kept as A when it is a vowel, the rest of the vowels are
+// dropped and consonants are replaced with their classes, repeated classes are written once.
+// The key is empty when there are no letters
+func Key(word string) string {
+	letters := make([]rune, 0, len(word))
+	for _, r := range Transliterate(word) {
+		if unicode.IsLetter(r) {
+			letters = append(letters, r)
+		}
+	}
+
+	normalized := digraphs.Replace(string(letters))
+
+	var b strings.Builder
+	var last rune
+	emit := func(class rune) {
+		if class != last {
+			b.WriteRune(class)
+		}
+		last = class
+	}
+
+	for i, r := range normalized {
+		switch {
+		case isVowel(r):
+			// a vowel between the same consonants keeps them apart only in the spelling, so they are still collapsed
+			if i == 0 {
+				emit('A')
+			}
+		case r == 'x':
+			// x sounds like ks, k and s belong to different classes
+			emit('K')
+			emit('S')
+		default:
+			if class, ok := classes[r]; ok {
+				emit(class)
+			}
+		}
+	}
+
+	return b.String()
+}
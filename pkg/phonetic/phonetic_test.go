@@ -0,0 +1,39 @@
+package phonetic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	var testTable = []struct {
+		key   string
+		words []string
+	}{
+		{"TMTR", []string{"Dmitriy", "Dmitry", "Dmitrii", "Dmitrij", "Дмитрий", "ДМИТРИЙ"}},
+		{"SMRNF", []string{"Smirnov", "Smirnoff", "Смирнов", "smirnova"}},
+		{"ALKSNTR", []string{"Aleksandr", "Alexander", "Александр", "Alexandr"}},
+		{"ALKS", []string{"Alexey", "Aleksei", "Алексей", "Aleksey"}},
+		{"AR", []string{"Yuri", "Yury", "Iurii", "Юрий"}},
+		{"AFKN", []string{"Evgeny", "Yevgeniy", "Евгений", "Evgenii"}},
+		{"MHL", []string{"Mikhail", "Mihail", "Михаил"}},
+		{"CFTKF", []string{"Tsvetkov", "Цветков", "Tzvetkov"}},
+		{"XKN", []string{"Shchukin", "Щукин", "Schukin"}},
+		{"FSL", []string{"Vasiliy", "Vassily", "Василий"}},
+		{"", []string{"", "-", "ъь"}},
+	}
+
+	for _, testCase := range testTable {
+		for _, word := range testCase.words {
+			require.Equal(t, testCase.key, Key(word), word)
+		}
+	}
+
+	require.NotEqual(t, Key("Ivanov"), Key("Petrov"))
+}
+
+func TestTransliterate(t *testing.T) {
+	require.Equal(t, "shchukin-ivanova, yuliya", Transliterate("Щукин-Иванова, Юлия"))
+	require.Equal(t, "o'brien", Transliterate("O'Brien"))
+}
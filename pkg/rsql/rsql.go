@@ -0,0 +1,378 @@
+package rsql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrEmptyExpression = errors.New("expression is empty")
+	ErrUnexpectedToken = errors.New("unexpected token")
+	ErrUnexpectedEnd   = errors.New("unexpected end of expression")
+	ErrUnclosedQuote   = errors.New("quoted value is not closed")
+	ErrWrongOperator   = errors.New("comparison operator is malformed")
+	ErrTooLong         = errors.New("expression is too long")
+	ErrTooDeep         = errors.New("expression is nested too deep")
+	ErrTooManyValues   = errors.New("too many arguments of the comparison")
+)
+
+// Limits of an expression, they keep the parser from exhausting the stack and the compiled query within the bind parameters
+const (
+	// MaxLength is the maximal length of an expression in characters
+	MaxLength = 4096
+	// MaxDepth is the maximal nesting of parentheses
+	MaxDepth = 32
+	// MaxArguments is the maximal number of arguments of a single comparison
+	MaxArguments = 100
+)
+
+// Error points to the token of an expression that could not be parsed or was rejected, Position counts characters from 1
+type Error struct {
+	Position int
+	Token    string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rsql: %v at position %d: %q", e.Err, e.Position, e.Token)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error    string `json:"error"`
+		Position int    `json:"position"`
+		Token    string `json:"token"`
+	}{e.Err.Error(), e.Position, e.Token})
+}
+
+// Node is either a Logical or a Comparison
+type Node interface {
+	node()
+}
+
+type LogicalOperator string
+
+const (
+	And LogicalOperator = ";"
+	Or  LogicalOperator = ","
+)
+
+type Logical struct {
+	Operator LogicalOperator
+	Children []Node
+}
+
+// Comparison is selector operator argument(s), short operators are replaced with their FIQL form, so < becomes =lt=
+type Comparison struct {
+	Selector  Token
+	Operator  Token
+	Arguments []Token
+}
+
+func (Logical) node()    {}
+func (Comparison) node() {}
+
+// Token is a part of the expression with quotes and escapes removed and its position in characters from 1
+type Token struct {
+	Value    string
+	Position int
+}
+
+// Errorf returns an Error pointing to the token
+func (t Token) Errorf(err error) *Error {
+	return &Error{Position: t.Position, Token: t.Value, Err: err}
+}
+
+var shortOperators = map[string]string{"<": "=lt=", "<=": "=le=", ">": "=gt=", ">=": "=ge="}
+
+type tokenKind int
+
+const (
+	kindEnd tokenKind = iota
+	kindValue
+	kindOperator
+	kindOpen
+	kindClose
+	kindAnd
+	kindOr
+)
+
+type lexeme struct {
+	kind tokenKind
+	Token
+}
+
+// reserved characters can not be a part of an unquoted value
+const reserved = "\"'();,=!~<>"
+
+func lex(expression string) ([]lexeme, error) {
+	runes := []rune(expression)
+	lexemes := make([]lexeme, 0)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			lexemes = append(lexemes, lexeme{kindOpen, Token{"(", start + 1}})
+			i++
+		case r == ')':
+			lexemes = append(lexemes, lexeme{kindClose, Token{")", start + 1}})
+			i++
+		case r == ';':
+			lexemes = append(lexemes, lexeme{kindAnd, Token{";", start + 1}})
+			i++
+		case r == ',':
+			lexemes = append(lexemes, lexeme{kindOr, Token{",", start + 1}})
+			i++
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+
+			if i >= len(runes) {
+				return nil, &Error{Position: start + 1, Token: string(runes[start:]), Err: ErrUnclosedQuote}
+			}
+
+			i++
+			lexemes = append(lexemes, lexeme{kindValue, Token{b.String(), start + 1}})
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			operator, err := lexOperator(runes, start)
+			if err != nil {
+				return nil, err
+			}
+
+			i += len([]rune(operator))
+			if full, ok := shortOperators[operator]; ok {
+				operator = full
+			}
+
+			lexemes = append(lexemes, lexeme{kindOperator, Token{operator, start + 1}})
+		case r == '~':
+			return nil, &Error{Position: start + 1, Token: string(r), Err: ErrUnexpectedToken}
+		default:
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(reserved, runes[i]) {
+				i++
+			}
+
+			lexemes = append(lexemes, lexeme{kindValue, Token{string(runes[start:i]), start + 1}})
+		}
+	}
+
+	return append(lexemes, lexeme{kindEnd, Token{"", len(runes) + 1}}), nil
+}
+
+// lexOperator reads one of == != < <= > >= or =name= starting at runes[start]
+func lexOperator(runes []rune, start int) (string, error) {
+	next := func(i int) rune {
+		if i < len(runes) {
+			return runes[i]
+		}
+
+		return 0
+	}
+
+	switch runes[start] {
+	case '!':
+		if next(start+1) == '=' {
+			return "!=", nil
+		}
+	case '<', '>':
+		if next(start+1) == '=' {
+			return string(runes[start : start+2]), nil
+		}
+
+		return string(runes[start]), nil
+	default:
+		i := start + 1
+		for i < len(runes) && unicode.IsLetter(runes[i]) {
+			i++
+		}
+
+		if next(i) == '=' {
+			return string(runes[start : i+1]), nil
+		}
+	}
+
+	end := start + 1
+	for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("();,", runes[end]) {
+		end++
+	}
+
+	return "", &Error{Position: start + 1, Token: string(runes[start:end]), Err: ErrWrongOperator}
+}
+
+type parser struct {
+	lexemes []lexeme
+	i       int
+	depth   int
+}
+
+func (p *parser) peek() lexeme {
+	return p.lexemes[p.i]
+}
+
+func (p *parser) next() lexeme {
+	l := p.lexemes[p.i]
+	if l.kind != kindEnd {
+		p.i++
+	}
+
+	return l
+}
+
+func (p *parser) unexpected(l lexeme) error {
+	if l.kind == kindEnd {
+		return l.Errorf(ErrUnexpectedEnd)
+	}
+
+	return l.Errorf(ErrUnexpectedToken)
+}
+
+// Parse builds the tree of an RSQL expression, where ; is AND and , is OR, AND binds tighter and parentheses group
+func Parse(expression string) (Node, error) {
+	if runes := []rune(expression); len(runes) > MaxLength {
+		end := MaxLength + 16
+		if end > len(runes) {
+			end = len(runes)
+		}
+
+		return nil, &Error{Position: MaxLength + 1, Token: string(runes[MaxLength:end]), Err: ErrTooLong}
+	}
+
+	lexemes, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{lexemes: lexemes}
+	if p.peek().kind == kindEnd {
+		return nil, p.peek().Errorf(ErrEmptyExpression)
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if l := p.peek(); l.kind != kindEnd {
+		return nil, p.unexpected(l)
+	}
+
+	return node, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	return p.parseLogical(Or, kindOr, p.parseAnd)
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	return p.parseLogical(And, kindAnd, p.parseConstraint)
+}
+
+// parseLogical reads operands separated by the operator, a single operand is returned as is
+func (p *parser) parseLogical(operator LogicalOperator, kind tokenKind, operand func() (Node, error)) (Node, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []Node{first}
+	for p.peek().kind == kind {
+		p.next()
+
+		child, err := operand()
+		if err != nil {
+			return nil, err
+		}
+
+		children = append(children, child)
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+
+	return Logical{Operator: operator, Children: children}, nil
+}
+
+func (p *parser) parseConstraint() (Node, error) {
+	if p.peek().kind == kindOpen {
+		open := p.next()
+		if p.depth == MaxDepth {
+			return nil, open.Errorf(ErrTooDeep)
+		}
+
+		p.depth++
+		node, err := p.parseOr()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+
+		if l := p.next(); l.kind != kindClose {
+			return nil, p.unexpected(l)
+		}
+
+		return node, nil
+	}
+
+	selector := p.next()
+	if selector.kind != kindValue {
+		return nil, p.unexpected(selector)
+	}
+
+	operator := p.next()
+	if operator.kind != kindOperator {
+		return nil, p.unexpected(operator)
+	}
+
+	comparison := Comparison{Selector: selector.Token, Operator: operator.Token}
+
+	argument := p.next()
+	switch argument.kind {
+	case kindValue:
+		comparison.Arguments = []Token{argument.Token}
+	case kindOpen:
+		for {
+			value := p.next()
+			if value.kind != kindValue {
+				return nil, p.unexpected(value)
+			}
+
+			if len(comparison.Arguments) == MaxArguments {
+				return nil, value.Errorf(ErrTooManyValues)
+			}
+
+			comparison.Arguments = append(comparison.Arguments, value.Token)
+
+			separator := p.next()
+			if separator.kind == kindClose {
+				break
+			}
+
+			if separator.kind != kindOr {
+				return nil, p.unexpected(separator)
+			}
+		}
+	default:
+		return nil, p.unexpected(argument)
+	}
+
+	return comparison, nil
+}
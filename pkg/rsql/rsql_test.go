@@ -0,0 +1,87 @@
+package rsql
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	node, err := Parse(`nationality=in=(RU,"U A");(gender==female,age<30)`)
+	require.NoError(t, err)
+
+	require.Equal(t, Logical{Operator: And, Children: []Node{
+		Comparison{Selector: Token{"nationality", 1}, Operator: Token{"=in=", 12}, Arguments: []Token{{"RU", 17}, {"U A", 20}}},
+		Logical{Operator: Or, Children: []Node{
+			Comparison{Selector: Token{"gender", 28}, Operator: Token{"==", 34}, Arguments: []Token{{"female", 36}}},
+			Comparison{Selector: Token{"age", 43}, Operator: Token{"=lt=", 46}, Arguments: []Token{{"30", 47}}},
+		}},
+	}}, node)
+
+	node, err = Parse(` surname == 'O\'Brien' , name!=Дмитрий `)
+	require.NoError(t, err)
+
+	require.Equal(t, Logical{Operator: Or, Children: []Node{
+		Comparison{Selector: Token{"surname", 2}, Operator: Token{"==", 10}, Arguments: []Token{{"O'Brien", 13}}},
+		Comparison{Selector: Token{"name", 26}, Operator: Token{"!=", 30}, Arguments: []Token{{"Дмитрий", 32}}},
+	}}, node)
+
+	node, err = Parse("((age=ge=18))")
+	require.NoError(t, err)
+	require.Equal(t, Comparison{Selector: Token{"age", 3}, Operator: Token{"=ge=", 6}, Arguments: []Token{{"18", 10}}}, node)
+}
+
+func TestParseErrors(t *testing.T) {
+	var testTable = []struct {
+		expression string
+		err        error
+		position   int
+		token      string
+	}{
+		{"  ", ErrEmptyExpression, 3, ""},
+		{"name==", ErrUnexpectedEnd, 7, ""},
+		{"name=Dmitriy", ErrWrongOperator, 5, "=Dmitriy"},
+		{"name~=Dmitriy", ErrUnexpectedToken, 5, "~"},
+		{"name==Dmitriy;", ErrUnexpectedEnd, 15, ""},
+		{"name==Dmitriy)", ErrUnexpectedToken, 14, ")"},
+		{"(name==Dmitriy", ErrUnexpectedEnd, 15, ""},
+		{"name==\"Dmitriy", ErrUnclosedQuote, 7, "\"Dmitriy"},
+		{"age=in=(1,)", ErrUnexpectedToken, 11, ")"},
+		{"age=in=(1;2)", ErrUnexpectedToken, 10, ";"},
+		{"==Dmitriy", ErrUnexpectedToken, 1, "=="},
+		{"name Dmitriy", ErrUnexpectedToken, 6, "Dmitriy"},
+		{strings.Repeat("(", MaxDepth+1) + "age==1" + strings.Repeat(")", MaxDepth+1), ErrTooDeep, MaxDepth + 1, "("},
+		{"name==" + strings.Repeat("a", MaxLength), ErrTooLong, MaxLength + 1, strings.Repeat("a", 6)},
+		{"age=in=(" + strings.Repeat("1,", MaxArguments) + "1)", ErrTooManyValues, 9 + 2*MaxArguments, "1"},
+	}
+
+	for _, testCase := range testTable {
+		_, err := Parse(testCase.expression)
+		require.ErrorIs(t, err, testCase.err, testCase.expression)
+
+		var parseErr *Error
+		require.True(t, errors.As(err, &parseErr), testCase.expression)
+		require.Equal(t, testCase.position, parseErr.Position, testCase.expression)
+		require.Equal(t, testCase.token, parseErr.Token, testCase.expression)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	_, err := Parse(strings.Repeat("(", MaxDepth) + "age==1" + strings.Repeat(")", MaxDepth))
+	require.NoError(t, err)
+
+	_, err = Parse("age=in=(" + strings.Repeat("1,", MaxArguments-1) + "1)")
+	require.NoError(t, err)
+
+	_, err = Parse(strings.Repeat("(", 400000) + "age==1" + strings.Repeat(")", 400000))
+	require.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestErrorJSON(t *testing.T) {
+	b, err := json.Marshal(Token{"password", 1}.Errorf(ErrUnexpectedToken))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error": "unexpected token", "position": 1, "token": "password"}`, string(b))
+}
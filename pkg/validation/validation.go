@@ -0,0 +1,306 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Codes of the violations, they are stable for the clients to match on unlike the messages
+const (
+	CodeRequired    = "required"
+	CodeTooShort    = "too_short"
+	CodeTooLong     = "too_long"
+	CodeTooSmall    = "too_small"
+	CodeTooLarge    = "too_large"
+	CodeNotAllowed  = "not_allowed"
+	CodeWrongScript = "wrong_script"
+	CodeCountryCode = "wrong_country_code"
+)
+
+// Violation is a rule a field does not follow, Field is the name of the field in JSON
+type Violation struct {
+	Field   string `json:"field" example:"age"`
+	Code    string `json:"code" example:"too_small"`
+	Message string `json:"message" example:"must be at least 0"`
+}
+
+// Errors are all the violations found in a value
+type Errors []Violation
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, v := range e {
+		messages = append(messages, v.Field+": "+v.Message)
+	}
+
+	return "validation: " + strings.Join(messages, "; ")
+}
+
+func (e Errors) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Errors []Violation `json:"errors"`
+	}{[]Violation(e)})
+}
+
+// rule checks a non-zero value of a field, a zero value is only checked by required
+type rule func(v reflect.Value) *Violation
+
+type field struct {
+	index    int
+	name     string
+	required bool
+	rules    []rule
+}
+
+var fieldsByType sync.Map
+
+// Struct checks the fields of a struct by the rules in their validate tags and returns Errors with all the violations or nil.
+// Rules are separated by commas:
+//   - required - the value is not zero, other rules are checked only for non-zero values
+//   - min=N and max=N - bounds of a number or of the length of a string in characters
+//   - oneof=a b c - the string is one of the values
+//   - script=Latin Cyrillic - the string is words of letters of one of the unicode scripts separated by single spaces,
+//     hyphens or apostrophes
+//   - iso3166 - the string is an ISO 3166-1 alpha-2 code in upper case
+//
+// A malformed tag is a programming error, so Struct panics on it
+func Struct(v any) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+
+	var violations Errors
+	for _, f := range fieldsOf(value.Type()) {
+		fieldValue := value.Field(f.index)
+
+		if fieldValue.IsZero() {
+			if f.required {
+				violations = append(violations, Violation{Field: f.name, Code: CodeRequired, Message: "is required"})
+			}
+
+			continue
+		}
+
+		for _, check := range f.rules {
+			if violation := check(fieldValue); violation != nil {
+				violation.Field = f.name
+				violations = append(violations, *violation)
+				break
+			}
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return violations
+}
+
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := fieldsByType.Load(t); ok {
+		return cached.([]field)
+	}
+
+	fields := make([]field, 0)
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		tag, ok := structField.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+
+		f := field{index: i, name: jsonName(structField)}
+		for _, definition := range strings.Split(tag, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(definition), "=")
+			if name == "required" {
+				f.required = true
+				continue
+			}
+
+			f.rules = append(f.rules, newRule(name, arg, structField))
+		}
+
+		fields = append(fields, f)
+	}
+
+	fieldsByType.Store(t, fields)
+	return fields
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+
+	return name
+}
+
+func newRule(name string, arg string, f reflect.StructField) rule {
+	switch name {
+	case "min", "max":
+		bound, err := strconv.Atoi(arg)
+		if err != nil {
+			panic(fmt.Sprintf("validation: bound %q of field %s is not a number", arg, f.Name))
+		}
+
+		return boundRule(name == "min", bound)
+	case "oneof":
+		return oneOfRule(strings.Fields(arg))
+	case "script":
+		return scriptRule(strings.Fields(arg), f)
+	case "iso3166":
+		return countryCodeRule
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q of field %s", name, f.Name))
+	}
+}
+
+func boundRule(isMin bool, bound int) rule {
+	return func(v reflect.Value) *Violation {
+		var actual int64
+		unit := ""
+
+		switch v.Kind() {
+		case reflect.String:
+			actual, unit = int64(utf8.RuneCountInString(v.String())), " characters"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			actual = v.Int()
+		default:
+			return nil
+		}
+
+		switch {
+		case isMin && actual < int64(bound) && unit != "":
+			return &Violation{Code: CodeTooShort, Message: fmt.Sprintf("must be at least %d%s long", bound, unit)}
+		case isMin && actual < int64(bound):
+			return &Violation{Code: CodeTooSmall, Message: fmt.Sprintf("must be at least %d", bound)}
+		case !isMin && actual > int64(bound) && unit != "":
+			return &Violation{Code: CodeTooLong, Message: fmt.Sprintf("must be at most %d%s long", bound, unit)}
+		case !isMin && actual > int64(bound):
+			return &Violation{Code: CodeTooLarge, Message: fmt.Sprintf("must be at most %d", bound)}
+		}
+
+		return nil
+	}
+}
+
+func oneOfRule(allowed []string) rule {
+	return func(v reflect.Value) *Violation {
+		for _, value := range allowed {
+			if v.String() == value {
+				return nil
+			}
+		}
+
+		return &Violation{Code: CodeNotAllowed, Message: "must be one of: " + strings.Join(allowed, ", ")}
+	}
+}
+
+// nameSeparators may stand between the words of a name, like in "Mamin-Sibiryak" or "O'Brien"
+const nameSeparators = " -'’"
+
+func scriptRule(names []string, f reflect.StructField) rule {
+	scripts := make([]*unicode.RangeTable, 0, len(names))
+	for _, name := range names {
+		script, ok := unicode.Scripts[name]
+		if !ok {
+			panic(fmt.Sprintf("validation: unknown script %q of field %s", name, f.Name))
+		}
+
+		scripts = append(scripts, script)
+	}
+
+	message := fmt.Sprintf("must consist of letters of one script of %s separated by single spaces, hyphens or apostrophes",
+		strings.Join(names, ", "))
+
+	return func(v reflect.Value) *Violation {
+		if !isWrittenIn(v.String(), scripts) {
+			return &Violation{Code: CodeWrongScript, Message: message}
+		}
+
+		return nil
+	}
+}
+
+func isWrittenIn(s string, scripts []*unicode.RangeTable) bool {
+	var script *unicode.RangeTable
+	afterLetter := false
+
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			if script == nil {
+				for _, candidate := range scripts {
+					if unicode.Is(candidate, r) {
+						script = candidate
+						break
+					}
+				}
+			}
+
+			if script == nil || !unicode.Is(script, r) {
+				return false
+			}
+
+			afterLetter = true
+		case unicode.Is(unicode.Mn, r) && afterLetter:
+			// combining marks like the accent of "й" written in two code points
+		case strings.ContainsRune(nameSeparators, r) && afterLetter:
+			afterLetter = false
+		default:
+			return false
+		}
+	}
+
+	return afterLetter
+}
+
+func countryCodeRule(v reflect.Value) *Violation {
+	if _, ok := countryCodes[v.String()]; !ok {
+		return &Violation{Code: CodeCountryCode, Message: "must be an ISO 3166-1 alpha-2 code in upper case, e.g. RU"}
+	}
+
+	return nil
+}
+
+// countryCodes are the officially assigned ISO 3166-1 alpha-2 codes
+var countryCodes = func() map[string]struct{} {
+	codes := make(map[string]struct{})
+	for _, code := range strings.Fields(`
+		AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ
+		BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+		CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ
+		DE DJ DK DM DO DZ
+		EC EE EG EH ER ES ET
+		FI FJ FK FM FO FR
+		GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY
+		HK HM HN HR HT HU
+		ID IE IL IM IN IO IQ IR IS IT
+		JE JM JO JP
+		KE KG KH KI KM KN KP KR KW KY KZ
+		LA LB LC LI LK LR LS LT LU LV LY
+		MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ
+		NA NC NE NF NG NI NL NO NP NR NU NZ
+		OM
+		PA PE PF PG PH PK PL PM PN PR PS PT PW PY
+		QA
+		RE RO RS RU RW
+		SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ
+		TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ
+		UA UG UM US UY UZ
+		VA VC VE VG VI VN VU
+		WF WS
+		YE YT
+		ZA ZM ZW`) {
+		codes[code] = struct{}{}
+	}
+
+	return codes
+}()
@@ -0,0 +1,91 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPerson struct {
+	Name        string `json:"name" validate:"required,max=10,script=Latin Cyrillic"`
+	Nickname    string `json:"nickname,omitempty" validate:"min=3"`
+	Age         int    `json:"age" validate:"min=0,max=150"`
+	Gender      string `json:"gender" validate:"oneof=male female"`
+	Nationality string `json:"nationality" validate:"iso3166"`
+	Comment     string `json:"comment"`
+}
+
+func TestStruct(t *testing.T) {
+	var testTable = []struct {
+		person testPerson
+		codes  map[string]string
+	}{
+		{testPerson{Name: "Dmitriy", Age: 42, Gender: "male", Nationality: "RU", Comment: "123"}, nil},
+		{testPerson{Name: "Дмитрий"}, nil},
+		{testPerson{Name: "Mamin-Sibir"}, map[string]string{"name": CodeTooLong}},
+		{testPerson{Name: "O'Brien"}, nil},
+		{testPerson{Name: "Anna Maria"}, nil},
+		{testPerson{Name: "Й"}, nil},
+		{testPerson{Name: "И\u0306"}, nil},
+		{testPerson{}, map[string]string{"name": CodeRequired}},
+		{testPerson{Name: "Dm1triy"}, map[string]string{"name": CodeWrongScript}},
+		{testPerson{Name: "Dmitriу"}, map[string]string{"name": CodeWrongScript}},
+		{testPerson{Name: "Ελένη"}, map[string]string{"name": CodeWrongScript}},
+		{testPerson{Name: "Ann  Mary"}, map[string]string{"name": CodeWrongScript}},
+		{testPerson{Name: "-Anna"}, map[string]string{"name": CodeWrongScript}},
+		{testPerson{Name: "Anna-"}, map[string]string{"name": CodeWrongScript}},
+		{testPerson{Name: "Anna", Nickname: "An"}, map[string]string{"nickname": CodeTooShort}},
+		{
+			testPerson{Name: "Anna", Age: -3, Gender: "banana", Nationality: "Russia"},
+			map[string]string{"age": CodeTooSmall, "gender": CodeNotAllowed, "nationality": CodeCountryCode},
+		},
+		{testPerson{Name: "Anna", Age: 151, Nationality: "ru"}, map[string]string{"age": CodeTooLarge, "nationality": CodeCountryCode}},
+		{testPerson{Name: strings.Repeat("a", 5000), Nationality: "XX"}, map[string]string{"name": CodeTooLong, "nationality": CodeCountryCode}},
+	}
+
+	for _, testCase := range testTable {
+		err := Struct(testCase.person)
+		if testCase.codes == nil {
+			require.NoError(t, err, testCase.person.Name)
+			continue
+		}
+
+		var violations Errors
+		require.True(t, errors.As(err, &violations), testCase.person.Name)
+
+		codes := make(map[string]string)
+		for _, violation := range violations {
+			codes[violation.Field] = violation.Code
+			require.NotEmpty(t, violation.Message)
+		}
+
+		require.Equal(t, testCase.codes, codes, testCase.person.Name)
+	}
+}
+
+func TestErrorsJSON(t *testing.T) {
+	err := Struct(&testPerson{Age: -1})
+
+	b, jsonErr := json.Marshal(err)
+	require.NoError(t, jsonErr)
+	require.JSONEq(t, `{"errors": [{"field": "name", "code": "required", "message": "is required"},
+		{"field": "age", "code": "too_small", "message": "must be at least 0"}]}`, string(b))
+	require.Equal(t, "validation: name: is required; age: must be at least 0", err.Error())
+}
+
+func TestMalformedTag(t *testing.T) {
+	require.Panics(t, func() {
+		_ = Struct(struct {
+			Name string `validate:"lowercase"`
+		}{"a"})
+	})
+
+	require.Panics(t, func() {
+		_ = Struct(struct {
+			Name string `validate:"script=Klingon"`
+		}{"a"})
+	})
+}
@@ -0,0 +1,461 @@
+package vcard
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the length of a content line after which it is folded, see RFC 6350 section 3.2
+const maxLineOctets = 75
+
+var (
+	ErrMalformedLine = errors.New("content line is malformed")
+	ErrNotStarted    = errors.New("content line outside of BEGIN:VCARD and END:VCARD")
+	ErrNotFinished   = errors.New("vCard is not finished with END:VCARD")
+)
+
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("vcard: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Property is a single content line, Value is kept escaped as it is on the wire, use Text or Structured to read it
+type Property struct {
+	Group  string
+	Name   string
+	Params map[string][]string
+	Value  string
+}
+
+// Text returns the value of a single text property with escaping removed
+func (p Property) Text() string {
+	return UnescapeText(p.Value)
+}
+
+// Structured returns the components of a structured value like N, each with escaping removed
+func (p Property) Structured() []string {
+	components := splitUnescaped(p.Value, ';')
+	for i := range components {
+		components[i] = UnescapeText(components[i])
+	}
+
+	return components
+}
+
+type Card struct {
+	// Line is the line of BEGIN:VCARD for decoded cards
+	Line       int
+	Properties []Property
+}
+
+// Get returns the first property with the name, names are case-insensitive
+func (c Card) Get(name string) (Property, bool) {
+	for _, p := range c.Properties {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+
+	return Property{}, false
+}
+
+func (c *Card) AddText(name string, value string) {
+	c.Properties = append(c.Properties, Property{Name: name, Value: EscapeText(value)})
+}
+
+func (c *Card) AddStructured(name string, components ...string) {
+	escaped := make([]string, len(components))
+	for i, component := range components {
+		escaped[i] = EscapeText(component)
+	}
+
+	c.Properties = append(c.Properties, Property{Name: name, Value: strings.Join(escaped, ";")})
+}
+
+// EscapeText escapes a text value per RFC 6350 section 3.4
+func EscapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString("\\\\")
+		case ',':
+			b.WriteString("\\,")
+		case ';':
+			b.WriteString("\\;")
+		case '\n':
+			b.WriteString("\\n")
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// EscapeParam escapes a parameter value per RFC 6868, so that it may hold carets, line breaks and double quotes
+func EscapeParam(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '^':
+			b.WriteString("^^")
+		case '\n':
+			b.WriteString("^n")
+		case '"':
+			b.WriteString("^'")
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// UnescapeParam reverts EscapeParam, a caret before any other character is kept as is
+func UnescapeParam(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if !escaped && r == '^' {
+			escaped = true
+			continue
+		}
+
+		if escaped {
+			switch r {
+			case '^':
+			case 'n', 'N':
+				r = '\n'
+			case '\'':
+				r = '"'
+			default:
+				b.WriteRune('^')
+			}
+		}
+
+		escaped = false
+		b.WriteRune(r)
+	}
+
+	if escaped {
+		b.WriteRune('^')
+	}
+
+	return b.String()
+}
+
+// UnescapeText reverts EscapeText, unknown escapes keep the escaped character
+func UnescapeText(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if !escaped && r == '\\' {
+			escaped = true
+			continue
+		}
+
+		if escaped && (r == 'n' || r == 'N') {
+			r = '\n'
+		}
+
+		escaped = false
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func splitUnescaped(s string, sep byte) []string {
+	parts := make([]string, 0)
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+
+		if s[i] == sep {
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+type Decoder struct {
+	r        *bufio.Reader
+	line     int
+	next     string
+	nextLine int
+	hasNext  bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// readPhysical reads a line without its line break, CRLF and LF are both accepted
+func (d *Decoder) readPhysical() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+
+	d.line++
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readLogical returns an unfolded content line and the number of the physical line it starts on
+func (d *Decoder) readLogical() (string, int, error) {
+	if !d.hasNext {
+		line, err := d.readPhysical()
+		if err != nil {
+			return "", 0, err
+		}
+
+		d.next, d.nextLine, d.hasNext = line, d.line, true
+	}
+
+	current, currentLine := d.next, d.nextLine
+	for {
+		line, err := d.readPhysical()
+		if errors.Is(err, io.EOF) {
+			d.hasNext = false
+			return current, currentLine, nil
+		}
+
+		if err != nil {
+			return "", 0, err
+		}
+
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			current += line[1:]
+			continue
+		}
+
+		d.next, d.nextLine = line, d.line
+		return current, currentLine, nil
+	}
+}
+
+// Decode reads the next vCard, io.EOF is returned when there are no more cards
+func (d *Decoder) Decode() (Card, error) {
+	var card Card
+	started := false
+
+	for {
+		line, lineNumber, err := d.readLogical()
+		if errors.Is(err, io.EOF) {
+			if started {
+				return Card{}, &ParseError{Line: d.line, Err: ErrNotFinished}
+			}
+
+			return Card{}, io.EOF
+		}
+
+		if err != nil {
+			return Card{}, err
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		p, err := parseLine(line)
+		if err != nil {
+			return Card{}, &ParseError{Line: lineNumber, Err: err}
+		}
+
+		switch {
+		case strings.EqualFold(p.Name, "BEGIN") && strings.EqualFold(p.Value, "VCARD"):
+			if started {
+				return Card{}, &ParseError{Line: lineNumber, Err: ErrNotFinished}
+			}
+
+			started = true
+			card.Line = lineNumber
+		case !started:
+			return Card{}, &ParseError{Line: lineNumber, Err: ErrNotStarted}
+		case strings.EqualFold(p.Name, "END") && strings.EqualFold(p.Value, "VCARD"):
+			return card, nil
+		default:
+			card.Properties = append(card.Properties, p)
+		}
+	}
+}
+
+// parseLine splits [group.]name *(;param) : value, parameter values may be quoted
+func parseLine(line string) (Property, error) {
+	var p Property
+
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, ErrMalformedLine
+	}
+
+	p.Name = line[:i]
+	if dot := strings.IndexByte(p.Name, '.'); dot >= 0 {
+		p.Group, p.Name = p.Name[:dot], p.Name[dot+1:]
+	}
+
+	if p.Name == "" {
+		return p, ErrMalformedLine
+	}
+
+	for line[i] == ';' {
+		i++
+		eq := strings.IndexByte(line[i:], '=')
+		if eq <= 0 {
+			return p, ErrMalformedLine
+		}
+
+		name := strings.ToUpper(line[i : i+eq])
+		i += eq + 1
+
+		for {
+			var value string
+			if i < len(line) && line[i] == '"' {
+				end := strings.IndexByte(line[i+1:], '"')
+				if end < 0 {
+					return p, ErrMalformedLine
+				}
+
+				value = line[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				end := strings.IndexAny(line[i:], ",;:")
+				if end < 0 {
+					return p, ErrMalformedLine
+				}
+
+				value = line[i : i+end]
+				i += end
+			}
+
+			if p.Params == nil {
+				p.Params = make(map[string][]string)
+			}
+			p.Params[name] = append(p.Params[name], UnescapeParam(value))
+
+			if i >= len(line) {
+				return p, ErrMalformedLine
+			}
+
+			if line[i] != ',' {
+				break
+			}
+			i++
+		}
+
+		if line[i] != ';' && line[i] != ':' {
+			return p, ErrMalformedLine
+		}
+	}
+
+	p.Value = line[i+1:]
+	return p, nil
+}
+
+type Encoder struct {
+	w io.Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes a vCard 4.0 with CRLF line breaks, folding lines longer than 75 octets without breaking UTF-8 sequences
+func (e *Encoder) Encode(card Card) error {
+	lines := make([]string, 0, len(card.Properties)+3)
+	lines = append(lines, "BEGIN:VCARD", "VERSION:4.0")
+
+	for _, p := range card.Properties {
+		if strings.EqualFold(p.Name, "VERSION") {
+			continue
+		}
+
+		lines = append(lines, formatLine(p))
+	}
+
+	lines = append(lines, "END:VCARD")
+
+	var b strings.Builder
+	for _, line := range lines {
+		fold(&b, line)
+	}
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func formatLine(p Property) string {
+	var b strings.Builder
+	if p.Group != "" {
+		b.WriteString(p.Group)
+		b.WriteByte('.')
+	}
+	b.WriteString(p.Name)
+
+	names := make([]string, 0, len(p.Params))
+	for name := range p.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		b.WriteByte(';')
+		b.WriteString(name)
+		b.WriteByte('=')
+		for i, value := range p.Params[name] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+
+			value = EscapeParam(value)
+			if strings.ContainsAny(value, ",;:") {
+				value = "\"" + value + "\""
+			}
+			b.WriteString(value)
+		}
+	}
+
+	b.WriteByte(':')
+	b.WriteString(p.Value)
+	return b.String()
+}
+
+func fold(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts towards its length
+		limit = maxLineOctets - 1
+	}
+
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
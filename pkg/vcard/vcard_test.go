@@ -0,0 +1,136 @@
+package vcard
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"N:Smirnov;Dmitriy;Petrovich;;\r\n" +
+		"FN:Dmitriy Petrovich\r\n" +
+		"  Smirnov\r\n" +
+		"item1.NOTE;LANGUAGE=ru;TYPE=\"work,home\":Line one\\nline two\\, with comma\\; and semicolon\r\n" +
+		"END:VCARD\n" +
+		"\n" +
+		"BEGIN:VCARD\n" +
+		"N:Иванова\\;Петрова;Анна;;;\n" +
+		"END:VCARD\n"
+
+	d := NewDecoder(strings.NewReader(input))
+
+	card, err := d.Decode()
+	require.NoError(t, err)
+	require.Equal(t, 1, card.Line)
+
+	n, ok := card.Get("n")
+	require.True(t, ok)
+	require.Equal(t, []string{"Smirnov", "Dmitriy", "Petrovich", "", ""}, n.Structured())
+
+	fn, ok := card.Get("FN")
+	require.True(t, ok)
+	require.Equal(t, "Dmitriy Petrovich Smirnov", fn.Text())
+
+	note, ok := card.Get("NOTE")
+	require.True(t, ok)
+	require.Equal(t, "item1", note.Group)
+	require.Equal(t, map[string][]string{"LANGUAGE": {"ru"}, "TYPE": {"work,home"}}, note.Params)
+	require.Equal(t, "Line one\nline two, with comma; and semicolon", note.Text())
+
+	card, err = d.Decode()
+	require.NoError(t, err)
+	require.Equal(t, 9, card.Line)
+
+	n, ok = card.Get("N")
+	require.True(t, ok)
+	require.Equal(t, []string{"Иванова;Петрова", "Анна", "", "", ""}, n.Structured())
+
+	_, err = d.Decode()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var testTable = []struct {
+		input string
+		line  int
+		err   error
+	}{
+		{"N:Smirnov;Dmitriy\r\n", 1, ErrNotStarted},
+		{"BEGIN:VCARD\r\nN:Smirnov;Dmitriy\r\n", 2, ErrNotFinished},
+		{"BEGIN:VCARD\r\nBEGIN:VCARD\r\n", 2, ErrNotFinished},
+		{"BEGIN:VCARD\r\nno colon here\r\nEND:VCARD\r\n", 2, ErrMalformedLine},
+		{"BEGIN:VCARD\r\nNOTE;LANGUAGE:ru\r\nEND:VCARD\r\n", 2, ErrMalformedLine},
+		{"BEGIN:VCARD\r\nNOTE;TYPE=\"work:ru\r\nEND:VCARD\r\n", 2, ErrMalformedLine},
+	}
+
+	for _, testCase := range testTable {
+		_, err := NewDecoder(strings.NewReader(testCase.input)).Decode()
+		require.ErrorIs(t, err, testCase.err, testCase.input)
+
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), testCase.input)
+		require.Equal(t, testCase.line, parseErr.Line, testCase.input)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var card Card
+	card.AddStructured("N", "Smirnov", "Dmitriy", "Petrovich", "", "")
+	card.AddText("FN", "Dmitriy Petrovich Smirnov")
+	card.AddText("NOTE", "a, b; c\\d\ne")
+	card.Properties = append(card.Properties, Property{Name: "GENDER", Value: "M"},
+		Property{Name: "TEL", Params: map[string][]string{"VALUE": {"uri"}, "TYPE": {"work", "voice"}}, Value: "tel:+1-555-555-5555"})
+	card.AddText("X-LONG", strings.Repeat("Ж", 60))
+
+	var b strings.Builder
+	require.NoError(t, NewEncoder(&b).Encode(card))
+
+	encoded := b.String()
+	for _, line := range strings.Split(strings.TrimSuffix(encoded, "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), maxLineOctets)
+	}
+
+	require.True(t, strings.HasPrefix(encoded, "BEGIN:VCARD\r\nVERSION:4.0\r\nN:Smirnov;Dmitriy;Petrovich;;\r\n"+
+		"FN:Dmitriy Petrovich Smirnov\r\nNOTE:a\\, b\\; c\\\\d\\ne\r\nGENDER:M\r\nTEL;TYPE=work,voice;VALUE=uri:tel:+1-555-555-5555\r\n"))
+	require.True(t, strings.HasSuffix(encoded, "END:VCARD\r\n"))
+
+	decoded, err := NewDecoder(strings.NewReader(encoded)).Decode()
+	require.NoError(t, err)
+
+	long, ok := decoded.Get("X-LONG")
+	require.True(t, ok)
+	require.Equal(t, strings.Repeat("Ж", 60), long.Text())
+
+	note, ok := decoded.Get("NOTE")
+	require.True(t, ok)
+	require.Equal(t, "a, b; c\\d\ne", note.Text())
+}
+
+func TestParamEscaping(t *testing.T) {
+	var card Card
+	card.Properties = append(card.Properties, Property{Name: "ADR", Params: map[string][]string{"LABEL": {"\"Home\" ^ 1\nMain St, 2"}},
+		Value: ";;Main St"})
+
+	var b strings.Builder
+	require.NoError(t, NewEncoder(&b).Encode(card))
+	require.Contains(t, b.String(), "ADR;LABEL=\"^'Home^' ^^ 1^nMain St, 2\":;;Main St\r\n")
+
+	decoded, err := NewDecoder(strings.NewReader(b.String())).Decode()
+	require.NoError(t, err)
+
+	adr, ok := decoded.Get("ADR")
+	require.True(t, ok)
+	require.Equal(t, map[string][]string{"LABEL": {"\"Home\" ^ 1\nMain St, 2"}}, adr.Params)
+
+	decoded, err = NewDecoder(strings.NewReader("BEGIN:VCARD\r\nNOTE;X-TAG=a^b^N^'c^:text\r\nEND:VCARD\r\n")).Decode()
+	require.NoError(t, err)
+
+	note, ok := decoded.Get("NOTE")
+	require.True(t, ok)
+	require.Equal(t, map[string][]string{"X-TAG": {"a^b\n\"c^"}}, note.Params)
+}
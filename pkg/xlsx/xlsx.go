@@ -0,0 +1,346 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var (
+	ErrNoSheetsLeft       = errors.New("all the sheets of the workbook are already written")
+	ErrSheetNotStarted    = errors.New("no sheet is started")
+	ErrUnsupportedCell    = errors.New("unsupported type of a cell value")
+	ErrInvalidSheetName   = errors.New("sheet name is empty, too long or contains forbidden characters")
+	ErrDuplicateSheetName = errors.New("sheet names must be unique")
+	ErrTooManyRows        = errors.New("a sheet holds at most 1048576 rows")
+)
+
+// MaxRows is the number of rows of a sheet, including the header, spreadsheet applications do not open longer sheets
+const MaxRows = 1 << 20
+
+const (
+	styleDefault = 0
+	styleHeader  = 1
+
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`%s</Types>`
+	sheetContentType = `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`
+
+	rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">%s` +
+		`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	sheetRel = `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`
+
+	// the second cell format makes header cells bold
+	styles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`
+
+	sheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
+	frozenHeader = `<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+		`</sheetView></sheetViews>`
+)
+
+// Writer streams a workbook: sheets are written one after another, row by row, without keeping the rows in memory.
+// Strings are stored inline, so no shared strings table has to be collected before the sheets.
+type Writer struct {
+	zip    *zip.Writer
+	sheets []string
+
+	current    int
+	sheet      io.Writer
+	rows       int
+	columns    int
+	autoFilter bool
+	filters    map[int]string
+}
+
+func NewWriter(w io.Writer, sheets ...string) (*Writer, error) {
+	seen := make(map[string]bool, len(sheets))
+	for _, name := range sheets {
+		if !isValidSheetName(name) {
+			return nil, ErrInvalidSheetName
+		}
+
+		if seen[name] {
+			return nil, ErrDuplicateSheetName
+		}
+
+		seen[name] = true
+	}
+
+	xw := &Writer{zip: zip.NewWriter(w), sheets: sheets, current: -1, filters: make(map[int]string)}
+
+	var overrides, rels bytes.Buffer
+	for i := range sheets {
+		fmt.Fprintf(&overrides, sheetContentType, i+1)
+		fmt.Fprintf(&rels, sheetRel, i+1, i+1)
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", fmt.Sprintf(contentTypes, overrides.String())},
+		{"_rels/.rels", rootRels},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(workbookRels, rels.String())},
+		{"xl/styles.xml", styles},
+	}
+
+	for _, part := range parts {
+		if err := xw.writePart(part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	return xw, nil
+}
+
+// NextSheet finishes the current sheet and starts the next one, a frozen header row is set up when withHeader is true
+func (w *Writer) NextSheet(withHeader bool) error {
+	if err := w.finishSheet(); err != nil {
+		return err
+	}
+
+	if w.current+1 >= len(w.sheets) {
+		return ErrNoSheetsLeft
+	}
+
+	w.current++
+
+	sheet, err := w.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", w.current+1))
+	if err != nil {
+		return err
+	}
+
+	w.sheet, w.rows, w.columns, w.autoFilter = sheet, 0, 0, false
+
+	start := sheetStart
+	if withHeader {
+		start += frozenHeader
+	}
+
+	_, err = io.WriteString(w.sheet, start+"<sheetData>")
+	return err
+}
+
+// SetAutoFilter adds an autofilter over all the rows and columns of the current sheet once it is finished
+func (w *Writer) SetAutoFilter() {
+	w.autoFilter = true
+}
+
+// WriteHeader writes a row of bold strings
+func (w *Writer) WriteHeader(titles ...string) error {
+	cells := make([]interface{}, len(titles))
+	for i, title := range titles {
+		cells[i] = title
+	}
+
+	return w.writeRow(styleHeader, cells)
+}
+
+// WriteRow writes a row of cells, strings become text cells, integers and floats become numeric cells and nil leaves a cell empty
+func (w *Writer) WriteRow(cells ...interface{}) error {
+	return w.writeRow(styleDefault, cells)
+}
+
+func (w *Writer) writeRow(style int, cells []interface{}) error {
+	if w.sheet == nil {
+		return ErrSheetNotStarted
+	}
+
+	if w.rows >= MaxRows {
+		return ErrTooManyRows
+	}
+
+	w.rows++
+	if len(cells) > w.columns {
+		w.columns = len(cells)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<row r="%d">`, w.rows)
+
+	for i, cell := range cells {
+		ref := ColumnName(i) + strconv.Itoa(w.rows)
+
+		styleAttr := ""
+		if style != styleDefault {
+			styleAttr = fmt.Sprintf(` s="%d"`, style)
+		}
+
+		switch v := cell.(type) {
+		case nil:
+			continue
+		case string:
+			fmt.Fprintf(&b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">`, ref, styleAttr)
+			if err := xml.EscapeText(&b, []byte(v)); err != nil {
+				return err
+			}
+			b.WriteString(`</t></is></c>`)
+		case int:
+			fmt.Fprintf(&b, `<c r="%s"%s><v>%d</v></c>`, ref, styleAttr, v)
+		case int64:
+			fmt.Fprintf(&b, `<c r="%s"%s><v>%d</v></c>`, ref, styleAttr, v)
+		case float64:
+			fmt.Fprintf(&b, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, strconv.FormatFloat(v, 'g', -1, 64))
+		default:
+			return ErrUnsupportedCell
+		}
+	}
+
+	b.WriteString(`</row>`)
+
+	_, err := w.sheet.Write(b.Bytes())
+	return err
+}
+
+func (w *Writer) finishSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+
+	end := "</sheetData>"
+	if w.autoFilter && w.rows > 0 && w.columns > 0 {
+		ref := "A1:" + ColumnName(w.columns-1) + strconv.Itoa(w.rows)
+		end += `<autoFilter ref="` + ref + `"/>`
+		w.filters[w.current] = "$A$1:$" + ColumnName(w.columns-1) + "$" + strconv.Itoa(w.rows)
+	}
+	end += "</worksheet>"
+
+	_, err := io.WriteString(w.sheet, end)
+	w.sheet = nil
+
+	return err
+}
+
+// Close finishes the current sheet, leaves the sheets which were not started empty and writes the workbook itself,
+// which goes last as it refers to the autofilter ranges known only after the rows are written
+func (w *Writer) Close() error {
+	if err := w.finishSheet(); err != nil {
+		return err
+	}
+
+	for w.current+1 < len(w.sheets) {
+		if err := w.NextSheet(false); err != nil {
+			return err
+		}
+
+		if err := w.finishSheet(); err != nil {
+			return err
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+
+	for i, name := range w.sheets {
+		b.WriteString(`<sheet name="`)
+		if err := xml.EscapeText(&b, []byte(name)); err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+	}
+	b.WriteString(`</sheets>`)
+
+	if len(w.filters) != 0 {
+		b.WriteString(`<definedNames>`)
+		for i, name := range w.sheets {
+			ref, ok := w.filters[i]
+			if !ok {
+				continue
+			}
+
+			fmt.Fprintf(&b, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">`, i)
+			if err := xml.EscapeText(&b, []byte(quoteSheetName(name)+"!"+ref)); err != nil {
+				return err
+			}
+			b.WriteString(`</definedName>`)
+		}
+		b.WriteString(`</definedNames>`)
+	}
+
+	b.WriteString(`</workbook>`)
+
+	if err := w.writePart("xl/workbook.xml", b.String()); err != nil {
+		return err
+	}
+
+	return w.zip.Close()
+}
+
+// quoteSheetName quotes a sheet name for use in a formula or a defined name, doubling the quotes inside
+func quoteSheetName(name string) string {
+	quoted := "'"
+	for _, r := range name {
+		if r == '\'' {
+			quoted += "''"
+			continue
+		}
+
+		quoted += string(r)
+	}
+
+	return quoted + "'"
+}
+
+func isValidSheetName(name string) bool {
+	if name == "" || len([]rune(name)) > 31 {
+		return false
+	}
+
+	for _, r := range name {
+		switch r {
+		case ':', '\\', '/', '?', '*', '[', ']':
+			return false
+		}
+	}
+
+	return true
+}
+
+// ColumnName converts a zero based column index to its letters: 0 is A, 25 is Z, 26 is AA
+func ColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
+
+func (w *Writer) writePart(name string, content string) error {
+	part, err := w.zip.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(part, content)
+	return err
+}
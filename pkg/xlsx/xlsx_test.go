@@ -0,0 +1,120 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readParts(t *testing.T, content []byte) map[string]string {
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	parts := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+
+		// every part has to be well-formed XML
+		d := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			_, err = d.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, f.Name)
+		}
+
+		parts[f.Name] = string(b)
+	}
+
+	return parts
+}
+
+func TestWriter(t *testing.T) {
+	var b bytes.Buffer
+
+	w, err := NewWriter(&b, "Persons", "O'Brien stats", "Empty")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, w.WriteRow("no sheet"), ErrSheetNotStarted)
+
+	require.NoError(t, w.NextSheet(true))
+	w.SetAutoFilter()
+	require.NoError(t, w.WriteHeader("id", "name", "age"))
+	require.NoError(t, w.WriteRow(1, "Dmitriy <&> Smirnov", 42))
+	require.NoError(t, w.WriteRow(2, " Анна ", nil))
+
+	require.NoError(t, w.NextSheet(false))
+	require.NoError(t, w.WriteRow("average age", 42.5))
+	require.ErrorIs(t, w.WriteRow(true), ErrUnsupportedCell)
+
+	require.NoError(t, w.Close())
+
+	parts := readParts(t, b.Bytes())
+	require.Len(t, parts, 8)
+
+	require.Contains(t, parts["[Content_Types].xml"], `PartName="/xl/worksheets/sheet3.xml"`)
+	require.Contains(t, parts["xl/workbook.xml"], `<sheet name="O&#39;Brien stats" sheetId="2" r:id="rId2"/>`)
+	require.Contains(t, parts["xl/workbook.xml"], `<definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">&#39;Persons&#39;!$A$1:$C$3</definedName>`)
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	require.Contains(t, sheet, `state="frozen"`)
+	require.Contains(t, sheet, `<row r="1"><c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	require.Contains(t, sheet, `<row r="2"><c r="A2"><v>1</v></c><c r="B2" t="inlineStr"><is><t xml:space="preserve">Dmitriy &lt;&amp;&gt; Smirnov</t></is></c><c r="C2"><v>42</v></c></row>`)
+	require.Contains(t, sheet, `<row r="3"><c r="A3"><v>2</v></c><c r="B3" t="inlineStr"><is><t xml:space="preserve"> Анна </t></is></c></row>`)
+	require.True(t, strings.HasSuffix(sheet, `</sheetData><autoFilter ref="A1:C3"/></worksheet>`))
+
+	require.Contains(t, parts["xl/worksheets/sheet2.xml"], `<c r="B1"><v>42.5</v></c>`)
+	require.NotContains(t, parts["xl/worksheets/sheet2.xml"], "autoFilter")
+	require.True(t, strings.HasSuffix(parts["xl/worksheets/sheet3.xml"], `<sheetData></sheetData></worksheet>`))
+}
+
+func TestNewWriterErrors(t *testing.T) {
+	_, err := NewWriter(io.Discard, "Persons", "Persons")
+	require.ErrorIs(t, err, ErrDuplicateSheetName)
+
+	_, err = NewWriter(io.Discard, "a/b")
+	require.ErrorIs(t, err, ErrInvalidSheetName)
+
+	_, err = NewWriter(io.Discard, strings.Repeat("a", 32))
+	require.ErrorIs(t, err, ErrInvalidSheetName)
+
+	w, err := NewWriter(io.Discard, "Persons")
+	require.NoError(t, err)
+	require.NoError(t, w.NextSheet(false))
+	require.ErrorIs(t, w.NextSheet(false), ErrNoSheetsLeft)
+}
+
+func TestTooManyRows(t *testing.T) {
+	w, err := NewWriter(io.Discard, "Persons", "Stats")
+	require.NoError(t, err)
+	require.NoError(t, w.NextSheet(true))
+	require.NoError(t, w.WriteHeader("id"))
+
+	for i := 1; i < MaxRows; i++ {
+		require.NoError(t, w.WriteRow(i))
+	}
+
+	require.ErrorIs(t, w.WriteRow(MaxRows), ErrTooManyRows)
+
+	// the limit is per sheet
+	require.NoError(t, w.NextSheet(false))
+	require.NoError(t, w.WriteRow(1))
+	require.NoError(t, w.Close())
+}
+
+func TestColumnName(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 6: "G", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		require.Equal(t, name, ColumnName(index))
+	}
+}